@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/fverse/protoc-graphql/internal"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	// Well-known types, resolved when a descriptor set imports them without including them
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/fieldmaskpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func runFromDescriptorSet() {
+	config := parseGenerateArgs()
+
+	if len(config.protoFiles) != 1 && config.descriptorSet == "" {
+		fmt.Fprintln(os.Stderr, "Error: expected exactly one descriptor set file")
+		fmt.Fprintln(os.Stderr, "Usage: protoc-gen-graphql from-descriptor-set [options] <descriptor_set>")
+		os.Exit(1)
+	}
+	if config.descriptorSet == "" {
+		config.descriptorSet = config.protoFiles[0]
+	}
+	runDescriptorSet(config)
+}
+
+// runDescriptorSet generates schemas from the descriptor set of the config, read from stdin for "-"
+func runDescriptorSet(config *generateConfig) {
+	data, err := readDescriptorSet(config.descriptorSet, os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading descriptor set: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := generateFromDescriptorSet(data, config.outputDir, config.pluginOpts, config.files...); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// readDescriptorSet reads a descriptor set from a file, or from stdin if path is "-",
+// e.g. piped from `buf build -o -`
+func readDescriptorSet(path string, stdin io.Reader) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// generateFromDescriptorSet generates schemas from a serialized FileDescriptorSet,
+// as produced by `protoc --descriptor_set_out` or `buf build`, and writes them to outputDir.
+// files selects the files to generate, by default the files not imported by another file of the set
+func generateFromDescriptorSet(data []byte, outputDir string, pluginOpts []string, files ...string) error {
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("error parsing descriptor set: %w", err)
+	}
+	if len(set.File) == 0 {
+		return errors.New("descriptor set contains no files")
+	}
+
+	if len(files) == 0 {
+		files = filesToGenerate(set.File)
+	} else if err := checkFiles(set.File, files); err != nil {
+		return err
+	}
+
+	protoFiles, err := resolveImports(set.File)
+	if err != nil {
+		return err
+	}
+
+	parameter, err := pluginParameter(pluginOpts)
+	if err != nil {
+		return err
+	}
+	request := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: files,
+		Parameter:      proto.String(parameter),
+		ProtoFile:      protoFiles,
+	}
+
+	plugin := internal.New(request)
+	plugin.Execute()
+
+	return writeResponse(plugin.Response, outputDir)
+}
+
+// filesToGenerate returns the files of the set that are not imported by another file of the set.
+// These are the files passed to protoc or buf, dependencies added with --include_imports are skipped.
+func filesToGenerate(files []*descriptorpb.FileDescriptorProto) []string {
+	imported := make(map[string]bool)
+	for _, file := range files {
+		for _, dependency := range file.Dependency {
+			imported[dependency] = true
+		}
+	}
+
+	var names []string
+	for _, file := range files {
+		if !imported[file.GetName()] {
+			names = append(names, file.GetName())
+		}
+	}
+	return names
+}
+
+// resolveImports returns the files of the set and their imports, dependencies first, as protoc passes them to plugins.
+// Imports missing from the set, such as well-known types of a set built without --include_imports, are resolved
+// from the descriptors built into the plugin. The files are then checked to only reference types that exist.
+func resolveImports(set []*descriptorpb.FileDescriptorProto) ([]*descriptorpb.FileDescriptorProto, error) {
+	byName := make(map[string]*descriptorpb.FileDescriptorProto)
+	for _, file := range set {
+		byName[file.GetName()] = file
+	}
+
+	var files []*descriptorpb.FileDescriptorProto
+	visited := make(map[string]bool)
+	var visit func(name, importedBy string) error
+	visit = func(name, importedBy string) error {
+		if visited[name] {
+			return nil
+		}
+		visited[name] = true
+
+		file, ok := byName[name]
+		if !ok {
+			builtin, err := protoregistry.GlobalFiles.FindFileByPath(name)
+			if err != nil {
+				return fmt.Errorf("%s imports %s, which is not part of the descriptor set. "+
+					"Build the descriptor set with --include_imports", importedBy, name)
+			}
+			file = protodesc.ToFileDescriptorProto(builtin)
+		}
+		for _, dependency := range file.Dependency {
+			if err := visit(dependency, name); err != nil {
+				return err
+			}
+		}
+		files = append(files, file)
+		return nil
+	}
+	for _, file := range set {
+		if err := visit(file.GetName(), ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: files}); err != nil {
+		return nil, fmt.Errorf("error resolving descriptor set: %w", err)
+	}
+	return files, nil
+}
+
+// checkFiles checks that the selected files are part of the set
+func checkFiles(set []*descriptorpb.FileDescriptorProto, files []string) error {
+	names := make(map[string]bool)
+	for _, file := range set {
+		names[file.GetName()] = true
+	}
+	for _, file := range files {
+		if !names[file] {
+			return fmt.Errorf("%s is not part of the descriptor set", file)
+		}
+	}
+	return nil
+}
+
+// writeResponse writes the generated files of the response to outputDir
+func writeResponse(response *pluginpb.CodeGeneratorResponse, outputDir string) error {
+	if response.Error != nil {
+		return errors.New(response.GetError())
+	}
+
+	for _, file := range response.File {
+		path := filepath.Join(outputDir, file.GetName())
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("error creating directory: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(file.GetContent()), 0644); err != nil {
+			return fmt.Errorf("error writing %s: %w", path, err)
+		}
+	}
+	return nil
+}
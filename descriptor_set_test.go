@@ -0,0 +1,198 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestGenerateFromDescriptorSet(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "users.pb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := t.TempDir()
+	if err := generateFromDescriptorSet(data, outputDir, []string{"keep_case"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// common.proto is only imported by users.proto and must not be generated
+	if _, err := os.Stat(filepath.Join(outputDir, "common.graphql")); !os.IsNotExist(err) {
+		t.Errorf("imported common.proto should not be generated")
+	}
+
+	out, err := os.ReadFile(filepath.Join(outputDir, "users.graphql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"type User {\n  name: String\n  address: Address\n}",
+		"getUser(input: IGetUserRequest!): User!",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("users.graphql should contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestGenerateFromInvalidDescriptorSet(t *testing.T) {
+	if err := generateFromDescriptorSet([]byte("not a descriptor set"), t.TempDir(), nil); err == nil {
+		t.Error("invalid descriptor set should return an error")
+	}
+	if err := generateFromDescriptorSet(nil, t.TempDir(), nil); err == nil {
+		t.Error("empty descriptor set should return an error")
+	}
+}
+
+func TestGenerateFromStdinDescriptorSet(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "users.pb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = stdinR
+	defer func() { os.Stdin = stdin }()
+
+	go func() {
+		stdinW.Write(data)
+		stdinW.Close()
+	}()
+
+	outputDir := t.TempDir()
+	runDescriptorSet(&generateConfig{descriptorSet: "-", outputDir: outputDir, files: []string{"common.proto"}})
+
+	// Only the selected file is generated
+	if _, err := os.Stat(filepath.Join(outputDir, "common.graphql")); err != nil {
+		t.Errorf("common.proto should be generated: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "users.graphql")); !os.IsNotExist(err) {
+		t.Errorf("users.proto is not selected and should not be generated")
+	}
+}
+
+func TestGenerateUnknownDescriptorSetFile(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "users.pb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = generateFromDescriptorSet(data, t.TempDir(), nil, "orders.proto")
+	if err == nil || !strings.Contains(err.Error(), "orders.proto is not part of the descriptor set") {
+		t.Errorf("unknown file should return an error, got %v", err)
+	}
+}
+
+// importChain returns a descriptor set where orders.proto imports users.proto, and both import the
+// well-known Timestamp type. The set is built without --include_imports, so timestamp.proto is missing
+func importChain() *descriptorpb.FileDescriptorSet {
+	stringType := descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+	messageType := descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	field := func(name string, number int32, typeName string) *descriptorpb.FieldDescriptorProto {
+		if typeName == "" {
+			return &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Type: stringType, Label: optional}
+		}
+		return &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Type: messageType, Label: optional, TypeName: proto.String(typeName)}
+	}
+
+	return &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		{
+			Name:       proto.String("users.proto"),
+			Package:    proto.String("users"),
+			Syntax:     proto.String("proto3"),
+			Dependency: []string{"google/protobuf/timestamp.proto"},
+			MessageType: []*descriptorpb.DescriptorProto{
+				{Name: proto.String("User"), Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, ""),
+					field("created_at", 2, ".google.protobuf.Timestamp"),
+				}},
+			},
+		},
+		{
+			Name:       proto.String("orders.proto"),
+			Package:    proto.String("orders"),
+			Syntax:     proto.String("proto3"),
+			Dependency: []string{"users.proto", "google/protobuf/timestamp.proto"},
+			MessageType: []*descriptorpb.DescriptorProto{
+				{Name: proto.String("GetOrderRequest"), Field: []*descriptorpb.FieldDescriptorProto{field("id", 1, "")}},
+				{Name: proto.String("Order"), Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, ""),
+					field("buyer", 2, ".users.User"),
+					field("placed_at", 3, ".google.protobuf.Timestamp"),
+				}},
+			},
+			Service: []*descriptorpb.ServiceDescriptorProto{{
+				Name: proto.String("OrderService"),
+				Method: []*descriptorpb.MethodDescriptorProto{{
+					Name:       proto.String("GetOrder"),
+					InputType:  proto.String(".orders.GetOrderRequest"),
+					OutputType: proto.String(".orders.Order"),
+				}},
+			}},
+		},
+	}}
+}
+
+func TestGenerateFromDescriptorSetImportChain(t *testing.T) {
+	// Generating from a descriptor set must not need protoc
+	t.Setenv("PATH", "")
+
+	data, err := proto.Marshal(importChain())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := t.TempDir()
+	if err := generateFromDescriptorSet(data, outputDir, []string{"scalar=google.protobuf.Timestamp:DateTime"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := os.ReadDir(outputDir)
+	if len(entries) != 1 {
+		t.Errorf("only orders.proto should be generated, got %v", entries)
+	}
+	out, err := os.ReadFile(filepath.Join(outputDir, "orders.graphql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"scalar DateTime\n",
+		"type Order {\n  id: String\n  buyer: User\n  placedAt: DateTime\n}",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("orders.graphql should contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestGenerateFromDescriptorSetMissingImport(t *testing.T) {
+	set := importChain()
+	set.File = set.File[1:]
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = generateFromDescriptorSet(data, t.TempDir(), nil)
+	if err == nil || !strings.Contains(err.Error(), "orders.proto imports users.proto, which is not part of the descriptor set") {
+		t.Errorf("missing import should return an error, got %v", err)
+	}
+
+	// A reference to a type that does not exist is reported too
+	set = importChain()
+	set.File[1].MessageType[1].Field[1].TypeName = proto.String(".users.Customer")
+	data, _ = proto.Marshal(set)
+	if err := generateFromDescriptorSet(data, t.TempDir(), nil); err == nil || !strings.Contains(err.Error(), "users.Customer") {
+		t.Errorf("unresolved type should return an error, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/fverse/protoc-graphql/internal/embedded"
+)
+
+func runDoctor() {
+	if !doctor(os.Stdout) {
+		os.Exit(1)
+	}
+}
+
+// A failed doctor check, with a hint to fix it
+type doctorFailure struct {
+	check, hint string
+}
+
+// doctor checks the environment of the generate command: that protoc is installed
+// and compiles the embedded options.proto. It writes a report to w and returns false if a check failed
+func doctor(w io.Writer) bool {
+	var failures []doctorFailure
+	pass := func(format string, a ...any) {
+		fmt.Fprintf(w, "[ok]   "+format+"\n", a...)
+	}
+	fail := func(check, detail, hint string) {
+		fmt.Fprintf(w, "[fail] %s: %s\n", check, detail)
+		failures = append(failures, doctorFailure{check, hint})
+	}
+
+	var version []byte
+	protoc, err := exec.LookPath("protoc")
+	if err != nil {
+		fail("protoc", "not found in PATH", "install protoc: https://grpc.io/docs/protoc-installation/")
+	} else if version, err = exec.Command(protoc, "--version").Output(); err != nil {
+		fail("protoc", fmt.Sprintf("%s --version failed: %v", protoc, err), "reinstall protoc: https://grpc.io/docs/protoc-installation/")
+	} else {
+		pass("protoc: %s (%s)", strings.TrimSpace(string(version)), protoc)
+	}
+
+	// options.proto is compiled with the protoc found above, if it runs
+	if err == nil {
+		if output, err := compileOptions(); err != nil {
+			fail("options.proto", "protoc can't compile the embedded options.proto: "+err.Error()+output,
+				"options.proto imports google/protobuf/descriptor.proto, install protoc with its include directory or add it to PROTO_PATH")
+		} else {
+			pass("options.proto: version %s compiles", embedded.OptionsVersion)
+		}
+	}
+
+	if len(failures) == 0 {
+		fmt.Fprintln(w, "\nAll checks passed")
+		return true
+	}
+	fmt.Fprintf(w, "\n%d check(s) failed:\n", len(failures))
+	for _, failure := range failures {
+		fmt.Fprintf(w, "  %s: %s\n", failure.check, failure.hint)
+	}
+	return false
+}
+
+// compileOptions runs protoc on the embedded options.proto, with the include paths of the generate command.
+// It returns protoc's errors, indented on the lines after the error
+func compileOptions() (string, error) {
+	tempDir, err := embedded.ExtractProtos()
+	if err != nil {
+		return "", fmt.Errorf("error extracting proto files: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	args := []string{"-I" + tempDir}
+	for _, p := range discoverIncludePaths([]string{tempDir}, os.Getenv("PROTO_PATH")) {
+		args = append(args, "-I"+p)
+	}
+	args = append(args,
+		"--descriptor_set_out="+filepath.Join(tempDir, "options.pb"),
+		filepath.Join("protobuf", "options", "options.proto"),
+	)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("protoc", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var output string
+		for _, line := range strings.Split(strings.TrimSpace(stderr.String()), "\n") {
+			if line != "" {
+				output += "\n       " + line
+			}
+		}
+		return output, err
+	}
+	return "", nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fverse/protoc-graphql/internal/embedded"
+)
+
+// Installs a fake protoc running script in an empty PATH
+func fakeProtoc(t *testing.T, script string) string {
+	bin := t.TempDir()
+	protoc := filepath.Join(bin, "protoc")
+	if err := os.WriteFile(protoc, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	t.Setenv("PROTO_PATH", "")
+	return protoc
+}
+
+func TestDoctor(t *testing.T) {
+	t.Run("healthy", func(t *testing.T) {
+		// A protoc that compiles the proto file if it exists below the first include path
+		protoc := fakeProtoc(t, `#!/bin/sh
+if [ "$1" = "--version" ]; then echo "libprotoc 25.1"; exit 0; fi
+include="${1#-I}"
+for arg in "$@"; do file="$arg"; done
+test -f "$include/$file"
+`)
+		var out bytes.Buffer
+		if !doctor(&out) {
+			t.Fatalf("doctor should pass, got:\n%s", out.String())
+		}
+		for _, want := range []string{
+			"[ok]   protoc: libprotoc 25.1 (" + protoc + ")\n",
+			"[ok]   options.proto: version " + embedded.OptionsVersion + " compiles\n",
+			"\nAll checks passed\n",
+		} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("output should contain %q, got:\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("options.proto doesn't compile", func(t *testing.T) {
+		fakeProtoc(t, `#!/bin/sh
+if [ "$1" = "--version" ]; then echo "libprotoc 25.1"; exit 0; fi
+echo "google/protobuf/descriptor.proto: File not found." >&2
+exit 1
+`)
+		var out bytes.Buffer
+		if doctor(&out) {
+			t.Fatalf("doctor should fail, got:\n%s", out.String())
+		}
+		for _, want := range []string{
+			"[ok]   protoc: libprotoc 25.1",
+			"[fail] options.proto: protoc can't compile the embedded options.proto: exit status 1\n" +
+				"       google/protobuf/descriptor.proto: File not found.\n",
+			"\n1 check(s) failed:\n  options.proto: options.proto imports google/protobuf/descriptor.proto",
+		} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("output should contain %q, got:\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("broken protoc", func(t *testing.T) {
+		protoc := fakeProtoc(t, "#!/bin/sh\nexit 1\n")
+		var out bytes.Buffer
+		if doctor(&out) {
+			t.Fatalf("doctor should fail, got:\n%s", out.String())
+		}
+		want := "[fail] protoc: " + protoc + " --version failed: exit status 1\n\n1 check(s) failed:\n" +
+			"  protoc: reinstall protoc: https://grpc.io/docs/protoc-installation/\n"
+		if out.String() != want {
+			t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+		}
+	})
+
+	t.Run("no protoc", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		var out bytes.Buffer
+		if doctor(&out) {
+			t.Fatalf("doctor should fail, got:\n%s", out.String())
+		}
+		want := "[fail] protoc: not found in PATH\n\n1 check(s) failed:\n" +
+			"  protoc: install protoc: https://grpc.io/docs/protoc-installation/\n"
+		if out.String() != want {
+			t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+		}
+	})
+}
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/fverse/protoc-graphql/internal"
 	"github.com/fverse/protoc-graphql/internal/embedded"
 )
 
@@ -15,11 +20,20 @@ type generateConfig struct {
 	outputDir  string
 	protoPaths []string
 	pluginOpts []string
+	// Path of a FileDescriptorSet to generate from instead of running protoc, "-" for stdin
+	descriptorSet string
+	// Files of the descriptor set to generate, by default the files not imported by another file
+	files []string
 }
 
 func runGenerate() {
 	config := parseGenerateArgs()
 
+	if config.descriptorSet != "" {
+		runDescriptorSet(config)
+		return
+	}
+
 	if len(config.protoFiles) == 0 {
 		fmt.Fprintln(os.Stderr, "Error: no proto files specified")
 		fmt.Fprintln(os.Stderr, "Usage: protoc-gen-graphql generate [options] <proto_files...>")
@@ -40,13 +54,49 @@ func runGenerate() {
 		os.Exit(1)
 	}
 
+	if err := runProtoc(config, pluginPath, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		var protocErr *protocError
+		if errors.As(err, &protocErr) && protocErr.code > 0 {
+			os.Exit(protocErr.code)
+		}
+		os.Exit(1)
+	}
+}
+
+// protocError is returned when protoc exits with an error, which it printed to stderr
+type protocError struct {
+	code int
+	// Partial output files removed after the failure
+	removed []string
+}
+
+func (err *protocError) Error() string {
+	msg := fmt.Sprintf("protoc failed with exit code %d", err.code)
+	if len(err.removed) > 0 {
+		msg += fmt.Sprintf(", removed partial output %s", strings.Join(err.removed, ", "))
+	}
+	return msg
+}
+
+// runProtoc runs protoc with the plugin and the embedded protos, writing protoc's errors to stderr.
+// If protoc fails, the plugin output files it created are removed. The embedded protos are removed in any case
+func runProtoc(config *generateConfig, pluginPath string, stderr io.Writer) error {
+	parameter, err := pluginParameter(config.pluginOpts)
+	if err != nil {
+		return err
+	}
+
 	// Extract embedded protos to temp directory
 	tempDir, err := embedded.ExtractProtos()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error extracting proto files: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("error extracting proto files: %w", err)
 	}
-	defer os.RemoveAll(tempDir)
+	defer func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			fmt.Fprintf(stderr, "Warning: error removing %s: %v\n", tempDir, err)
+		}
+	}()
 
 	// Build protoc command
 	args := []string{
@@ -76,35 +126,152 @@ func runGenerate() {
 		args = append(args, fmt.Sprintf("-I%s", cwd))
 	}
 
-	// Add plugin options if any
-	if len(config.pluginOpts) > 0 {
-		args[1] = fmt.Sprintf("--graphql_out=%s:%s", strings.Join(config.pluginOpts, ","), config.outputDir)
+	// Add the include roots of PROTO_PATH and of the protoc installation last, so the paths above take precedence
+	for _, p := range discoverIncludePaths(append([]string{cwd}, config.protoPaths...), os.Getenv("PROTO_PATH")) {
+		args = append(args, fmt.Sprintf("-I%s", p))
+	}
+
+	// Add plugin options if any. They are passed with --graphql_opt, values may contain colons such as URLs
+	if parameter != "" {
+		args = append(args, fmt.Sprintf("--graphql_opt=%s", parameter))
 	}
 
 	// Add proto files
 	args = append(args, config.protoFiles...)
 
+	// Files of the output directory before the run, so the files of a failed run can be removed
+	existing := listFiles(config.outputDir)
+
 	// Run protoc
 	cmd := exec.Command("protoc", args...)
 	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stderr = stderr
 
 	if err := cmd.Run(); err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			os.Exit(exitErr.ExitCode())
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return fmt.Errorf("error running protoc: %w", err)
 		}
-		fmt.Fprintf(os.Stderr, "Error running protoc: %v\n", err)
-		os.Exit(1)
+		isOutput := pluginOutput(config.outputDir, internal.ParseArgs(parameter, internal.NewLogger(internal.LevelError)))
+		return &protocError{code: exitErr.ExitCode(), removed: removeNewFiles(config.outputDir, existing, isOutput, stderr)}
 	}
+	return nil
+}
+
+// pluginParameter returns the plugin parameter of the plugin options, joined with commas.
+// The plugin splits its parameter on commas, and protoc joins repeated --graphql_opt flags with commas too,
+// so a value containing a comma is an error. List flags split their values into repeated options before
+func pluginParameter(pluginOpts []string) (string, error) {
+	for _, opt := range pluginOpts {
+		name, value, _ := strings.Cut(opt, "=")
+		if strings.Contains(value, ",") {
+			return "", fmt.Errorf("invalid value %q of option %s: plugin options can't contain commas", value, name)
+		}
+	}
+	return strings.Join(pluginOpts, ","), nil
+}
+
+// discoverIncludePaths returns the include roots the user may have forgotten to pass with -I:
+// the directories of protoPathEnv, a list like PATH, and the include directory bundled with protoc,
+// e.g. /usr/local/include for /usr/local/bin/protoc, which holds the well-known types.
+// Directories that don't exist or are already in protoPaths are skipped
+func discoverIncludePaths(protoPaths []string, protoPathEnv string) []string {
+	seen := make(map[string]bool)
+	for _, p := range protoPaths {
+		abs, _ := filepath.Abs(p)
+		seen[abs] = true
+	}
+
+	var candidates []string
+	if protoPathEnv != "" {
+		candidates = append(candidates, filepath.SplitList(protoPathEnv)...)
+	}
+	if protoc, err := exec.LookPath("protoc"); err == nil {
+		if resolved, err := filepath.EvalSymlinks(protoc); err == nil {
+			protoc = resolved
+		}
+		include := filepath.Join(filepath.Dir(filepath.Dir(protoc)), "include")
+		if _, err := os.Stat(filepath.Join(include, "google", "protobuf", "descriptor.proto")); err == nil {
+			candidates = append(candidates, include)
+		}
+	}
+
+	var paths []string
+	for _, p := range candidates {
+		abs, err := filepath.Abs(p)
+		if err != nil || seen[abs] {
+			continue
+		}
+		if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+			continue
+		}
+		seen[abs] = true
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// listFiles returns the files below dir
+func listFiles(dir string) map[string]bool {
+	files := make(map[string]bool)
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files[path] = true
+		}
+		return nil
+	})
+	return files
+}
+
+// pluginOutput returns a check of whether a file below dir may be written by the plugin with the options args:
+// a file with the extension of the output files, or of an output_filenames name, or the operations file,
+// the enums file or the manifest. Other files, e.g. written by other tools meanwhile, are not plugin output
+func pluginOutput(dir string, args *internal.Args) func(path string) bool {
+	extensions := map[string]bool{args.FileExtension(): true}
+	for _, name := range args.OutputFileNames {
+		extensions[filepath.Ext(name)] = true
+	}
+	names := make(map[string]bool)
+	for _, name := range []string{args.OperationsFile, args.EnumsFile, args.Manifest} {
+		if name != "" {
+			names[filepath.Clean(name)] = true
+		}
+	}
+	return func(path string) bool {
+		name, err := filepath.Rel(dir, path)
+		return err == nil && (names[name] || extensions[filepath.Ext(name)])
+	}
+}
+
+// removeNewFiles removes the files below dir that are not in existing and that isOutput reports as plugin output,
+// and returns them
+func removeNewFiles(dir string, existing map[string]bool, isOutput func(path string) bool, stderr io.Writer) []string {
+	var removed []string
+	for path := range listFiles(dir) {
+		if existing[path] || !isOutput(path) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			fmt.Fprintf(stderr, "Warning: error removing %s: %v\n", path, err)
+			continue
+		}
+		removed = append(removed, path)
+	}
+	sort.Strings(removed)
+	return removed
 }
 
 func parseGenerateArgs() *generateConfig {
+	return parseGenerateFlags(os.Args[2:]) // Skip "protoc-gen-graphql" and "generate"
+}
+
+// parseGenerateFlags parses the flags of the generate command.
+// Plugin option flags are mapped to plugin options with the shared option specs
+func parseGenerateFlags(args []string) *generateConfig {
 	config := &generateConfig{
 		outputDir: ".",
 	}
 
-	args := os.Args[2:] // Skip "protoc-gen-graphql" and "generate"
-
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
 
@@ -129,49 +296,47 @@ func parseGenerateArgs() *generateConfig {
 		case strings.HasPrefix(arg, "--proto_path="):
 			config.protoPaths = append(config.protoPaths, strings.TrimPrefix(arg, "--proto_path="))
 
-		case arg == "--target":
+		case arg == "--descriptor_set":
 			if i+1 < len(args) {
 				i++
-				config.pluginOpts = append(config.pluginOpts, "target="+args[i])
+				config.descriptorSet = args[i]
 			}
-		case strings.HasPrefix(arg, "--target="):
-			config.pluginOpts = append(config.pluginOpts, "target="+strings.TrimPrefix(arg, "--target="))
-
-		case arg == "--keep_case":
-			config.pluginOpts = append(config.pluginOpts, "keep_case")
-
-		case arg == "--keep_prefix":
-			config.pluginOpts = append(config.pluginOpts, "keep_prefix=true")
+		case strings.HasPrefix(arg, "--descriptor_set="):
+			config.descriptorSet = strings.TrimPrefix(arg, "--descriptor_set=")
 
-		case arg == "--combine_output":
-			config.pluginOpts = append(config.pluginOpts, "combine_output")
-
-		case arg == "--output_filename":
+		case arg == "--files":
 			if i+1 < len(args) {
 				i++
-				config.pluginOpts = append(config.pluginOpts, "output_filenames="+args[i])
+				config.files = append(config.files, strings.Split(args[i], ",")...)
 			}
-		case strings.HasPrefix(arg, "--output_filename="):
-			config.pluginOpts = append(config.pluginOpts, "output_filenames="+strings.TrimPrefix(arg, "--output_filename="))
+		case strings.HasPrefix(arg, "--files="):
+			config.files = append(config.files, strings.Split(strings.TrimPrefix(arg, "--files="), ",")...)
 
-		case arg == "--input_naming":
-			if i+1 < len(args) {
-				i++
-				config.pluginOpts = append(config.pluginOpts, "input_naming="+args[i])
+		case strings.HasPrefix(arg, "--"):
+			flag, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+			spec := lookupFlag(flag)
+			if spec == nil {
+				fmt.Fprintf(os.Stderr, "Warning: unknown flag --%s\n", flag)
+				break
 			}
-		case strings.HasPrefix(arg, "--input_naming="):
-			config.pluginOpts = append(config.pluginOpts, "input_naming="+strings.TrimPrefix(arg, "--input_naming="))
-
-		case arg == "--affix":
-			if i+1 < len(args) {
+			switch {
+			case hasValue:
+			case spec.Bool:
+				config.pluginOpts = append(config.pluginOpts, spec.Name)
+				continue
+			case i+1 < len(args):
 				i++
-				config.pluginOpts = append(config.pluginOpts, "affix="+args[i])
+				value = args[i]
+			default:
+				continue
+			}
+			values := []string{value}
+			if spec.List {
+				values = strings.Split(value, ",")
+			}
+			for _, v := range values {
+				config.pluginOpts = append(config.pluginOpts, spec.Name+"="+v)
 			}
-		case strings.HasPrefix(arg, "--affix="):
-			config.pluginOpts = append(config.pluginOpts, "affix="+strings.TrimPrefix(arg, "--affix="))
-
-		case arg == "--all":
-			config.pluginOpts = append(config.pluginOpts, "all=true")
 
 		case !strings.HasPrefix(arg, "-"):
 			// Assume it's a proto file
@@ -181,3 +346,13 @@ func parseGenerateArgs() *generateConfig {
 
 	return config
 }
+
+// lookupFlag returns the spec of the plugin option set by a generate command flag, or nil if there is none
+func lookupFlag(flag string) *internal.OptionSpec {
+	for i := range internal.OptionSpecs {
+		if internal.OptionSpecs[i].FlagName() == flag {
+			return &internal.OptionSpecs[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fverse/protoc-graphql/internal"
+)
+
+func TestGenerateFlagsRoundTrip(t *testing.T) {
+	defaults := internal.ParseArgs("", nil)
+
+	for _, spec := range internal.OptionSpecs {
+		flag := "--" + spec.FlagName()
+		forms := [][]string{{flag + "=" + spec.Example}, {flag, spec.Example}}
+		if spec.Bool {
+			forms = [][]string{{flag}, {flag + "=true"}}
+		}
+
+		for _, form := range forms {
+			config := parseGenerateFlags(append(form, "users.proto"))
+			if len(config.pluginOpts) != 1 || !reflect.DeepEqual(config.protoFiles, []string{"users.proto"}) {
+				t.Errorf("%v: expected one plugin option and the proto file, got %+v", form, config)
+				continue
+			}
+
+			name, _, _ := strings.Cut(config.pluginOpts[0], "=")
+			if internal.LookupOption(name) == nil {
+				t.Errorf("%v: plugin option %q is not recognized", form, name)
+			}
+			if args := internal.ParseArgs(config.pluginOpts[0], nil); reflect.DeepEqual(args, defaults) {
+				t.Errorf("%v: plugin option %q sets no Args field", form, config.pluginOpts[0])
+			}
+		}
+	}
+}
+
+func TestGenerateFlagsList(t *testing.T) {
+	config := parseGenerateFlags([]string{"--service=UserService,AdminService", "--service", "BillingService"})
+	want := []string{"service=UserService", "service=AdminService", "service=BillingService"}
+	if !reflect.DeepEqual(config.pluginOpts, want) {
+		t.Errorf("pluginOpts = %v, want %v", config.pluginOpts, want)
+	}
+}
+
+func TestGenerateFlagsFalseBool(t *testing.T) {
+	config := parseGenerateFlags([]string{"--keep_prefix=false"})
+	if args := internal.ParseArgs(strings.Join(config.pluginOpts, ","), nil); args.KeepPrefix {
+		t.Errorf("--keep_prefix=false should not keep prefixes, got options %v", config.pluginOpts)
+	}
+}
+
+func TestRunProtocFailure(t *testing.T) {
+	// A protoc that writes a partial output file before failing
+	bin := t.TempDir()
+	script := `#!/bin/sh
+for arg in "$@"; do
+  case "$arg" in --graphql_out=*) out="${arg#--graphql_out=}" ;; esac
+done
+echo "type User {" > "$out/users.graphql"
+echo "{" > "$out/manifest.json"
+echo "notes" > "$out/notes.txt"
+echo "users.proto:3:1: Expected top-level statement" >&2
+exit 3
+`
+	if err := os.WriteFile(filepath.Join(bin, "protoc"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	outputDir := t.TempDir()
+	kept := filepath.Join(outputDir, "orders.graphql")
+	if err := os.WriteFile(kept, []byte("type Order {\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stderr bytes.Buffer
+	config := &generateConfig{outputDir: outputDir, protoFiles: []string{"users.proto"}, pluginOpts: []string{"manifest=manifest.json"}}
+	err := runProtoc(config, "protoc-gen-graphql", &stderr)
+
+	var protocErr *protocError
+	if !errors.As(err, &protocErr) || protocErr.code != 3 {
+		t.Fatalf("expected a protoc error with exit code 3, got %v", err)
+	}
+	want := "protoc failed with exit code 3, removed partial output " + filepath.Join(outputDir, "manifest.json") + ", " +
+		filepath.Join(outputDir, "users.graphql")
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+	if !strings.Contains(stderr.String(), "Expected top-level statement") {
+		t.Errorf("protoc's errors should be printed, got %q", stderr.String())
+	}
+
+	if _, err := os.Stat(filepath.Join(outputDir, "users.graphql")); !os.IsNotExist(err) {
+		t.Errorf("partial output should be removed, got %v", err)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("files existing before the run should be kept, got %v", err)
+	}
+	// Files the plugin doesn't write, e.g. of other tools writing to the directory meanwhile, are kept
+	if _, err := os.Stat(filepath.Join(outputDir, "notes.txt")); err != nil {
+		t.Errorf("files other than plugin output should be kept, got %v", err)
+	}
+	if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+		t.Errorf("the embedded protos should be removed, got %v", entries)
+	}
+}
+
+func TestRunProtocCommaValue(t *testing.T) {
+	// A protoc that fails if it runs, the options are checked before
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "protoc"), []byte("#!/bin/sh\nexit 9\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	// List flags split their values, other values can't contain the commas separating plugin options
+	config := parseGenerateFlags([]string{"--section_order=types,inputs", "--scalar_spec=URL=https://example.com/a,b", "users.proto"})
+	want := `invalid value "URL=https://example.com/a,b" of option scalar_spec: plugin options can't contain commas`
+	if err := runProtoc(config, "protoc-gen-graphql", io.Discard); err == nil || err.Error() != want {
+		t.Errorf("error = %v, want %q", err, want)
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", "users.pb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := generateFromDescriptorSet(data, t.TempDir(), config.pluginOpts); err == nil || err.Error() != want {
+		t.Errorf("descriptor set error = %v, want %q", err, want)
+	}
+}
+
+func TestRunProtocIncludePaths(t *testing.T) {
+	// A protoc installed with its include directory, which records its arguments
+	root := t.TempDir()
+	bin := filepath.Join(root, "bin")
+	include := filepath.Join(root, "include")
+	if err := os.MkdirAll(filepath.Join(include, "google", "protobuf"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(include, "google", "protobuf", "descriptor.proto"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" > \"$ARGS_FILE\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "protoc"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	argsFile := filepath.Join(root, "args")
+	t.Setenv("ARGS_FILE", argsFile)
+	vendor := t.TempDir()
+	t.Setenv("PROTO_PATH", vendor+string(os.PathListSeparator)+filepath.Join(root, "missing"))
+
+	config := &generateConfig{outputDir: t.TempDir(), protoFiles: []string{"users.proto"}}
+	if err := runProtoc(config, "protoc-gen-graphql", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for _, want := range []string{"-I" + vendor, "-I" + include} {
+		if !slices.Contains(args, want) {
+			t.Errorf("protoc arguments should contain %s, got %v", want, args)
+		}
+	}
+	if slices.Contains(args, "-I"+filepath.Join(root, "missing")) {
+		t.Errorf("missing directories should be skipped, got %v", args)
+	}
+	if args[len(args)-1] != "users.proto" {
+		t.Errorf("proto files should come last, got %v", args)
+	}
+
+	// Paths passed with -I are not added twice
+	config.protoPaths = []string{include}
+	if err := runProtoc(config, "protoc-gen-graphql", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(argsFile)
+	if n := strings.Count(string(data), "-I"+include+"\n"); n != 1 {
+		t.Errorf("include path should be passed once, got %d times in %s", n, data)
+	}
+}
@@ -1,6 +1,10 @@
 package analyzer
 
 import (
+	"slices"
+	"sort"
+	"strings"
+
 	"github.com/fverse/protoc-graphql/options"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
@@ -29,6 +33,16 @@ type TypeAnalyzer struct {
 	// Package names for cross-file resolution
 	packageName  string
 	packageNames map[string]bool
+
+	// Packages whose types are never reachable, with their subpackages
+	excludedPackages []string
+
+	// If true, string keyed maps are JSON scalars in inputs, so their entries and values are not input-reachable through them
+	jsonInputMaps bool
+
+	// Files of the messages and messages of the fields, by fully qualified message name, to resolve the features of fields
+	messageFiles map[string]*descriptorpb.FileDescriptorProto
+	fieldOwners  map[*descriptorpb.FieldDescriptorProto]string
 }
 
 func NewTypeAnalyzer(protoFiles []*descriptorpb.FileDescriptorProto) *TypeAnalyzer {
@@ -41,6 +55,8 @@ func NewTypeAnalyzer(protoFiles []*descriptorpb.FileDescriptorProto) *TypeAnalyz
 		inProgressInput:      make(map[string]bool),
 		inProgressOutput:     make(map[string]bool),
 		packageNames:         make(map[string]bool),
+		messageFiles:         make(map[string]*descriptorpb.FileDescriptorProto),
+		fieldOwners:          make(map[*descriptorpb.FieldDescriptorProto]string),
 	}
 
 	if len(protoFiles) > 0 {
@@ -50,13 +66,59 @@ func NewTypeAnalyzer(protoFiles []*descriptorpb.FileDescriptorProto) *TypeAnalyz
 	for _, protoFile := range protoFiles {
 		pkgName := protoFile.GetPackage()
 		ta.packageNames[pkgName] = true
-		ta.RegisterTypesFromFile(protoFile.MessageType, "", pkgName)
+		ta.registerTypes(protoFile, protoFile.MessageType, "", pkgName)
 		ta.RegisterEnumsFromFile(protoFile.EnumType, "", pkgName)
 	}
 
 	return ta
 }
 
+// ImportCycles returns the cycles of imports among the proto files, each as the file names along the cycle
+// ending with its first file, e.g. [a.proto b.proto a.proto]. Imports of files outside the set are ignored.
+// protoc rejects import cycles, but malformed descriptor sets may contain them
+func ImportCycles(protoFiles []*descriptorpb.FileDescriptorProto) [][]string {
+	files := make(map[string]*descriptorpb.FileDescriptorProto)
+	for _, protoFile := range protoFiles {
+		files[protoFile.GetName()] = protoFile
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int)
+	var path []string
+	var cycles [][]string
+
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		path = append(path, name)
+		for _, dependency := range files[name].Dependency {
+			if _, ok := files[dependency]; !ok {
+				continue
+			}
+			switch state[dependency] {
+			case visiting:
+				start := slices.Index(path, dependency)
+				cycle := append(slices.Clone(path[start:]), dependency)
+				cycles = append(cycles, cycle)
+			case 0:
+				visit(dependency)
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+	}
+
+	for _, protoFile := range protoFiles {
+		if state[protoFile.GetName()] == 0 {
+			visit(protoFile.GetName())
+		}
+	}
+	return cycles
+}
+
 func NewTypeAnalyzerSingle(protoFile *descriptorpb.FileDescriptorProto) *TypeAnalyzer {
 	return NewTypeAnalyzer([]*descriptorpb.FileDescriptorProto{protoFile})
 }
@@ -66,6 +128,11 @@ func (ta *TypeAnalyzer) RegisterTypes(messages []*descriptorpb.DescriptorProto,
 }
 
 func (ta *TypeAnalyzer) RegisterTypesFromFile(messages []*descriptorpb.DescriptorProto, prefix string, pkgName string) {
+	ta.registerTypes(nil, messages, prefix, pkgName)
+}
+
+// Registers the messages of a proto file, with the file and the fields of each message if the file is known
+func (ta *TypeAnalyzer) registerTypes(protoFile *descriptorpb.FileDescriptorProto, messages []*descriptorpb.DescriptorProto, prefix string, pkgName string) {
 	for _, message := range messages {
 		var fullName string
 		if prefix == "" {
@@ -79,9 +146,15 @@ func (ta *TypeAnalyzer) RegisterTypesFromFile(messages []*descriptorpb.Descripto
 		}
 
 		ta.typeRegistry[fullName] = message
+		if protoFile != nil {
+			ta.messageFiles[fullName] = protoFile
+			for _, field := range message.Field {
+				ta.fieldOwners[field] = fullName
+			}
+		}
 
 		if len(message.NestedType) > 0 {
-			ta.RegisterTypesFromFile(message.NestedType, fullName, pkgName)
+			ta.registerTypes(protoFile, message.NestedType, fullName, pkgName)
 		}
 
 		if len(message.EnumType) > 0 {
@@ -118,10 +191,57 @@ func (ta *TypeAnalyzer) RegisterEnumsFromFile(enums []*descriptorpb.EnumDescript
 	}
 }
 
+// Package returns the proto package of a fully qualified message or enum name, e.g. "users" for ".users.User.Status"
+func (ta *TypeAnalyzer) Package(fullName string) string {
+	pkg := ""
+	for name := range ta.packageNames {
+		if len(name) > len(pkg) && strings.HasPrefix(fullName, "."+name+".") {
+			pkg = name
+		}
+	}
+	return pkg
+}
+
+// ExcludePackages makes the types of the packages and their subpackages unreachable, e.g. "google.protobuf".
+// Their dependencies are not traversed. It must be called before the types are marked reachable
+func (ta *TypeAnalyzer) ExcludePackages(packages []string) {
+	ta.excludedPackages = packages
+}
+
+// JSONInputMaps sets whether string keyed maps are JSON scalars in inputs, as with input_maps=json.
+// It must be called before the types are marked reachable
+func (ta *TypeAnalyzer) JSONInputMaps(enabled bool) {
+	ta.jsonInputMaps = enabled
+}
+
+// Checks if a field references a message: message fields and groups, which editions write as DELIMITED message fields
+func isMessage(field *descriptorpb.FieldDescriptorProto) bool {
+	return field.GetType() == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE || field.GetType() == descriptorpb.FieldDescriptorProto_TYPE_GROUP
+}
+
+// Checks if a message is the entry of a map with string keys
+func isStringKeyedMapEntry(message *descriptorpb.DescriptorProto) bool {
+	return message.GetOptions().GetMapEntry() && len(message.Field) > 0 &&
+		message.Field[0].GetType() == descriptorpb.FieldDescriptorProto_TYPE_STRING
+}
+
+// IsExcluded checks if the fully qualified type or enum belongs to an excluded package
+func (ta *TypeAnalyzer) IsExcluded(fullName string) bool {
+	for _, pkg := range ta.excludedPackages {
+		if strings.HasPrefix(fullName, "."+strings.Trim(pkg, ".")+".") {
+			return true
+		}
+	}
+	return false
+}
+
 // MarkTypeReachableAsInput recursively marks a type and its dependencies as input-reachable.
 // This is used for RPC input types that need GraphQL input generation.
 func (ta *TypeAnalyzer) MarkTypeReachableAsInput(typeName string) {
 	resolvedName := ta.ResolveTypeName(typeName)
+	if ta.IsExcluded(resolvedName) {
+		return
+	}
 
 	// Skip if already reachable or currently being processed in input context
 	if ta.inputReachableTypes[resolvedName] || ta.inProgressInput[resolvedName] {
@@ -129,7 +249,7 @@ func (ta *TypeAnalyzer) MarkTypeReachableAsInput(typeName string) {
 	}
 
 	descriptor, exists := ta.typeRegistry[resolvedName]
-	if !exists {
+	if !exists || ta.jsonInputMaps && isStringKeyedMapEntry(descriptor) {
 		return
 	}
 
@@ -152,7 +272,7 @@ func (ta *TypeAnalyzer) MarkTypeReachableAsInput(typeName string) {
 
 	// Traverse field dependencies in input context
 	for _, field := range descriptor.Field {
-		if field.GetType() == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
+		if isMessage(field) {
 			ta.MarkTypeReachableAsInput(field.GetTypeName())
 		}
 
@@ -170,6 +290,9 @@ func (ta *TypeAnalyzer) MarkTypeReachableAsInput(typeName string) {
 // This is used for RPC output types that need GraphQL type generation.
 func (ta *TypeAnalyzer) MarkTypeReachableAsOutput(typeName string) {
 	resolvedName := ta.ResolveTypeName(typeName)
+	if ta.IsExcluded(resolvedName) {
+		return
+	}
 
 	// Skip if already reachable or currently being processed in output context
 	if ta.outputReachableTypes[resolvedName] || ta.inProgressOutput[resolvedName] {
@@ -200,7 +323,7 @@ func (ta *TypeAnalyzer) MarkTypeReachableAsOutput(typeName string) {
 
 	// Traverse field dependencies in output context
 	for _, field := range descriptor.Field {
-		if field.GetType() == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
+		if isMessage(field) {
 			ta.MarkTypeReachableAsOutput(field.GetTypeName())
 		}
 
@@ -214,17 +337,22 @@ func (ta *TypeAnalyzer) MarkTypeReachableAsOutput(typeName string) {
 	delete(ta.inProgressOutput, resolvedName)
 }
 
-func (ta *TypeAnalyzer) AnalyzeRPCDependencies(services []*descriptorpb.ServiceDescriptorProto, target string) {
+// AnalyzeRPCDependencies marks the types referenced by the RPCs of the target as reachable.
+// If serviceNames is not empty, only the RPCs of the named services are considered
+func (ta *TypeAnalyzer) AnalyzeRPCDependencies(services []*descriptorpb.ServiceDescriptorProto, target Target, serviceNames []string) {
 	for _, service := range services {
+		if !IncludesService(serviceNames, service) {
+			continue
+		}
 		for _, method := range service.Method {
 			methodOptions := getMethodOptions(method)
 
-			if !shouldIncludeMethod(target, methodOptions) {
+			if !IncludesMethod(target, methodOptions) {
 				continue
 			}
 
 			// Mark input type in input context
-			if inputType := method.GetInputType(); inputType != "" {
+			if inputType := RequestType(method); inputType != "" {
 				ta.MarkTypeReachableAsInput(inputType)
 			}
 
@@ -236,6 +364,20 @@ func (ta *TypeAnalyzer) AnalyzeRPCDependencies(services []*descriptorpb.ServiceD
 	}
 }
 
+// RequestType returns the message the arguments of a method are generated from:
+// the message of the gql_args_type option if set, else the input type of the RPC
+func RequestType(method *descriptorpb.MethodDescriptorProto) string {
+	if argsType := getMethodOptions(method).GetGqlArgsType(); argsType != "" {
+		return "." + strings.TrimPrefix(argsType, ".")
+	}
+	return method.GetInputType()
+}
+
+// IncludesService checks if the service is one of serviceNames, or if serviceNames is empty
+func IncludesService(serviceNames []string, service *descriptorpb.ServiceDescriptorProto) bool {
+	return len(serviceNames) == 0 || slices.Contains(serviceNames, service.GetName())
+}
+
 func getMethodOptions(method *descriptorpb.MethodDescriptorProto) *options.MethodOptions {
 	opts := method.GetOptions()
 	if proto.HasExtension(opts, options.E_Method) {
@@ -245,17 +387,39 @@ func getMethodOptions(method *descriptorpb.MethodDescriptorProto) *options.Metho
 	return &options.MethodOptions{}
 }
 
-func shouldIncludeMethod(cliTarget string, methodOptions *options.MethodOptions) bool {
+// Target selects the RPCs generated with the target option
+type Target struct {
+	// Target of the command line, "all" or "*" act as wildcards
+	Name string
+	// Target of the methods without a target option, set with default_target
+	Default string
+	// If true, methods without a target option are not generated for named targets, set with exclude_untargeted
+	ExcludeUntargeted bool
+}
+
+// IncludesMethod checks if a method is generated for the target. Methods without a target option
+// take the default target if one is set, else they are generated for every target unless excluded
+func IncludesMethod(target Target, methodOptions *options.MethodOptions) bool {
 	if methodOptions.Skip {
 		return false
 	}
 
+	methodTarget := methodOptions.Target
+	if methodTarget == "" {
+		switch {
+		case target.Default != "":
+			methodTarget = target.Default
+		case target.Name != "" && !target.ExcludeUntargeted:
+			return true
+		}
+	}
+
 	// "all" or "*" acts as wildcard (matches everything)
-	if cliTarget == "all" || cliTarget == "*" || methodOptions.Target == "all" || methodOptions.Target == "*" {
+	if target.Name == "all" || target.Name == "*" || methodTarget == "all" || methodTarget == "*" {
 		return true
 	}
 
-	return cliTarget == methodOptions.Target
+	return target.Name == methodTarget
 }
 
 // IsInputReachable checks if a type needs GraphQL input generation.
@@ -383,6 +547,42 @@ func (ta *TypeAnalyzer) IsEnumReachable(enumName string) bool {
 	return false
 }
 
+// InputReachableTypes returns the sorted, fully qualified names of the input-reachable types
+func (ta *TypeAnalyzer) InputReachableTypes() []string {
+	return sortedNames(ta.inputReachableTypes)
+}
+
+// OutputReachableTypes returns the sorted, fully qualified names of the output-reachable types
+func (ta *TypeAnalyzer) OutputReachableTypes() []string {
+	return sortedNames(ta.outputReachableTypes)
+}
+
+// ReachableEnums returns the sorted, fully qualified names of the reachable enums
+func (ta *TypeAnalyzer) ReachableEnums() []string {
+	return sortedNames(ta.reachableEnums)
+}
+
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name, ok := range set {
+		if ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Message returns the message with the fully qualified name, or nil if it is not registered
+func (ta *TypeAnalyzer) Message(fullName string) *descriptorpb.DescriptorProto {
+	return ta.typeRegistry[fullName]
+}
+
+// Enum returns the enum with the fully qualified name, or nil if it is not registered
+func (ta *TypeAnalyzer) Enum(fullName string) *descriptorpb.EnumDescriptorProto {
+	return ta.enumRegistry[fullName]
+}
+
 func (ta *TypeAnalyzer) ResolveTypeName(typeName string) string {
 	if len(typeName) > 0 && typeName[0] == '.' {
 		if _, exists := ta.typeRegistry[typeName]; exists {
@@ -1,8 +1,11 @@
 package analyzer
 
 import (
+	"slices"
 	"testing"
 
+	"github.com/fverse/protoc-graphql/options"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
@@ -444,20 +447,222 @@ func TestWildcardTargetBehavior(t *testing.T) {
 	})
 }
 
-// testShouldIncludeMethod is a test helper that mirrors the logic in analyzer.go
-// for testing wildcard behavior without needing actual protobuf options
+// testShouldIncludeMethod is a test helper checking IncludesMethod for a CLI target and method options
 func testShouldIncludeMethod(cliTarget string, methodTarget string, skip bool) bool {
-	if skip {
-		return false
-	}
-	// "all" or "*" acts as wildcard (matches everything)
-	if cliTarget == "all" || cliTarget == "*" || methodTarget == "all" || methodTarget == "*" {
-		return true
-	}
-	return cliTarget == methodTarget
+	return IncludesMethod(Target{Name: cliTarget}, &options.MethodOptions{Target: methodTarget, Skip: skip})
 }
 
 // fieldType is a helper to create field type pointers
 func fieldType(t descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto_Type {
 	return &t
 }
+
+// TestReachabilityAccessors verifies the exported accessors return the sorted,
+// fully qualified reachable names after AnalyzeRPCDependencies
+func TestReachabilityAccessors(t *testing.T) {
+	pkgName := "test"
+
+	protoFile := &descriptorpb.FileDescriptorProto{
+		Name:    strPtr("test.proto"),
+		Package: &pkgName,
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: strPtr("Request"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: strPtr("filter"), Number: int32Ptr(1), Type: fieldType(descriptorpb.FieldDescriptorProto_TYPE_MESSAGE), TypeName: strPtr(".test.Filter")},
+				},
+			},
+			{Name: strPtr("Filter")},
+			{
+				Name: strPtr("Response"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: strPtr("status"), Number: int32Ptr(1), Type: enumType(descriptorpb.FieldDescriptorProto_TYPE_ENUM), TypeName: strPtr(".test.Status")},
+				},
+			},
+			{Name: strPtr("Unused")},
+		},
+		EnumType: []*descriptorpb.EnumDescriptorProto{
+			{Name: strPtr("Status"), Value: []*descriptorpb.EnumValueDescriptorProto{{Name: strPtr("OK"), Number: int32Ptr(0)}}},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: strPtr("Service"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{Name: strPtr("Get"), InputType: strPtr(".test.Request"), OutputType: strPtr(".test.Response")},
+				},
+			},
+		},
+	}
+
+	ta := NewTypeAnalyzer([]*descriptorpb.FileDescriptorProto{protoFile})
+	ta.AnalyzeRPCDependencies(protoFile.Service, Target{}, nil)
+
+	assertNames := func(name string, got, want []string) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Errorf("%s = %v, want %v", name, got, want)
+			return
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s = %v, want %v", name, got, want)
+				return
+			}
+		}
+	}
+
+	assertNames("InputReachableTypes", ta.InputReachableTypes(), []string{".test.Filter", ".test.Request"})
+	assertNames("OutputReachableTypes", ta.OutputReachableTypes(), []string{".test.Response"})
+	assertNames("ReachableEnums", ta.ReachableEnums(), []string{".test.Status"})
+}
+
+func TestMapValueReachability(t *testing.T) {
+	pkgName := "test"
+	mapField := func(name, entry string) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name: strPtr(name), Number: int32Ptr(1), Label: descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+			Type: fieldType(descriptorpb.FieldDescriptorProto_TYPE_MESSAGE), TypeName: strPtr(entry),
+		}
+	}
+	mapEntry := func(name string, key descriptorpb.FieldDescriptorProto_Type, value string) *descriptorpb.DescriptorProto {
+		return &descriptorpb.DescriptorProto{
+			Name: strPtr(name),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: strPtr("key"), Number: int32Ptr(1), Type: fieldType(key)},
+				{Name: strPtr("value"), Number: int32Ptr(2), Type: fieldType(descriptorpb.FieldDescriptorProto_TYPE_MESSAGE), TypeName: strPtr(value)},
+			},
+			Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+		}
+	}
+
+	protoFile := &descriptorpb.FileDescriptorProto{
+		Name:    strPtr("test.proto"),
+		Package: &pkgName,
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				// map<string, Item> items = 1 and map<int32, Price> prices = 1
+				Name:  strPtr("Request"),
+				Field: []*descriptorpb.FieldDescriptorProto{mapField("items", ".test.Request.ItemsEntry"), mapField("prices", ".test.Request.PricesEntry")},
+				NestedType: []*descriptorpb.DescriptorProto{
+					mapEntry("ItemsEntry", descriptorpb.FieldDescriptorProto_TYPE_STRING, ".test.Item"),
+					mapEntry("PricesEntry", descriptorpb.FieldDescriptorProto_TYPE_INT32, ".test.Price"),
+				},
+			},
+			{Name: strPtr("Item")},
+			{Name: strPtr("Price")},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: strPtr("Service"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{Name: strPtr("Set"), InputType: strPtr(".test.Request"), OutputType: strPtr(".test.Request")},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		jsonInputMaps bool
+		input         []string
+	}{
+		{false, []string{".test.Item", ".test.Price", ".test.Request", ".test.Request.ItemsEntry", ".test.Request.PricesEntry"}},
+		// Entries of string keyed maps are JSON scalars in inputs, maps with other keys still reference their values
+		{true, []string{".test.Price", ".test.Request", ".test.Request.PricesEntry"}},
+	}
+	for _, tt := range tests {
+		ta := NewTypeAnalyzer([]*descriptorpb.FileDescriptorProto{protoFile})
+		ta.JSONInputMaps(tt.jsonInputMaps)
+		ta.AnalyzeRPCDependencies(protoFile.Service, Target{}, nil)
+
+		if got := ta.InputReachableTypes(); !slices.Equal(got, tt.input) {
+			t.Errorf("jsonInputMaps=%v: InputReachableTypes = %v, want %v", tt.jsonInputMaps, got, tt.input)
+		}
+		output := []string{".test.Item", ".test.Price", ".test.Request", ".test.Request.ItemsEntry", ".test.Request.PricesEntry"}
+		if got := ta.OutputReachableTypes(); !slices.Equal(got, output) {
+			t.Errorf("jsonInputMaps=%v: OutputReachableTypes = %v, want %v", tt.jsonInputMaps, got, output)
+		}
+	}
+}
+
+func TestImportCycles(t *testing.T) {
+	files := []*descriptorpb.FileDescriptorProto{
+		{Name: strPtr("users.proto"), Dependency: []string{"common.proto", "orders.proto"}},
+		{Name: strPtr("orders.proto"), Dependency: []string{"google/protobuf/timestamp.proto", "users.proto"}},
+		{Name: strPtr("common.proto")},
+	}
+
+	cycles := ImportCycles(files)
+	want := []string{"users.proto", "orders.proto", "users.proto"}
+	if len(cycles) != 1 || !slices.Equal(cycles[0], want) {
+		t.Errorf("ImportCycles() = %v, want [%v]", cycles, want)
+	}
+
+	// Without the back edge there is no cycle
+	files[1].Dependency = files[1].Dependency[:1]
+	if cycles := ImportCycles(files); len(cycles) != 0 {
+		t.Errorf("ImportCycles() = %v, want no cycles", cycles)
+	}
+}
+
+func TestFieldPresence(t *testing.T) {
+	features := func(presence descriptorpb.FeatureSet_FieldPresence) *descriptorpb.FeatureSet {
+		return &descriptorpb.FeatureSet{FieldPresence: presence.Enum()}
+	}
+	field := func(name string) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{Name: strPtr(name), Number: int32Ptr(1), Type: fieldType(descriptorpb.FieldDescriptorProto_TYPE_STRING)}
+	}
+	own, inherited, member, nested, fileDefault := field("own"), field("inherited"), field("member"), field("nested"), field("file_default")
+	own.Options = &descriptorpb.FieldOptions{Features: features(descriptorpb.FeatureSet_LEGACY_REQUIRED)}
+	member.OneofIndex = int32Ptr(0)
+
+	protoFile := &descriptorpb.FileDescriptorProto{
+		Name:    strPtr("test.proto"),
+		Package: strPtr("test"),
+		Syntax:  strPtr("editions"),
+		Edition: descriptorpb.Edition_EDITION_2023.Enum(),
+		Options: &descriptorpb.FileOptions{Features: features(descriptorpb.FeatureSet_IMPLICIT)},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name:    strPtr("Message"),
+				Field:   []*descriptorpb.FieldDescriptorProto{own, inherited, member},
+				Options: &descriptorpb.MessageOptions{Features: features(descriptorpb.FeatureSet_EXPLICIT)},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{
+					{Name: strPtr("choice"), Options: &descriptorpb.OneofOptions{Features: features(descriptorpb.FeatureSet_IMPLICIT)}},
+				},
+				// Nested messages inherit the features of the messages containing them
+				NestedType: []*descriptorpb.DescriptorProto{{Name: strPtr("Nested"), Field: []*descriptorpb.FieldDescriptorProto{nested}}},
+			},
+			{Name: strPtr("Other"), Field: []*descriptorpb.FieldDescriptorProto{fileDefault}},
+		},
+	}
+
+	tests := []struct {
+		field *descriptorpb.FieldDescriptorProto
+		want  descriptorpb.FeatureSet_FieldPresence
+	}{
+		{own, descriptorpb.FeatureSet_LEGACY_REQUIRED},
+		{inherited, descriptorpb.FeatureSet_EXPLICIT},
+		{member, descriptorpb.FeatureSet_IMPLICIT},
+		{nested, descriptorpb.FeatureSet_EXPLICIT},
+		{fileDefault, descriptorpb.FeatureSet_IMPLICIT},
+	}
+	ta := NewTypeAnalyzer([]*descriptorpb.FileDescriptorProto{protoFile})
+	for _, tt := range tests {
+		if got := ta.FieldPresence(tt.field); got != tt.want {
+			t.Errorf("FieldPresence(%s) = %v, want %v", tt.field.GetName(), got, tt.want)
+		}
+	}
+
+	// Without features, the presence is the default of the edition or syntax
+	protoFile.Options = nil
+	for syntax, want := range map[string]descriptorpb.FeatureSet_FieldPresence{
+		"editions": descriptorpb.FeatureSet_EXPLICIT,
+		"proto3":   descriptorpb.FeatureSet_IMPLICIT,
+		"proto2":   descriptorpb.FeatureSet_EXPLICIT,
+	} {
+		protoFile.Syntax = strPtr(syntax)
+		if got := ta.FieldPresence(fileDefault); got != want {
+			t.Errorf("%s: FieldPresence(file_default) = %v, want %v", syntax, got, want)
+		}
+	}
+}
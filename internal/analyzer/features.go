@@ -0,0 +1,61 @@
+package analyzer
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// FieldPresence returns the presence of a field, resolved like the features of editions: the field_presence feature
+// of the field, else of its oneof, its message and the messages containing it, its file, else the default of the edition
+// of the file. Files with proto2 and proto3 syntax have the presence of their editions: fields of proto2 files have
+// explicit presence, fields of proto3 files implicit presence unless they are optional.
+// Fields of unregistered messages have explicit presence
+func (ta *TypeAnalyzer) FieldPresence(field *descriptorpb.FieldDescriptorProto) descriptorpb.FeatureSet_FieldPresence {
+	if presence, ok := fieldPresence(field.GetOptions().GetFeatures()); ok {
+		return presence
+	}
+	if field.GetProto3Optional() {
+		return descriptorpb.FeatureSet_EXPLICIT
+	}
+	owner, ok := ta.fieldOwners[field]
+	if !ok {
+		return descriptorpb.FeatureSet_EXPLICIT
+	}
+
+	if message := ta.typeRegistry[owner]; field.OneofIndex != nil && int(field.GetOneofIndex()) < len(message.OneofDecl) {
+		if presence, ok := fieldPresence(message.OneofDecl[field.GetOneofIndex()].GetOptions().GetFeatures()); ok {
+			return presence
+		}
+	}
+	// The message, then the messages containing it, up to the package
+	for name := owner; ; name = name[:strings.LastIndex(name, ".")] {
+		message, ok := ta.typeRegistry[name]
+		if !ok {
+			break
+		}
+		if presence, ok := fieldPresence(message.GetOptions().GetFeatures()); ok {
+			return presence
+		}
+	}
+
+	protoFile := ta.messageFiles[owner]
+	if presence, ok := fieldPresence(protoFile.GetOptions().GetFeatures()); ok {
+		return presence
+	}
+	return editionPresence(protoFile)
+}
+
+// Returns the field presence set by a feature set, if any
+func fieldPresence(features *descriptorpb.FeatureSet) (descriptorpb.FeatureSet_FieldPresence, bool) {
+	return features.GetFieldPresence(), features != nil && features.FieldPresence != nil
+}
+
+// Returns the default field presence of the edition of a proto file. Only proto3 defaults to implicit presence,
+// proto2 and the editions up to 2023 default to explicit presence
+func editionPresence(protoFile *descriptorpb.FileDescriptorProto) descriptorpb.FeatureSet_FieldPresence {
+	if protoFile.GetSyntax() == "proto3" || protoFile.GetSyntax() == "editions" && protoFile.GetEdition() == descriptorpb.Edition_EDITION_PROTO3 {
+		return descriptorpb.FeatureSet_IMPLICIT
+	}
+	return descriptorpb.FeatureSet_EXPLICIT
+}
@@ -1,23 +1,174 @@
 package internal
 
 import (
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/fverse/protoc-graphql/pkg/utils"
 )
 
+// Values of the enum_aliases option
+const (
+	// Generates aliased enum values as regular values
+	EnumAliasesKeep = "keep"
+	// Marks aliased enum values as @deprecated in favor of the first value with the same number
+	EnumAliasesDeprecate = "deprecate"
+)
+
+// Values of the enum_value_order option
+const (
+	// Generates enum values in proto declaration order. The default
+	EnumValueOrderProto = "proto"
+	// Sorts enum values by number, keeping the zero value first
+	EnumValueOrderNumber = "number"
+	// Sorts enum values by name
+	EnumValueOrderName = "name"
+)
+
+// Values of the input_naming option
+const (
+	// Prefixes input type names, with "I" unless another affix is set. The default
+	InputNamingPrefix = "prefix"
+	// Suffixes input type names, with "Input" unless another affix is set
+	InputNamingSuffix = "suffix"
+)
+
+// Values of the nullable option
+const (
+	// Fields are nullable unless required. The default
+	NullableDefault = "default"
+	// proto3 fields are non-null, unless declared optional, repeated or in a oneof
+	NullableNone = "none"
+	// Every field and payload is nullable, like all_nullable
+	NullableAll = "all"
+)
+
+// Values of the recursive_inputs option
+const (
+	// Makes the field closing a cycle of non-null input fields nullable. The default
+	RecursiveInputsNullable = "nullable"
+	// Fails generation on a cycle of non-null input fields
+	RecursiveInputsError = "error"
+)
+
+// Values of the reserved_names option
+const (
+	// Renames fields starting with "__", reserved by GraphQL for introspection, to start with a single "_". The default
+	ReservedNamesSanitize = "sanitize"
+	// Fails generation on fields starting with "__"
+	ReservedNamesError = "error"
+)
+
+// Values of the oneof option
+const (
+	// Describes the fields of a oneof as members of the oneof, of which only one may be set
+	OneofDescribe = "describe"
+)
+
+// Values of the input_maps option
+const (
+	// Generates map fields of inputs as lists of key-value entry inputs with non-null keys and values. The default
+	InputMapsEntries = "entries"
+	// Generates string keyed map fields of inputs as a JSON scalar, other maps as entries
+	InputMapsJSON = "json"
+)
+
+// Sections of a schema, in the default order of the section_order option
+const (
+	SectionTypes      = "types"
+	SectionInputs     = "inputs"
+	SectionEnums      = "enums"
+	SectionOperations = "operations"
+)
+
+var defaultSectionOrder = []string{SectionTypes, SectionInputs, SectionEnums, SectionOperations}
+
+// Values of the scalar_name_match option
+const (
+	// Maps string fields whose name ends with a token of scalar_by_name. The default
+	ScalarNameMatchSuffix = "suffix"
+	// Maps string fields whose name contains a token of scalar_by_name
+	ScalarNameMatchSubstring = "substring"
+)
+
+// A custom scalar of string fields selected by name, set with scalar_by_name
+type NamedScalar struct {
+	// Token matched case-insensitively against the proto field name, e.g. "uuid"
+	Token string
+	// Custom scalar of the matching fields, e.g. "UUID"
+	Scalar string
+}
+
+// Target of the expose_option option writing the option value to descriptions instead of a directive
+const ExposeDescription = "description"
+
+// A custom option of the user's protos exposed in the schema, set with expose_option
+type ExposedOption struct {
+	// Full name of the extension, e.g. "acme.http"
+	Extension string
+	// Directive the value is written as, e.g. "@http", or ExposeDescription
+	Target string
+}
+
+// Values of the field_case and type_case options
+const (
+	// Converts names to camelCase. The default for fields
+	CaseCamel = "camel"
+	// Converts names to snake_case
+	CaseSnake = "snake"
+	// Converts names to PascalCase
+	CasePascal = "pascal"
+	// Keeps names as declared in the proto file. The default for types
+	CaseOriginal = "original"
+)
+
 type Args struct {
 	// Sets the code gen target
 	Target string
-	// If true, keep the casing for type fields. Else fields will be converted to camel case
+	// Targets of the target option, several only with split_by_target
+	Targets []string
+	// If true, generates the combined schema of each target to its own file, e.g. admin.graphql
+	SplitByTarget bool
+	// Target of the methods without a target option. If empty, they are generated for every target
+	DefaultTarget string
+	// If true, methods without a target option are not generated for named targets
+	ExcludeUntargeted bool
+	// Names of the services whose RPCs are generated, all services if empty
+	Services []string
+	// Proto packages whose types are never generated, with their subpackages, e.g. "google.protobuf"
+	ExcludePackages []string
+	// If true, keep the casing for type fields. Same as FieldCase "original"
 	KeepCase bool
-	// If true, keeps the prefix in type names
+	// Casing of field names, "camel", "snake", "pascal" or "original"
+	FieldCase string
+	// Casing of type, input and enum names, "camel", "snake", "pascal" or "original"
+	TypeCase string
+	// Words kept as written by camel and pascal casing, e.g. ID and URL
+	Acronyms []string
+	// If true, prefixes the names of types, inputs and enums with their proto package, e.g. UsersStatus
 	KeepPrefix bool
+	// Suffixes stripped from message names, e.g. Response makes GetUserResponse the type GetUser
+	StripSuffixes []string
+	// Namespace prefixed to the names of generated types, inputs and enums, e.g. "Billing"
+	TypePrefix string
+	// Maximum length of type, input and enum names, longer names are truncated and end with a hash. 0, the default, is unbounded
+	MaxNameLength int
 	// If true, combines the output file to one single file
 	CombineOutput bool
 	// Sets custom output file names
 	OutputFileNames []string
-	// Wether to suffix or prefix input names. Suffixing the word 'Input' is the default behavior
+	// Default name of the input parameter of queries and mutations, overridden by the gql_input param.
+	// Defaults to "input"
+	InputParamName string
+	// Name of the file the Query and Mutation roots are written to with combine_output,
+	// the types stay in the combined file
+	OperationsFile string
+	// Name of the file all enums are written to without combine_output, the other files reference them
+	EnumsFile string
+	// Name of a JSON file listing the generated files with the SHA-256 hashes of their content
+	Manifest string
+	// Wether to suffix or prefix input names. Prefixing the letter 'I' is the default behavior
 	InputNaming string
 	// What to prefix or suffix with the input type names.
 	// Word 'Input' is default for suffix and letter 'I' is default for prefix
@@ -25,40 +176,522 @@ type Args struct {
 	// If true, generate schema against all the files explicitly listed in the command line
 	// and everything they import. Default to false
 	All bool
+	// If true, annotates generated types and fields with the proto message and field number they come from
+	AnnotateSource bool
+	// If true, comments generated types, inputs and enums with the proto file they come from
+	AnnotateFile bool
+	// If true, comments generated types, inputs and enums with the proto package they come from
+	AnnotatePackage bool
+	// If true, describes each query and mutation with the gRPC method backing it and its streaming kind
+	AnnotateOperations bool
+	// If true, describes each field with its proto type, e.g. "(proto: int64)"
+	AnnotateProtoType bool
+	// Maps fully qualified proto message names to custom GraphQL scalars, e.g. google.protobuf.Timestamp to DateTime
+	Scalars map[string]string
+	// Custom scalars of string fields matched by name, in order of precedence
+	ScalarsByName []NamedScalar
+	// How the tokens of ScalarsByName match field names, "suffix" or "substring"
+	ScalarNameMatch string
+	// Specification URLs of custom scalars, declared with @specifiedBy, by scalar name
+	ScalarSpecs map[string]string
+	// Scalar of proto double fields, e.g. "Float64". double and float fields are Float by default
+	DoubleScalar string
+	// Custom method, message and field options written as directives or descriptions
+	ExposeOptions []ExposedOption
+	// If true, describes each query and mutation with the REST mappings of its google.api.http option
+	ExposeHttp bool
+	// Fully qualified proto enum names generated as String instead of GraphQL enums
+	EnumsAsScalars map[string]bool
+	// If true, every enum is generated as String, and enum fields list the allowed values with a @values directive
+	EnumAsStringWithValues bool
+	// Path of a handwritten GraphQL file prepended to the generated output
+	Prepend string
+	// Path of a Markdown file whose sections describe the types and fields named by their headings
+	Docs string
+	// If true, fails generation when a referenced message produces a type without fields
+	ErrorOnEmptyType bool
+	// If true, messages without fields generate types and inputs with a placeholder field instead of being skipped
+	KeepEmptyMessages bool
+	// How enum values sharing a number (allow_alias) are generated, "keep" or "deprecate"
+	EnumAliases string
+	// Order of enum values, "proto", "number" or "name"
+	EnumValueOrder string
+	// Indentation of fields, enum values and operations. Two spaces by default
+	Indent string
+	// Number of arguments above which flattened arguments are written one per line. 0, the default, never wraps
+	ArgWrap int
+	// Prefix stripped from proto file paths when naming output files
+	StripPathPrefix string
+	// If true, names output files after the base name of the proto file, without its directories
+	FlattenNames bool
+	// Extension of the output files, without the dot, e.g. graphqls. "graphql" by default
+	FileExt string
+	// If true, names fields after their json_name instead of the camel cased proto name
+	UseJsonName bool
+	// If true, prints warnings for questionable input such as unknown method kinds
+	Strict bool
+	// If true, every field of output types and every query and mutation payload is nullable. Inputs are unchanged
+	AllNullable bool
+	// Nullability of the fields of output types: "default", "none" or "all"
+	Nullable string
+	// If true, generates the Relay Node interface, implemented by the types with an id, and the node query
+	RelayNode bool
+	// If true, generates a get<Type>(id: ID!) query for every type with an id, without an RPC
+	ResourceQueries bool
+	// If true, mutations return a generated payload type wrapping their result, e.g. CreateUserPayload
+	MutationPayloads bool
+	// Suffix of the payload types of mutation_payloads, appended to the mutation name. Defaults to "Payload"
+	PayloadSuffix string
+	// If true, the payload types of mutation_payloads have a clientMutationId field
+	ClientMutationId bool
+	// If true, generation fails if any warning was emitted
+	FailOnWarning bool
+	// If true, combined output keeps the definitions of every file, including duplicates, for debugging
+	NoDedup bool
+	// Order the sections of a schema are written in, the default order if empty
+	SectionOrder []string
+	// If true, only the first output file defines the Query and Mutation roots, the next ones extend them
+	ExtendRoots bool
+	// If true, generates an input type for every output type, not only for RPC inputs
+	AllInputs bool
+	// If true, the fields of request messages become arguments of the queries and mutations,
+	// instead of a single input argument
+	FlattenArgs bool
+	// If true, queries and mutations returning a message with a single field return the field instead
+	UnwrapSingleField bool
+	// How cycles of non-null input fields, which make inputs unconstructable, are handled: "nullable" or "error"
+	RecursiveInputs string
+	// How field names starting with "__", reserved for introspection, are handled: "sanitize" or "error"
+	ReservedNames string
+	// Version of the federation spec the directives are imported from, e.g. "2.3"
+	FederationVersion string
+	// How the fields of a proto oneof are generated. They are generated as regular fields by default
+	Oneof string
+	// How map fields of inputs are generated, "entries" or "json"
+	InputMaps string
+	// If true, warns about top-level messages and enums that are not generated, with the reason
+	EmitUnusedWarnings bool
+	// Path of a file the CodeGeneratorRequest is written to before generation, for bug reports.
+	// Paths ending with .txtpb or .textproto get the text format, others the binary format
+	DumpRequest string
+	// If true, prints debug messages to stderr
+	Verbose bool
+	// If true, prints only errors to stderr. Takes precedence over Verbose
+	Quiet bool
+}
+
+// OptionSpec describes a plugin option and the generate command flag setting it
+type OptionSpec struct {
+	// Name of the plugin option, e.g. "target" in --graphql_out=target=admin:.
+	Name string
+	// Name of the generate command flag, without dashes, if it differs from Name
+	Flag string
+	// If true, the option is a boolean that is set without a value, e.g. "keep_case"
+	Bool bool
+	// Example value of the option, empty for boolean options
+	Example string
+	// If true, the flag takes a comma-separated list, passed as one option per item.
+	// The plugin option is repeated instead, as plugin options are separated by commas
+	List bool
+	// Sets the option on the parsed args
+	set func(args *Args, v string, logger *Logger)
+}
+
+// FlagName returns the name of the generate command flag of the option
+func (spec *OptionSpec) FlagName() string {
+	if spec.Flag != "" {
+		return spec.Flag
+	}
+	return spec.Name
+}
+
+// Returns the spec of a boolean option. It is set without a value or with "true", and unset with "false"
+func boolOption(name string, set func(args *Args, v bool)) OptionSpec {
+	return OptionSpec{Name: name, Bool: true, set: func(args *Args, v string, logger *Logger) {
+		switch v {
+		case "", "true":
+			set(args, true)
+		case "false":
+			set(args, false)
+		default:
+			logger.Warn("invalid %s %q, expected \"true\" or \"false\"", name, v)
+		}
+	}}
+}
+
+// Returns the spec of an option taking a value
+func valueOption(name, example string, set func(args *Args, v string, logger *Logger)) OptionSpec {
+	return OptionSpec{Name: name, Example: example, set: set}
 }
 
+// Returns the spec of a repeated option, whose flag takes a comma-separated list
+func listOption(name, example string, set func(args *Args, v string, logger *Logger)) OptionSpec {
+	return OptionSpec{Name: name, Example: example, List: true, set: set}
+}
+
+// OptionSpecs are the options of the plugin, shared by the plugin parameter and the generate command flags
+var OptionSpecs = []OptionSpec{
+	listOption("target", "admin", func(args *Args, v string, logger *Logger) {
+		args.Targets = append(args.Targets, v)
+		args.Target = v
+	}),
+	boolOption("split_by_target", func(args *Args, v bool) { args.SplitByTarget = v }),
+	valueOption("default_target", "client", func(args *Args, v string, logger *Logger) { args.DefaultTarget = v }),
+	boolOption("exclude_untargeted", func(args *Args, v bool) { args.ExcludeUntargeted = v }),
+	listOption("service", "UserService", func(args *Args, v string, logger *Logger) { args.Services = append(args.Services, v) }),
+	listOption("exclude_package", "google.protobuf", func(args *Args, v string, logger *Logger) {
+		args.ExcludePackages = append(args.ExcludePackages, v)
+	}),
+	boolOption("keep_case", func(args *Args, v bool) { args.KeepCase = v }),
+	valueOption("field_case", CaseSnake, func(args *Args, v string, logger *Logger) {
+		args.FieldCase = parseCase("field_case", v, logger)
+	}),
+	valueOption("type_case", CasePascal, func(args *Args, v string, logger *Logger) {
+		args.TypeCase = parseCase("type_case", v, logger)
+	}),
+	listOption("acronyms", "ID", func(args *Args, v string, logger *Logger) {
+		if !acronym.MatchString(v) {
+			logger.Warn("invalid acronyms %q, expected letters and digits, e.g. ID", v)
+			return
+		}
+		args.Acronyms = append(args.Acronyms, v)
+	}),
+	boolOption("keep_prefix", func(args *Args, v bool) { args.KeepPrefix = v }),
+	listOption("strip_suffix", "Response", func(args *Args, v string, logger *Logger) {
+		args.StripSuffixes = append(args.StripSuffixes, v)
+	}),
+	valueOption("type_prefix", "Billing", func(args *Args, v string, logger *Logger) { args.TypePrefix = v }),
+	valueOption("max_name_length", "40", func(args *Args, v string, logger *Logger) {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < minNameLength {
+			logger.Warn("invalid max_name_length %q, expected a number of at least %d", v, minNameLength)
+			n = 0
+		}
+		args.MaxNameLength = n
+	}),
+	boolOption("combine_output", func(args *Args, v bool) { args.CombineOutput = v }),
+	boolOption("no_dedup", func(args *Args, v bool) { args.NoDedup = v }),
+	{Name: "output_filenames", Flag: "output_filename", Example: "api.graphql", set: func(args *Args, v string, logger *Logger) {
+		args.OutputFileNames = append(args.OutputFileNames, v)
+	}},
+	valueOption("operations_file", "operations.graphql", func(args *Args, v string, logger *Logger) { args.OperationsFile = v }),
+	valueOption("enums_file", "enums.graphql", func(args *Args, v string, logger *Logger) { args.EnumsFile = v }),
+	valueOption("manifest", "manifest.json", func(args *Args, v string, logger *Logger) { args.Manifest = v }),
+	valueOption("input_naming", InputNamingSuffix, func(args *Args, v string, logger *Logger) { args.InputNaming = v }),
+	valueOption("affix", "Args", func(args *Args, v string, logger *Logger) { args.Affix = v }),
+	boolOption("all", func(args *Args, v bool) { args.All = v }),
+	boolOption("annotate_source", func(args *Args, v bool) { args.AnnotateSource = v }),
+	boolOption("annotate_file", func(args *Args, v bool) { args.AnnotateFile = v }),
+	boolOption("annotate_package", func(args *Args, v bool) { args.AnnotatePackage = v }),
+	boolOption("annotate_operations", func(args *Args, v bool) { args.AnnotateOperations = v }),
+	boolOption("annotate_proto_type", func(args *Args, v bool) { args.AnnotateProtoType = v }),
+	valueOption("scalar", "google.protobuf.Timestamp:DateTime", func(args *Args, v string, logger *Logger) {
+		protoType, scalar, ok := strings.Cut(v, ":")
+		if !ok {
+			logger.Warn("invalid scalar %q, expected <proto type>:<scalar>", v)
+			return
+		}
+		if args.Scalars == nil {
+			args.Scalars = make(map[string]string)
+		}
+		args.Scalars[strings.TrimPrefix(protoType, ".")] = scalar
+	}),
+	listOption("scalar_by_name", "uuid:UUID", func(args *Args, v string, logger *Logger) {
+		token, scalar, _ := strings.Cut(v, ":")
+		if token == "" || !graphqlName.MatchString(scalar) {
+			logger.Warn("invalid scalar_by_name %q, expected <token>:<scalar>", v)
+			return
+		}
+		args.ScalarsByName = append(args.ScalarsByName, NamedScalar{Token: token, Scalar: scalar})
+	}),
+	valueOption("scalar_name_match", ScalarNameMatchSubstring, func(args *Args, v string, logger *Logger) {
+		if v != ScalarNameMatchSuffix && v != ScalarNameMatchSubstring {
+			logger.Warn("invalid scalar_name_match %q, expected \"suffix\" or \"substring\"", v)
+			v = ""
+		}
+		args.ScalarNameMatch = v
+	}),
+	valueOption("scalar_spec", "DateTime=https://scalars.graphql.org/andimarek/date-time", func(args *Args, v string, logger *Logger) {
+		scalar, url, ok := strings.Cut(v, "=")
+		if !ok || scalar == "" || url == "" {
+			logger.Warn("invalid scalar_spec %q, expected <scalar>=<url>", v)
+			return
+		}
+		if args.ScalarSpecs == nil {
+			args.ScalarSpecs = make(map[string]string)
+		}
+		args.ScalarSpecs[scalar] = url
+	}),
+	valueOption("double_scalar", "Float64", func(args *Args, v string, logger *Logger) {
+		if !graphqlName.MatchString(v) {
+			logger.Warn("invalid double_scalar %q, expected a GraphQL name such as \"Float64\"", v)
+			return
+		}
+		args.DoubleScalar = v
+	}),
+	valueOption("enum_as_scalar", "users.Status", func(args *Args, v string, logger *Logger) {
+		if args.EnumsAsScalars == nil {
+			args.EnumsAsScalars = make(map[string]bool)
+		}
+		args.EnumsAsScalars[strings.TrimPrefix(v, ".")] = true
+	}),
+	boolOption("enum_as_string_with_values", func(args *Args, v bool) { args.EnumAsStringWithValues = v }),
+	valueOption("input_param_name", "data", func(args *Args, v string, logger *Logger) { args.InputParamName = v }),
+	valueOption("prepend", "scalars.graphql", func(args *Args, v string, logger *Logger) { args.Prepend = v }),
+	valueOption("docs", "docs.md", func(args *Args, v string, logger *Logger) { args.Docs = v }),
+	boolOption("error_on_empty_type", func(args *Args, v bool) { args.ErrorOnEmptyType = v }),
+	boolOption("keep_empty_messages", func(args *Args, v bool) { args.KeepEmptyMessages = v }),
+	valueOption("enum_aliases", EnumAliasesDeprecate, func(args *Args, v string, logger *Logger) {
+		if v != EnumAliasesKeep && v != EnumAliasesDeprecate {
+			logger.Warn("invalid enum_aliases %q, expected \"keep\" or \"deprecate\"", v)
+			v = ""
+		}
+		args.EnumAliases = v
+	}),
+	valueOption("enum_value_order", EnumValueOrderName, func(args *Args, v string, logger *Logger) {
+		if v != EnumValueOrderProto && v != EnumValueOrderNumber && v != EnumValueOrderName {
+			logger.Warn("invalid enum_value_order %q, expected \"proto\", \"number\" or \"name\"", v)
+			v = ""
+		}
+		args.EnumValueOrder = v
+	}),
+	valueOption("indent", "4", func(args *Args, v string, logger *Logger) {
+		indent, ok := parseIndent(v)
+		if !ok {
+			logger.Warn("invalid indent %q, expected a number of spaces or \"tab\"", v)
+		}
+		args.Indent = indent
+	}),
+	valueOption("arg_wrap", "3", func(args *Args, v string, logger *Logger) {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			logger.Warn("invalid arg_wrap %q, expected a number of arguments", v)
+			n = 0
+		}
+		args.ArgWrap = n
+	}),
+	valueOption("strip_path_prefix", "protos", func(args *Args, v string, logger *Logger) { args.StripPathPrefix = v }),
+	boolOption("flatten_names", func(args *Args, v bool) { args.FlattenNames = v }),
+	valueOption("file_ext", "graphqls", func(args *Args, v string, logger *Logger) {
+		v = strings.TrimPrefix(v, ".")
+		if v == "" || strings.ContainsAny(v, "/\\") {
+			logger.Warn("invalid file_ext %q, expected an extension such as \"graphqls\"", v)
+			v = ""
+		}
+		args.FileExt = v
+	}),
+	boolOption("use_json_name", func(args *Args, v bool) { args.UseJsonName = v }),
+	boolOption("strict", func(args *Args, v bool) { args.Strict = v }),
+	boolOption("relay_node", func(args *Args, v bool) { args.RelayNode = v }),
+	boolOption("resource_queries", func(args *Args, v bool) { args.ResourceQueries = v }),
+	boolOption("mutation_payloads", func(args *Args, v bool) { args.MutationPayloads = v }),
+	valueOption("payload_suffix", "Result", func(args *Args, v string, logger *Logger) {
+		if !graphqlName.MatchString(v) {
+			logger.Warn("invalid payload_suffix %q, expected a GraphQL name", v)
+			v = ""
+		}
+		args.PayloadSuffix = v
+	}),
+	boolOption("client_mutation_id", func(args *Args, v bool) { args.ClientMutationId = v }),
+	boolOption("all_nullable", func(args *Args, v bool) { args.AllNullable = v }),
+	valueOption("nullable", NullableNone, func(args *Args, v string, logger *Logger) {
+		switch v {
+		case NullableDefault, NullableNone:
+		case NullableAll:
+			args.AllNullable = true
+		default:
+			logger.Warn("invalid nullable %q, expected \"default\", \"none\" or \"all\"", v)
+			v = ""
+		}
+		args.Nullable = v
+	}),
+	boolOption("fail_on_warning", func(args *Args, v bool) { args.FailOnWarning = v }),
+	boolOption("extend_roots", func(args *Args, v bool) { args.ExtendRoots = v }),
+	listOption("section_order", SectionOperations, func(args *Args, v string, logger *Logger) {
+		args.SectionOrder = append(args.SectionOrder, v)
+	}),
+	boolOption("all_inputs", func(args *Args, v bool) { args.AllInputs = v }),
+	boolOption("flatten_args", func(args *Args, v bool) { args.FlattenArgs = v }),
+	boolOption("unwrap_single_field", func(args *Args, v bool) { args.UnwrapSingleField = v }),
+	valueOption("recursive_inputs", RecursiveInputsError, func(args *Args, v string, logger *Logger) {
+		if v != RecursiveInputsNullable && v != RecursiveInputsError {
+			logger.Warn("invalid recursive_inputs %q, expected \"nullable\" or \"error\"", v)
+			v = ""
+		}
+		args.RecursiveInputs = v
+	}),
+	valueOption("reserved_names", ReservedNamesError, func(args *Args, v string, logger *Logger) {
+		if v != ReservedNamesSanitize && v != ReservedNamesError {
+			logger.Warn("invalid reserved_names %q, expected \"sanitize\" or \"error\"", v)
+			v = ""
+		}
+		args.ReservedNames = v
+	}),
+	valueOption("federation_version", "2.5", func(args *Args, v string, logger *Logger) {
+		if !validFederationVersion(v) {
+			logger.Warn("invalid federation_version %q, expected a federation 2 version such as \"2.3\"", v)
+			v = ""
+		}
+		args.FederationVersion = v
+	}),
+	valueOption("oneof", OneofDescribe, func(args *Args, v string, logger *Logger) {
+		if v != OneofDescribe {
+			logger.Warn("invalid oneof %q, expected \"describe\"", v)
+			v = ""
+		}
+		args.Oneof = v
+	}),
+	valueOption("input_maps", InputMapsJSON, func(args *Args, v string, logger *Logger) {
+		if v != InputMapsEntries && v != InputMapsJSON {
+			logger.Warn("invalid input_maps %q, expected \"entries\" or \"json\"", v)
+			v = ""
+		}
+		args.InputMaps = v
+	}),
+	valueOption("expose_option", "acme.http=@http", func(args *Args, v string, logger *Logger) {
+		extension, target, _ := strings.Cut(v, "=")
+		validTarget := target == ExposeDescription ||
+			strings.HasPrefix(target, "@") && graphqlName.MatchString(strings.TrimPrefix(target, "@"))
+		if extension == "" || !validTarget {
+			logger.Warn("invalid expose_option %q, expected <extension>=@<directive> or <extension>=description", v)
+			return
+		}
+		args.ExposeOptions = append(args.ExposeOptions, ExposedOption{Extension: strings.TrimPrefix(extension, "."), Target: target})
+	}),
+	boolOption("expose_http", func(args *Args, v bool) { args.ExposeHttp = v }),
+	boolOption("emit_unused_warnings", func(args *Args, v bool) { args.EmitUnusedWarnings = v }),
+	valueOption("dump_request", "request.binpb", func(args *Args, v string, logger *Logger) { args.DumpRequest = v }),
+	boolOption("verbose", func(args *Args, v bool) { args.Verbose = v }),
+	boolOption("quiet", func(args *Args, v bool) { args.Quiet = v }),
+}
+
+// LookupOption returns the spec of a plugin option, or nil if there is no such option
+func LookupOption(name string) *OptionSpec {
+	for i := range OptionSpecs {
+		if OptionSpecs[i].Name == name {
+			return &OptionSpecs[i]
+		}
+	}
+	return nil
+}
+
+// ParseArgs parses the comma separated plugin options, e.g. "target=admin,keep_case".
+// Unknown options are reported and ignored
 func ParseArgs(params string, logger *Logger) *Args {
 	args := Args{}
-	argSlice := strings.Split(params, ",")
-
-	for _, p := range argSlice {
-		var k string
-		var v string
-
-		pair := strings.Split(p, "=")
-		k = pair[0]
-		if len(pair) >= 2 {
-			v = pair[1]
-		}
-
-		switch k {
-		case "target":
-			args.Target = v
-		case "keep_case":
-			args.KeepCase = true
-		case "keep_prefix":
-			args.KeepPrefix = utils.ParseTrue(v)
-		case "combine_output":
-			args.CombineOutput = true
-		case "output_filenames":
-			args.OutputFileNames = append(args.OutputFileNames, v)
-		case "input_naming":
-			args.InputNaming = v
-		case "affix":
-			args.Affix = v
-		case "all":
-			args.All = utils.ParseTrue(v)
+
+	for _, p := range strings.Split(params, ",") {
+		if p == "" {
+			continue
 		}
+		k, v, _ := strings.Cut(p, "=")
+
+		spec := LookupOption(k)
+		if spec == nil {
+			logger.Warn("unknown option %q", k)
+			continue
+		}
+		spec.set(&args, v, logger)
 	}
 	return &args
 }
+
+// Parses the indent option, a number of spaces or "tab"
+func parseIndent(v string) (string, bool) {
+	if v == "tab" {
+		return "\t", true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return "", false
+	}
+	return strings.Repeat(" ", n), true
+}
+
+// Acronyms of the acronyms option, words of letters and digits
+var acronym = regexp.MustCompile(`^[A-Za-z][0-9A-Za-z]*$`)
+
+// GraphQL names, e.g. of scalars
+var graphqlName = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// Checks that a federation_version is a federation 2 version, "2.<minor>"
+func validFederationVersion(v string) bool {
+	minor, ok := strings.CutPrefix(v, "2.")
+	if !ok {
+		return false
+	}
+	n, err := strconv.Atoi(minor)
+	return err == nil && n >= 0
+}
+
+// Parses the field_case and type_case options
+func parseCase(option, v string, logger *Logger) string {
+	switch v {
+	case CaseCamel, CaseSnake, CasePascal, CaseOriginal:
+		return v
+	}
+	logger.Warn("invalid %s %q, expected \"camel\", \"snake\", \"pascal\" or \"original\"", option, v)
+	return ""
+}
+
+// Returns the log level selected by the verbose and quiet options
+func (args *Args) LogLevel() Level {
+	switch {
+	case args.Quiet:
+		return LevelError
+	case args.Verbose:
+		return LevelDebug
+	default:
+		return LevelInfo
+	}
+}
+
+// Returns the extension of the output files, with its dot, selected by the file_ext option
+func (args *Args) FileExtension() string {
+	if args.FileExt == "" {
+		return ".graphql"
+	}
+	return "." + args.FileExt
+}
+
+// Returns the order the sections of a schema are written in, selected by the section_order option
+func (args *Args) Sections() []string {
+	if len(args.SectionOrder) == 0 {
+		return defaultSectionOrder
+	}
+	return args.SectionOrder
+}
+
+// Returns the transform applied to proto field names, selected by the field_case option.
+// keep_case is the same as field_case=original, fields are camel cased by default
+func (args *Args) FieldCaseTransform() func(string) string {
+	fieldCase := args.FieldCase
+	if fieldCase == "" && args.KeepCase {
+		fieldCase = CaseOriginal
+	}
+	if fieldCase == "" {
+		fieldCase = CaseCamel
+	}
+	return caseTransform(fieldCase, args.Acronyms)
+}
+
+// Returns the transform applied to message and enum names, selected by the type_case option.
+// Type names are kept as declared by default
+func (args *Args) TypeCaseTransform() func(string) string {
+	return caseTransform(args.TypeCase, args.Acronyms)
+}
+
+// Returns the transform of a field_case or type_case value, names are kept for unknown values.
+// Camel and pascal casing write the words matching acronyms as the acronyms
+func caseTransform(c string, acronyms []string) func(string) string {
+	switch c {
+	case CaseCamel:
+		return func(name string) string { return utils.CamelCase(name, acronyms...) }
+	case CaseSnake:
+		return utils.SnakeCase
+	case CasePascal:
+		return func(name string) string { return utils.PascalCase(name, acronyms...) }
+	default:
+		return func(name string) string { return name }
+	}
+}
@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"sort"
+
+	"github.com/fverse/protoc-graphql/options"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// An extension of a vendored options.proto whose number differs from the plugin's
+type mismatchedExtension struct {
+	name     string
+	expected protoreflect.FieldNumber
+	// First element setting the option, empty if the option is not used
+	usedBy string
+}
+
+// Warns about options set with extensions of a vendored options.proto whose numbers differ from
+// the embedded one. Such options are unknown to the plugin and silently fall back to their defaults.
+func (plugin *Plugin) checkOptionsCompatibility() {
+	mismatched := plugin.mismatchedExtensions()
+	if len(mismatched) == 0 {
+		return
+	}
+
+	for _, protoFile := range plugin.Request.ProtoFile {
+		if plugin.isFileExplicit(protoFile) {
+			findUnknownOptions(protoFile, mismatched)
+		}
+	}
+
+	numbers := make([]int, 0, len(mismatched))
+	for number := range mismatched {
+		numbers = append(numbers, int(number))
+	}
+	sort.Ints(numbers)
+
+	for _, number := range numbers {
+		extension := mismatched[protoreflect.FieldNumber(number)]
+		if extension.usedBy == "" {
+			continue
+		}
+		plugin.Logger.Warn("%s sets (%s) with extension number %d but %s expects %d, the option is ignored. "+
+			"Run `%s init --force` to update options.proto", extension.usedBy, extension.name, number,
+			NAME, extension.expected, NAME)
+	}
+}
+
+// Returns the extensions of vendored options.proto files that are numbered differently than the embedded one.
+// A file is a copy of options.proto if it defines the GqlInput message.
+func (plugin *Plugin) mismatchedExtensions() map[protoreflect.FieldNumber]*mismatchedExtension {
+	expected := make(map[string]protoreflect.FieldNumber)
+	extensions := options.File_options_options_proto.Extensions()
+	for i := 0; i < extensions.Len(); i++ {
+		extension := extensions.Get(i)
+		expected["."+string(extension.ContainingMessage().FullName())+"."+string(extension.Name())] = extension.Number()
+	}
+
+	mismatched := make(map[protoreflect.FieldNumber]*mismatchedExtension)
+	for _, protoFile := range plugin.Request.ProtoFile {
+		if !definesGqlInput(protoFile) {
+			continue
+		}
+		for _, extension := range protoFile.Extension {
+			number, ok := expected[extension.GetExtendee()+"."+extension.GetName()]
+			if ok && number != protoreflect.FieldNumber(extension.GetNumber()) {
+				mismatched[protoreflect.FieldNumber(extension.GetNumber())] = &mismatchedExtension{
+					name:     extension.GetName(),
+					expected: number,
+				}
+			}
+		}
+	}
+	return mismatched
+}
+
+func definesGqlInput(protoFile *descriptorpb.FileDescriptorProto) bool {
+	for _, message := range protoFile.MessageType {
+		if message.GetName() == "GqlInput" {
+			return true
+		}
+	}
+	return false
+}
+
+// Records the first method, message or field of the file setting one of the mismatched extensions
+func findUnknownOptions(protoFile *descriptorpb.FileDescriptorProto, mismatched map[protoreflect.FieldNumber]*mismatchedExtension) {
+	record := func(opts proto.Message, element string) {
+		for _, number := range unknownFields(opts) {
+			if extension, ok := mismatched[number]; ok && extension.usedBy == "" {
+				extension.usedBy = protoFile.GetName() + ":" + element
+			}
+		}
+	}
+
+	for _, service := range protoFile.Service {
+		for _, method := range service.Method {
+			if method.Options != nil {
+				record(method.Options, service.GetName()+"."+method.GetName())
+			}
+		}
+	}
+
+	var visit func(messages []*descriptorpb.DescriptorProto, prefix string)
+	visit = func(messages []*descriptorpb.DescriptorProto, prefix string) {
+		for _, message := range messages {
+			name := prefix + message.GetName()
+			if message.Options != nil {
+				record(message.Options, name)
+			}
+			for _, field := range message.Field {
+				if field.Options != nil {
+					record(field.Options, name+"."+field.GetName())
+				}
+			}
+			visit(message.NestedType, name+".")
+		}
+	}
+	visit(protoFile.MessageType, "")
+}
+
+// Returns the numbers of the unknown fields of a message
+func unknownFields(message proto.Message) []protoreflect.FieldNumber {
+	var numbers []protoreflect.FieldNumber
+	unknown := message.ProtoReflect().GetUnknown()
+	for len(unknown) > 0 {
+		number, typ, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			break
+		}
+		unknown = unknown[n:]
+		n = protowire.ConsumeFieldValue(number, typ, unknown)
+		if n < 0 {
+			break
+		}
+		unknown = unknown[n:]
+		numbers = append(numbers, number)
+	}
+	return numbers
+}
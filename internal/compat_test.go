@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// vendoredOptionsFile returns a copy of options.proto declaring keep_case with the given number
+func vendoredOptionsFile(keepCaseNumber int32) *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:        proto.String("options/options.proto"),
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("GqlInput")}},
+		Extension: []*descriptorpb.FieldDescriptorProto{
+			{
+				Name:     proto.String("required"),
+				Number:   proto.Int32(50021),
+				Extendee: proto.String(".google.protobuf.FieldOptions"),
+			},
+			{
+				Name:     proto.String("keep_case"),
+				Number:   proto.Int32(keepCaseNumber),
+				Extendee: proto.String(".google.protobuf.FieldOptions"),
+			},
+		},
+	}
+}
+
+func TestMismatchedOptionsWarning(t *testing.T) {
+	// A field annotated with (keep_case) = true using the vendored extension number,
+	// which the plugin can only see as an unknown field
+	field := scalarField("API_KEY", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	field.Options = &descriptorpb.FieldOptions{}
+	field.Options.ProtoReflect().SetUnknown(protowire.AppendVarint(protowire.AppendTag(nil, 50099, protowire.VarintType), 1))
+
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", field),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+
+	tests := []struct {
+		name           string
+		keepCaseNumber int32
+		want           string
+	}{
+		{"mismatched", 50099, "users.proto:User.API_KEY sets (keep_case) with extension number 50099 but " +
+			NAME + " expects 50022, the option is ignored. Run `" + NAME + " init --force` to update options.proto"},
+		{"matching", 50022, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := New(&pluginpb.CodeGeneratorRequest{
+				FileToGenerate: []string{"users.proto"},
+				ProtoFile:      []*descriptorpb.FileDescriptorProto{vendoredOptionsFile(tt.keepCaseNumber), file},
+			})
+			var buf bytes.Buffer
+			plugin.Logger = newLogger(&buf, LevelInfo)
+			plugin.checkOptionsCompatibility()
+
+			if tt.want == "" {
+				if buf.Len() != 0 {
+					t.Errorf("expected no warning, got %q", buf.String())
+				}
+				return
+			}
+			if !strings.Contains(buf.String(), NAME+": warning: "+tt.want+"\n") {
+				t.Errorf("expected warning %q, got %q", tt.want, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/fverse/protoc-graphql/internal/descriptor"
+	"github.com/fverse/protoc-graphql/options"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Directive of the repeated fields with the gql_non_empty option, which have at least one element
+const nonEmptyDirective = "@constraint(minItems: 1)"
+
+// Declaration of the @constraint directive of gql_non_empty
+const constraintDirectiveDeclaration = "directive @constraint(minItems: Int) on FIELD_DEFINITION | INPUT_FIELD_DEFINITION | ARGUMENT_DEFINITION"
+
+// Adds the @constraint directive to a field with the gql_non_empty option, which must be repeated
+func (schema *Schema) nonEmptyConstraint(field *descriptorpb.FieldDescriptorProto, f *descriptor.Field) {
+	if !boolFieldOption(field.GetOptions(), options.E_GqlNonEmpty) {
+		return
+	}
+	if !f.IsList {
+		schema.Error(fmt.Errorf("gql_non_empty field %s must be repeated", field.GetName()), "error generating field")
+		return
+	}
+	f.Directives = append(f.Directives, nonEmptyDirective)
+}
+
+// Checks if a field has the @constraint directive of gql_non_empty, e.g. a flattened argument
+func isNonEmpty(field *descriptor.Field) bool {
+	return slices.Contains(field.Directives, nonEmptyDirective)
+}
+
+// Checks if a field of the schema uses the @constraint directive
+func (schema *Schema) collectConstraintDirective() {
+	add := func(fields []*descriptor.Field) {
+		for _, field := range fields {
+			for _, directive := range field.Directives {
+				schema.declareConstraint = schema.declareConstraint || directive == nonEmptyDirective
+			}
+		}
+	}
+	for _, objectType := range schema.objectTypes {
+		add(objectType.Fields)
+	}
+	for _, inputType := range schema.inputTypes {
+		add(inputType.Fields)
+	}
+	for _, query := range schema.queries {
+		add(query.Arguments)
+	}
+	for _, mutation := range schema.mutations {
+		add(mutation.Arguments)
+	}
+}
+
+// Declares the @constraint directive, if the schema uses it
+func (schema *Schema) generateConstraintDirective() {
+	if !schema.declareConstraint {
+		return
+	}
+	schema.Write(constraintDirectiveDeclaration)
+	schema.NewLine(2)
+}
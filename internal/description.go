@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fverse/protoc-graphql/options"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// formatDescription renders a description as a GraphQL string, followed by a newline.
+// Surrounding whitespace, trailing whitespace of lines and line endings are normalized first.
+// Single-line descriptions are written as "..." strings, multi-line ones as """...""" block strings
+// with every line indented by indent. Empty descriptions render as an empty string.
+func formatDescription(description, indent string) string {
+	description = strings.ReplaceAll(description, "\r\n", "\n")
+	lines := strings.Split(strings.TrimSpace(description), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+
+	switch {
+	case lines[0] == "":
+		return ""
+	case len(lines) == 1:
+		return indent + quoteString(lines[0]) + "\n"
+	}
+
+	var b strings.Builder
+	b.WriteString(indent + `"""` + "\n")
+	for _, line := range lines {
+		// Block strings have no escapes but for triple quotes
+		line = strings.ReplaceAll(line, `"""`, `\"""`)
+		if line != "" {
+			b.WriteString(indent + line)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(indent + `"""` + "\n")
+	return b.String()
+}
+
+// Returns the description of a field from its gql_example options, one "Example: ..." line per example
+func fieldExamples(fieldOptions *descriptorpb.FieldOptions) string {
+	if !proto.HasExtension(fieldOptions, options.E_GqlExample) {
+		return ""
+	}
+	var lines []string
+	for _, example := range proto.GetExtension(fieldOptions, options.E_GqlExample).([]string) {
+		lines = append(lines, "Example: "+example)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// joinDescriptions joins the non-empty parts of a description with blank lines
+func joinDescriptions(parts ...string) string {
+	var nonEmpty []string
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, "\n\n")
+}
+
+// quoteString quotes a single-line GraphQL string, escaping quotes, backslashes and control characters
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			b.WriteString(fmt.Sprintf(`\u%04X`, r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// writeDescriptionString writes the description of a definition, a field or an enum value on the lines before it.
+// Nested descriptions, e.g. of fields, are indented
+func (schema *Schema) writeDescriptionString(description string, nested bool) {
+	indent := ""
+	if nested {
+		indent = schema.indentation()
+	}
+	schema.Write(formatDescription(description, indent))
+}
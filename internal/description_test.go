@@ -0,0 +1,210 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fverse/protoc-graphql/options"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestFormatDescription(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		indent      string
+		want        string
+	}{
+		{"empty", "", "", ""},
+		{"whitespace only", " \n\t\n ", "  ", ""},
+		{"single line", "The user's name", "", "\"The user's name\"\n"},
+		{"single line indented", "The user's name", "  ", "  \"The user's name\"\n"},
+		{"surrounding whitespace", "\n  The user's name \n\n", "", "\"The user's name\"\n"},
+		{"quotes and backslashes", `Path like "C:\tmp"`, "", `"Path like \"C:\\tmp\""` + "\n"},
+		{"control characters", "Tab\there\x01", "", `"Tab\there\u0001"` + "\n"},
+		{"triple quotes on one line", `Use """ to quote`, "", `"Use \"\"\" to quote"` + "\n"},
+		{
+			"multi-line",
+			"First line\nSecond line",
+			"",
+			"\"\"\"\nFirst line\nSecond line\n\"\"\"\n",
+		},
+		{
+			"multi-line indented",
+			"First line\n\nThird line",
+			"  ",
+			"  \"\"\"\n  First line\n\n  Third line\n  \"\"\"\n",
+		},
+		{
+			"multi-line normalized",
+			"\r\n First line  \r\nSecond line\t\r\n\r\n",
+			"",
+			"\"\"\"\nFirst line\nSecond line\n\"\"\"\n",
+		},
+		{
+			"multi-line triple quotes",
+			"Example:\n\"\"\"quoted\"\"\" and \\ kept",
+			"",
+			"\"\"\"\nExample:\n\\\"\"\"quoted\\\"\"\" and \\ kept\n\"\"\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatDescription(tt.description, tt.indent); got != tt.want {
+				t.Errorf("formatDescription(%q, %q) = %q, want %q", tt.description, tt.indent, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldExamples(t *testing.T) {
+	email := scalarField("email", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	email.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(email.Options, options.E_GqlExample, []string{"ada@example.com", `"quoted"`})
+	email.OneofIndex = proto.Int32(0)
+	phone := scalarField("phone", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	phone.OneofIndex = proto.Int32(0)
+	name := scalarField("name", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	name.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(name.Options, options.E_GqlExample, []string{`Say """hi"""`})
+
+	user := testMessage("User", email, phone, name)
+	user.OneofDecl = []*descriptorpb.OneofDescriptorProto{{Name: proto.String("contact")}}
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{user},
+		testMethod("GetUser", ".users.User", ".users.User", nil),
+	)
+
+	out := generate(t, "", file)["users.graphql"]
+	for _, want := range []string{
+		"  \"\"\"\n  Example: ada@example.com\n  Example: \"quoted\"\n  \"\"\"\n  email: String\n",
+		"  \"Example: Say \\\"\\\"\\\"hi\\\"\\\"\\\"\"\n  name: String\n",
+		"  phone: String\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+
+	// Examples follow the oneof description
+	out = generate(t, "oneof=describe", file)["users.graphql"]
+	want := "  \"\"\"\n  Member of oneof contact, only one may be set\n\n  Example: ada@example.com\n  Example: \"quoted\"\n  \"\"\"\n  email: String\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+	}
+}
+
+func TestAnnotateProtoType(t *testing.T) {
+	email := scalarField("email", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	email.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(email.Options, options.E_GqlExample, []string{"ada@example.com"})
+	avatars := scalarField("avatars", 2, descriptorpb.FieldDescriptorProto_TYPE_BYTES)
+	avatars.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("User",
+				scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64),
+				avatars,
+				email,
+				messageField("created_at", 4, ".google.protobuf.Timestamp"),
+			),
+		},
+		testMethod("GetUser", ".users.User", ".users.User", nil),
+	)
+
+	out := generate(t, "annotate_proto_type", file)["users.graphql"]
+	for _, want := range []string{
+		"  \"(proto: int64)\"\n  id: Int\n",
+		"  \"(proto: repeated bytes)\"\n  avatars: [String]\n",
+		"  \"\"\"\n  Example: ada@example.com\n\n  (proto: string)\n  \"\"\"\n  email: String\n",
+		"  \"(proto: google.protobuf.Timestamp)\"\n  createdAt: String\n",
+		"input IUser {\n  \"(proto: int64)\"\n  id: Int\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+
+	if out := generate(t, "", file)["users.graphql"]; strings.Contains(out, "(proto: ") {
+		t.Errorf("proto types should not be annotated by default, got:\n%s", out)
+	}
+}
+
+func TestDocs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "docs.md")
+	docs := "# Users API\n\nIntro, not a description.\n\n" +
+		"## User\n\nA registered user.\n\nCreated on sign up.\n\n" +
+		"### `User.name`\n\nDisplay name\n\n" +
+		"## IGetUserRequest\n\nSelects a user.\n\n```\n## Not a heading\n```\n\n" +
+		"## Query.getUser\n\nFetches a user by id.\n\n" +
+		"## Empty\n\n" +
+		"## Order\n\nNot generated.\n\n" +
+		"## User\n\nRepeated.\n"
+	if err := os.WriteFile(path, []byte(docs), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out string
+	_, stderr := captureOutput(t, func() { out = generate(t, "docs="+path, usersFile("users.proto", "users"))["users.graphql"] })
+	for _, want := range []string{
+		"\"\"\"\nA registered user.\n\nCreated on sign up.\n\"\"\"\ntype User {\n  \"Display name\"\n  name: String\n}",
+		"\"\"\"\nSelects a user.\n\n```\n## Not a heading\n```\n\"\"\"\ninput IGetUserRequest {\n  id: String\n}",
+		"type Query {\n  \"Fetches a user by id.\"\n  getUser(",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Intro") || strings.Contains(out, "Repeated.") {
+		t.Errorf("other sections should not be descriptions, got:\n%s", out)
+	}
+	for _, want := range []string{
+		"warning: docs section User is repeated, the first one is used",
+		"warning: docs section Order names no generated type, input, enum, field or operation",
+	} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("stderr should contain %q, got:\n%s", want, stderr)
+		}
+	}
+
+	stderr = generateError(t, "docs="+filepath.Join(t.TempDir(), "missing.md"), usersFile("users.proto", "users"))
+	if !strings.Contains(stderr, "error reading docs file") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+}
+
+func TestArgumentAndEnumDescriptions(t *testing.T) {
+	email := scalarField("email", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	email.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(email.Options, options.E_GqlExample, []string{"ada@example.com"})
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING), email),
+			testMessage("User", enumField("status", 1, ".users.Status")),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+	file.EnumType = []*descriptorpb.EnumDescriptorProto{testEnum("Status", "STATUS_UNSPECIFIED", 0, "STATUS_ACTIVE", 1)}
+
+	// Arguments with descriptions are written on their own lines, after their description
+	out := generate(t, "flatten_args", file)["users.graphql"]
+	want := "  getUser(\n    id: String\n    \"Example: ada@example.com\"\n    email: String\n  ): User!\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+	}
+
+	path := filepath.Join(t.TempDir(), "docs.md")
+	docs := "## Status\n\nState of an account.\n\nSet on sign up.\n\n## Status.STATUS_ACTIVE\n\nCan sign in\n"
+	if err := os.WriteFile(path, []byte(docs), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out = generate(t, "docs="+path, file)["users.graphql"]
+	want = "\"\"\"\nState of an account.\n\nSet on sign up.\n\"\"\"\nenum Status {\n  STATUS_UNSPECIFIED\n  \"Can sign in\"\n  STATUS_ACTIVE\n}"
+	if !strings.Contains(out, want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+	}
+}
@@ -1,7 +1,6 @@
 package descriptor
 
 import (
-	"log"
 	"strings"
 
 	"github.com/fverse/protoc-graphql/options"
@@ -17,6 +16,7 @@ const (
 	Float   GraphQLType = "Float"
 	Boolean GraphQLType = "Boolean"
 	String  GraphQLType = "String"
+	ID      GraphQLType = "ID"
 	Object  GraphQLType = "type"
 	Input   GraphQLType = "input"
 	Enum    GraphQLType = "enum"
@@ -30,6 +30,13 @@ type Mutation struct {
 	Input   *options.GqlInput
 	Payload *string
 	Skip    bool
+	// Arguments flattened from the fields of the request message, with flatten_args
+	Arguments []*Field
+	// If true, the payload is nullable, for payloads unwrapped from optional fields
+	NullablePayload bool
+	// Description and directives of the field, from exposed custom options
+	Description string
+	Directives  []string
 }
 
 // Represents GraphQL Query type
@@ -39,6 +46,13 @@ type Query struct {
 	Input   *options.GqlInput
 	Payload *string
 	Skip    bool
+	// Arguments flattened from the fields of the request message, with flatten_args
+	Arguments []*Field
+	// If true, the payload is nullable, for payloads unwrapped from optional fields
+	NullablePayload bool
+	// Description and directives of the field, from exposed custom options
+	Description string
+	Directives  []string
 }
 
 type ObjectType struct {
@@ -46,16 +60,51 @@ type ObjectType struct {
 	Name   *string
 	Nested []*ObjectType
 	Enums  []*Enumeration
+	// Proto file and message the type was generated from, e.g. "users.proto:User"
+	Source string
+	// Proto package the type was generated from, e.g. "acme.users"
+	Package string
+	// Directives written after the type name, e.g. @inaccessible
+	Directives []string
+	// Interfaces the type implements, e.g. Node
+	Interfaces []string
+	// Description of the type, from exposed custom options
+	Description string
 }
 
 type Enumeration struct {
 	Name   *string
-	Values []*string
+	Values []*EnumValue
+	// Proto file and enum the enum was generated from, e.g. "users.proto:Status"
+	Source string
+	// Proto package the enum was generated from, e.g. "acme.users"
+	Package string
+	// Description of the enum, from the docs file
+	Description string
+}
+
+// EnumValue represents a value of an enum
+type EnumValue struct {
+	Name *string
+	// Proto enum value number
+	Number int32
+	// Reason of the @deprecated directive, if the value is deprecated
+	Deprecation string
+	// Description of the value, from the docs file
+	Description string
 }
 
 type InputType struct {
 	Fields []*Field
 	Name   *string
+	// Proto file and message the type was generated from, e.g. "users.proto:User"
+	Source string
+	// Proto package the input was generated from, e.g. "acme.users"
+	Package string
+	// Directives written after the input name, e.g. @inaccessible
+	Directives []string
+	// Description of the input, from exposed custom options
+	Description string
 }
 
 // Field represents a field inside a an object type
@@ -65,6 +114,20 @@ type Field struct {
 	NonPrimitive bool
 	Optional     bool
 	IsList       bool
+	// If true, the list of a repeated field is non-null, with gql_element_nullable. Optional is about its elements
+	ListRequired bool
+	// Proto field number
+	Number int32
+	// Proto type of signed integer fields, e.g. "sint64", whose signedness annotate_source notes
+	SignedType string
+	// If true, Type is a custom scalar that must be declared in the schema
+	CustomScalar bool
+	// Directives written after the field type, e.g. @tag(name: "public")
+	Directives []string
+	// Values of the enum of a field generated as String, written as @values with enum_as_string_with_values
+	AllowedValues []string
+	// Description written before the field
+	Description string
 }
 
 type GqlOutput struct {
@@ -82,13 +145,22 @@ func (f *Field) GetType(field *descriptorpb.FieldDescriptorProto) {
 		descriptorpb.FieldDescriptorProto_TYPE_UINT32,
 		descriptorpb.FieldDescriptorProto_TYPE_UINT64:
 		f.Type = scalar(Int)
+	case descriptorpb.FieldDescriptorProto_TYPE_SINT32,
+		descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+		descriptorpb.FieldDescriptorProto_TYPE_SFIXED32,
+		descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+		// Signed integers are Int like int32 and int64, only their encoding differs
+		f.Type = scalar(Int)
+		f.SignedType = strings.ToLower(strings.TrimPrefix(field.GetType().String(), "TYPE_"))
 	case descriptorpb.FieldDescriptorProto_TYPE_FLOAT, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:
 		f.Type = scalar(Float)
 	case descriptorpb.FieldDescriptorProto_TYPE_BOOL:
 		f.Type = scalar(Boolean)
-	case descriptorpb.FieldDescriptorProto_TYPE_STRING:
+	case descriptorpb.FieldDescriptorProto_TYPE_STRING, descriptorpb.FieldDescriptorProto_TYPE_BYTES:
+		// bytes are base64 encoded strings in JSON
 		f.Type = scalar(String)
-	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE:
+	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+		// Groups, and message fields with the DELIMITED message encoding of editions, are messages with another wire encoding
 		if isWellKnownType(field) {
 			// TODO: This needs to mapped to a custom Gql scalar type instead of string
 			f.Type = scalar(String)
@@ -121,20 +193,19 @@ func (f *Field) IsOptional(field *descriptorpb.FieldDescriptorProto) {
 	f.Optional = isOptional(field)
 }
 
-// Checks if the field is required
-func (f *Field) IsRequired(field *descriptorpb.FieldDescriptorProto) {
-	f.Optional = !fieldRequired(field.GetOptions()) && !isRequired(field)
+// Checks if the field is required. presence is the field presence resolved from the features of the field and its parents
+func (f *Field) IsRequired(field *descriptorpb.FieldDescriptorProto, presence descriptorpb.FeatureSet_FieldPresence) {
+	f.Optional = !fieldRequired(field.GetOptions()) && !isRequired(field, presence)
 }
 
-// Check if the field is repeated
+// Check if the field is repeated.
+// Setting gql_element_nullable makes the list non-null, since repeated fields are never null, and sets the nullability of its elements
 func (f *Field) IsRepeated(field *descriptorpb.FieldDescriptorProto) {
 	f.IsList = isRepeated(field)
-}
-
-// Prints a message
-func (p *Field) Print(msg ...string) {
-	s := strings.Join(msg, " ")
-	log.Print(s)
+	if f.IsList && proto.HasExtension(field.GetOptions(), options.E_GqlElementNullable) {
+		f.ListRequired = true
+		f.Optional = proto.GetExtension(field.GetOptions(), options.E_GqlElementNullable).(bool)
+	}
 }
 
 func fieldRequired(fieldOptions *descriptorpb.FieldOptions) bool {
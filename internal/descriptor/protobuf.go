@@ -28,9 +28,12 @@ func isOptional(field *descriptorpb.FieldDescriptorProto) bool {
 	return field.Label != nil && *field.Label == descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
 }
 
-// Checks if the field is required
-func isRequired(field *descriptorpb.FieldDescriptorProto) bool {
-	return field.Label != nil && *field.Label == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED
+// Checks if the field is required: a proto2 required field, or a field with the LEGACY_REQUIRED presence of editions.
+// presence is the resolved presence of the field. Fields with explicit or implicit presence are optional,
+// like proto2 optional and proto3 fields
+func isRequired(field *descriptorpb.FieldDescriptorProto, presence descriptorpb.FeatureSet_FieldPresence) bool {
+	return field.Label != nil && *field.Label == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED ||
+		presence == descriptorpb.FeatureSet_LEGACY_REQUIRED
 }
 
 // Check if the field is repeated
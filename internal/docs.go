@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/fverse/protoc-graphql/pkg/utils"
+)
+
+// Headings of the docs file naming a definition or one of its fields, e.g. "## User" or "### `User.name`"
+var docsHeading = regexp.MustCompile("^#{1,6}\\s+`?([_A-Za-z][_0-9A-Za-z]*(?:\\.[_A-Za-z][_0-9A-Za-z]*)?)`?\\s*#*\\s*$")
+
+// parseDocs returns the sections of a Markdown docs file, keyed by the name of their heading, and the names of
+// the repeated sections. A section is the text after its heading, up to the next heading. Headings that don't
+// name a definition, e.g. "# Users API", end the section before them. Headings in fenced code blocks are text
+func parseDocs(content string) (map[string]string, []string) {
+	docs := make(map[string]string)
+	var repeated []string
+	name, lines, fenced := "", []string(nil), false
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(lines, "\n"))
+		if name == "" || text == "" {
+			return
+		}
+		if _, ok := docs[name]; ok {
+			repeated = append(repeated, name)
+			return
+		}
+		docs[name] = text
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+		}
+		if !fenced && strings.HasPrefix(line, "#") {
+			flush()
+			name, lines = "", nil
+			if match := docsHeading.FindStringSubmatch(line); match != nil {
+				name = match[1]
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return docs, repeated
+}
+
+// Reads the sections of the docs file, which override the descriptions of the definitions and fields they name
+func (plugin *Plugin) readDocs() {
+	if plugin.args.Docs == "" {
+		return
+	}
+	content, err := os.ReadFile(plugin.args.Docs)
+	if err != nil {
+		plugin.Error(err, "error reading docs file")
+	}
+	var repeated []string
+	plugin.docs, repeated = parseDocs(string(content))
+	plugin.docsUsed = make(map[string]bool)
+	for _, name := range repeated {
+		plugin.Logger.Warn("docs section %s is repeated, the first one is used", name)
+	}
+}
+
+// Warns about the sections of the docs file that name no generated definition or field
+func (plugin *Plugin) checkDocs() {
+	var unused []string
+	for name := range plugin.docs {
+		if !plugin.docsUsed[name] {
+			unused = append(unused, name)
+		}
+	}
+	slices.Sort(unused)
+	for _, name := range unused {
+		plugin.Logger.Warn("docs section %s names no generated type, input, enum, field or operation", name)
+	}
+}
+
+// Sets the descriptions of the schema's definitions and fields from the sections of the docs file naming them:
+// "User" and "User.name" for types and inputs, as named in the schema, "Status" and "Status.ACTIVE" for enums and their values,
+// "Query.getUser" and "Mutation.saveUser" for operations
+func (schema *Schema) applyDocs() {
+	if schema.plugin.docs == nil {
+		return
+	}
+	for _, objectType := range schema.objectTypes {
+		schema.applyDoc(&objectType.Description, *objectType.Name)
+		for _, field := range objectType.Fields {
+			schema.applyDoc(&field.Description, *objectType.Name+"."+*field.Name)
+		}
+	}
+	for _, inputType := range schema.inputTypes {
+		name := schema.inputTypeName(*inputType.Name)
+		schema.applyDoc(&inputType.Description, name)
+		for _, field := range inputType.Fields {
+			schema.applyDoc(&field.Description, name+"."+*field.Name)
+		}
+	}
+	for _, enum := range schema.enums {
+		schema.applyDoc(&enum.Description, *enum.Name)
+		for _, value := range enum.Values {
+			schema.applyDoc(&value.Description, *enum.Name+"."+*value.Name)
+		}
+	}
+	for _, query := range schema.queries {
+		schema.applyDoc(&query.Description, "Query."+utils.LowercaseFirst(*query.Name))
+	}
+	for _, mutation := range schema.mutations {
+		schema.applyDoc(&mutation.Description, "Mutation."+utils.LowercaseFirst(*mutation.Name))
+	}
+}
+
+// Replaces a description with the section of the docs file named name, if there is one
+func (schema *Schema) applyDoc(description *string, name string) {
+	if text, ok := schema.plugin.docs[name]; ok {
+		*description = text
+		schema.plugin.docsUsed[name] = true
+	}
+}
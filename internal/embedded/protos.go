@@ -5,6 +5,11 @@ import (
 	"path/filepath"
 )
 
+// OptionsVersion is the version of the embedded options.proto.
+// It is incremented whenever an option or extension number changes,
+// so vendored copies can be checked against the plugin with `options --version`
+const OptionsVersion = "13"
+
 // OptionsProto contains the embedded options.proto content
 const OptionsProto = `syntax = "proto3";
 
@@ -31,15 +36,38 @@ message MethodOptions {
   GqlInput gql_input = 50003;
   string gql_output = 50004;
   bool skip = 50005;
+  string gql_args_type = 50006;
 }
 
 extend google.protobuf.MessageOptions {
   bool skip = 50011;
+  optional string gql_type_name = 50012;
+  repeated string gql_type_tag = 50013;
+  optional bool gql_type_inaccessible = 50014;
+  optional bool gql_resource_query = 50015;
 }
 
 extend google.protobuf.FieldOptions {
   optional bool required = 50021;
   optional bool keep_case = 50022;
+  optional string gql_name = 50023;
+  repeated string gql_tag = 50024;
+  optional bool gql_inaccessible = 50025;
+  repeated string gql_example = 50026;
+  optional bool gql_input_optional = 50027;
+  optional bool gql_input_required = 50028;
+  optional bool gql_id = 50029;
+  optional bool gql_element_nullable = 50030;
+  optional bool gql_non_empty = 50031;
+  optional bool gql_output_only = 50032;
+  optional bool gql_input_only = 50033;
+}
+
+extend google.protobuf.EnumOptions {
+  optional bool gql_as_scalar = 50041;
+}
+extend google.protobuf.EnumValueOptions {
+  optional string gql_value_name = 50051;
 }
 `
 
@@ -1,9 +1,22 @@
 package internal
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
+	"github.com/fverse/protoc-graphql/internal/analyzer"
+	"github.com/fverse/protoc-graphql/internal/descriptor"
 	"github.com/fverse/protoc-graphql/pkg/utils"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/pluginpb"
 )
@@ -20,11 +33,142 @@ func (plugin *Plugin) isFileExplicit(protoFile *descriptorpb.FileDescriptorProto
 
 // Generates the protoc response
 func (plugin *Plugin) Execute() {
-	plugin.processProtoFiles()
-	plugin.generateOutput()
+	plugin.dumpRequest()
+	plugin.readPreamble()
+	plugin.readDocs()
+	plugin.checkOptionsCompatibility()
+	plugin.checkSectionOrder()
+	plugin.checkImportCycles()
+	plugin.resolveExposedOptions()
+	plugin.checkServices()
+	plugin.checkTargets()
+	if plugin.args.SplitByTarget {
+		plugin.generateTargets()
+	} else {
+		plugin.processProtoFiles()
+		plugin.generateOutput()
+	}
+	plugin.normalizeNewlines()
+	plugin.generateManifest()
+	plugin.checkDocs()
+	plugin.checkWarnings()
+}
+
+// Ends every generated file with exactly one newline, and converts CRLF line endings, e.g. of prepend files
+// or proto comments, to LF. It runs before the manifest hashes the files
+func (plugin *Plugin) normalizeNewlines() {
+	for _, file := range plugin.Response.File {
+		content := strings.ReplaceAll(file.GetContent(), "\r\n", "\n")
+		content = strings.ReplaceAll(content, "\r", "\n")
+		file.Content = utils.String(strings.TrimRight(content, "\n") + "\n")
+	}
+}
+
+// Fails generation if several targets are set without split_by_target, or none with it
+func (plugin *Plugin) checkTargets() {
+	if len(plugin.args.Targets) > 1 && !plugin.args.SplitByTarget {
+		plugin.Error(fmt.Errorf("got %s, generate several targets with split_by_target", strings.Join(plugin.args.Targets, ",")),
+			"invalid target")
+	}
+	if plugin.args.SplitByTarget && len(plugin.args.Targets) == 0 {
+		plugin.Error(errors.New("no target is set"), "invalid split_by_target")
+	}
+}
+
+// Fails generation if fail_on_warning is set and any warning was emitted
+func (plugin *Plugin) checkWarnings() {
+	if !plugin.args.FailOnWarning || plugin.Logger.Warnings() == 0 {
+		return
+	}
+	plugin.Error(fmt.Errorf("%d warning(s) emitted", plugin.Logger.Warnings()), "generation failed with fail_on_warning")
+}
+
+// Fails generation unless the section_order option lists every section exactly once
+func (plugin *Plugin) checkSectionOrder() {
+	order := plugin.args.SectionOrder
+	if len(order) == 0 {
+		return
+	}
+	valid := len(order) == len(defaultSectionOrder)
+	for _, section := range defaultSectionOrder {
+		valid = valid && slices.Contains(order, section)
+	}
+	if !valid {
+		plugin.Error(fmt.Errorf("got %s, expected types, inputs, enums and operations, each exactly once", strings.Join(order, ",")),
+			"invalid section_order")
+	}
+}
+
+// Warns about cycles of imports among the proto files, which protoc rejects.
+// Types of files in a cycle may not resolve as expected
+func (plugin *Plugin) checkImportCycles() {
+	for _, cycle := range analyzer.ImportCycles(plugin.Request.ProtoFile) {
+		plugin.Logger.Warn("import cycle %s, fix the imports of these files to get a complete schema",
+			strings.Join(cycle, " -> "))
+	}
+}
+
+// Warns about the services selected by the service option that no generated proto file defines
+func (plugin *Plugin) checkServices() {
+	for _, name := range plugin.args.Services {
+		found := false
+		for _, protoFile := range plugin.Request.ProtoFile {
+			if !plugin.isFileExplicit(protoFile) {
+				continue
+			}
+			for _, service := range protoFile.Service {
+				found = found || service.GetName() == name
+			}
+		}
+		if !found {
+			plugin.Logger.Warn("service %s is not defined by the generated proto files", name)
+		}
+	}
+}
+
+// Writes the request to the dump_request file, as received from protoc
+func (plugin *Plugin) dumpRequest() {
+	path := plugin.args.DumpRequest
+	if path == "" {
+		return
+	}
+	var content []byte
+	var err error
+	if ext := filepath.Ext(path); ext == ".txtpb" || ext == ".textproto" {
+		content, err = prototext.MarshalOptions{Multiline: true}.Marshal(plugin.Request)
+	} else {
+		content, err = proto.Marshal(plugin.Request)
+	}
+	if err == nil {
+		err = os.WriteFile(path, content, 0644)
+	}
+	if err != nil {
+		plugin.Error(err, "error writing dump_request file")
+	}
+	plugin.Logger.Log("request written to %s", path)
+}
+
+// Reads the handwritten SDL that is prepended to every output file
+func (plugin *Plugin) readPreamble() {
+	if plugin.args.Prepend == "" {
+		return
+	}
+	content, err := os.ReadFile(plugin.args.Prepend)
+	if err != nil {
+		plugin.Error(err, "error reading prepend file")
+	}
+	plugin.preamble = strings.TrimSpace(string(content))
 }
 
 func (plugin *Plugin) processProtoFiles() {
+	// protoc sends the descriptor of every file to generate, malformed requests may not
+	for _, file := range plugin.Request.FileToGenerate {
+		if !slices.ContainsFunc(plugin.Request.ProtoFile, func(protoFile *descriptorpb.FileDescriptorProto) bool {
+			return protoFile.GetName() == file
+		}) {
+			plugin.Error(fmt.Errorf("%s is in file_to_generate but the request has no descriptor for it", file), "invalid request")
+		}
+	}
 	for _, protoFile := range plugin.Request.ProtoFile {
 		if !plugin.isFileExplicit(protoFile) {
 			continue
@@ -35,86 +179,360 @@ func (plugin *Plugin) processProtoFiles() {
 }
 
 func (plugin *Plugin) generateOutput() {
+	if plugin.args.OperationsFile != "" && !plugin.args.CombineOutput {
+		plugin.Logger.Warn("operations_file is ignored without combine_output")
+	}
+	if plugin.args.NoDedup && !plugin.args.CombineOutput {
+		plugin.Logger.Warn("no_dedup is ignored without combine_output")
+	}
+	if plugin.args.EnumsFile != "" && plugin.args.CombineOutput {
+		plugin.Logger.Warn("enums_file is ignored with combine_output")
+	}
 	if plugin.args.CombineOutput {
 		plugin.generateCombinedOutput()
+	} else {
+		plugin.generateSeparateOutputs()
+	}
+}
+
+// Generates the combined schema of each target with split_by_target, named by output_filename, {target}.graphql by default.
+// The types of each schema are the types reachable from the RPCs of its target
+func (plugin *Plugin) generateTargets() {
+	args := plugin.args
+	defer func() { plugin.args = args }()
+
+	// Targets of the generated files, whose names must differ
+	targets := make(map[string]string)
+	for _, target := range args.Targets {
+		targetArgs := *args
+		targetArgs.Target = target
+		plugin.args = &targetArgs
+		plugin.schema = nil
+		plugin.processProtoFiles()
+
+		files := len(plugin.Response.File)
+		plugin.generateCombinedOutput()
+		for _, file := range plugin.Response.File[files:] {
+			if other, ok := targets[file.GetName()]; ok {
+				plugin.Error(fmt.Errorf("targets %s and %s both generate %s, add {target} to the file name", other, target, file.GetName()),
+					"error generating output")
+			}
+			targets[file.GetName()] = target
+		}
+	}
+}
+
+// A file listed in the manifest
+type manifestFile struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+}
+
+// Writes the manifest file listing the generated files and the hashes of their content, if manifest is set
+func (plugin *Plugin) generateManifest() {
+	if plugin.args.Manifest == "" {
 		return
 	}
-	plugin.generateSeparateOutputs()
+	manifest := struct {
+		Files []manifestFile `json:"files"`
+	}{Files: []manifestFile{}}
+	for _, file := range plugin.Response.File {
+		if file.GetName() == plugin.args.Manifest {
+			plugin.Error(fmt.Errorf("manifest and a generated file are both %s", file.GetName()), "error generating manifest")
+		}
+		hash := sha256.Sum256([]byte(file.GetContent()))
+		manifest.Files = append(manifest.Files, manifestFile{Name: file.GetName(), SHA256: hex.EncodeToString(hash[:])})
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		plugin.Error(err, "error generating manifest")
+	}
+	plugin.Response.File = append(plugin.Response.File, &pluginpb.CodeGeneratorResponse_File{
+		Name:    utils.String(plugin.args.Manifest),
+		Content: utils.String(string(content) + "\n"),
+	})
 }
 
 func (plugin *Plugin) generateCombinedOutput() {
 	var combinedSchema = new(Schema)
 	combinedSchema.Builder = new(strings.Builder)
 	combinedSchema.args = plugin.args
+	combinedSchema.preamble = plugin.preamble
 
 	// Track already-generated type names for deduplication
 	seenObjectTypes := make(map[string]bool)
-	seenEnums := make(map[string]bool)
+	seenEnums := make(map[string]*descriptor.Enumeration)
 	seenInputTypes := make(map[string]bool)
 	seenMutations := make(map[string]bool)
 	seenQueries := make(map[string]bool)
+	seenScalars := make(map[string]bool)
+	// Checks if a name is generated for the first time and records it. Every name is new with no_dedup
+	firstSeen := func(seen map[string]bool, name string) bool {
+		if seen[name] && !plugin.args.NoDedup {
+			return false
+		}
+		seen[name] = true
+		return true
+	}
 
+	var descriptions []string
+	usedDirectives := make(map[string]bool)
 	for _, schema := range plugin.schema {
+		for _, directive := range schema.federationImports {
+			usedDirectives[directive] = true
+		}
+		combinedSchema.declareValues = combinedSchema.declareValues || schema.declareValues
+		combinedSchema.declareConstraint = combinedSchema.declareConstraint || schema.declareConstraint
+
+		if schema.description != "" {
+			descriptions = append(descriptions, schema.description)
+		}
+
+		// Deduplicate scalars
+		for _, scalar := range schema.scalars {
+			if firstSeen(seenScalars, scalar) {
+				combinedSchema.scalars = append(combinedSchema.scalars, scalar)
+			}
+		}
+
 		// Deduplicate object types
 		for _, objType := range schema.objectTypes {
-			if objType.Name != nil && !seenObjectTypes[*objType.Name] {
-				seenObjectTypes[*objType.Name] = true
+			if objType.Name != nil && firstSeen(seenObjectTypes, *objType.Name) {
 				combinedSchema.objectTypes = append(combinedSchema.objectTypes, objType)
 			}
 		}
 
-		// Deduplicate enums
+		// Deduplicate enums. Enums of the same name merge if they have the same values
 		for _, enum := range schema.enums {
-			if enum.Name != nil && !seenEnums[*enum.Name] {
-				seenEnums[*enum.Name] = true
-				combinedSchema.enums = append(combinedSchema.enums, enum)
+			if enum.Name == nil {
+				continue
+			}
+			if seen, ok := seenEnums[*enum.Name]; ok && !plugin.args.NoDedup {
+				if !sameEnumValues(seen, enum) {
+					plugin.Error(fmt.Errorf("%s and %s both generate enum %s with different values", seen.Source, enum.Source, *enum.Name),
+						"error combining output")
+				}
+				continue
 			}
+			seenEnums[*enum.Name] = enum
+			combinedSchema.enums = append(combinedSchema.enums, enum)
 		}
 
 		// Deduplicate input types
 		for _, inputType := range schema.inputTypes {
-			if inputType.Name != nil && !seenInputTypes[*inputType.Name] {
-				seenInputTypes[*inputType.Name] = true
+			if inputType.Name != nil && firstSeen(seenInputTypes, *inputType.Name) {
 				combinedSchema.inputTypes = append(combinedSchema.inputTypes, inputType)
 			}
 		}
 
 		// Deduplicate mutations
 		for _, mutation := range schema.mutations {
-			if mutation.Name != nil && !seenMutations[*mutation.Name] {
-				seenMutations[*mutation.Name] = true
+			if mutation.Name != nil && firstSeen(seenMutations, *mutation.Name) {
 				combinedSchema.mutations = append(combinedSchema.mutations, mutation)
 			}
 		}
 
 		// Deduplicate queries
 		for _, query := range schema.queries {
-			if query.Name != nil && !seenQueries[*query.Name] {
-				seenQueries[*query.Name] = true
+			if query.Name != nil && firstSeen(seenQueries, *query.Name) {
 				combinedSchema.queries = append(combinedSchema.queries, query)
 			}
 		}
 	}
-	combinedSchema.generate()
+	combinedSchema.description = strings.Join(descriptions, "\n\n")
+	for _, directive := range federationDirectives {
+		if usedDirectives[directive] {
+			combinedSchema.federationImports = append(combinedSchema.federationImports, directive)
+		}
+	}
+	if plugin.args.OperationsFile != "" {
+		combinedSchema.generateDefinitions()
+	} else {
+		combinedSchema.generate()
+	}
 
-	// Use custom output filename if provided, otherwise default to "schema.graphql"
-	outputFileName := "schema.graphql"
+	// Use custom output filename if provided, otherwise default to "schema.graphql",
+	// or the name of the target with split_by_target, with the extension of file_ext
+	outputFileName := "schema" + plugin.args.FileExtension()
+	if plugin.args.SplitByTarget {
+		outputFileName = "{target}" + plugin.args.FileExtension()
+	}
 	if len(plugin.args.OutputFileNames) > 0 {
 		outputFileName = plugin.args.OutputFileNames[0]
 	}
+	if len(plugin.args.OutputFileNames) > 0 || plugin.args.SplitByTarget {
+		name, err := plugin.expandFileName(outputFileName)
+		if err != nil {
+			plugin.Error(err, "error generating output")
+		}
+		outputFileName = name
+	}
 
 	plugin.Response.File = append(plugin.Response.File, &pluginpb.CodeGeneratorResponse_File{
 		Name:    utils.String(outputFileName),
 		Content: utils.String(combinedSchema.String()),
 	})
+
+	if plugin.args.OperationsFile != "" {
+		plugin.generateOperationsFile(combinedSchema, outputFileName)
+	}
+}
+
+// Checks if two enums have the same values, in any order
+func sameEnumValues(a, b *descriptor.Enumeration) bool {
+	if len(a.Values) != len(b.Values) {
+		return false
+	}
+	numbers := make(map[string]int32, len(a.Values))
+	for _, value := range a.Values {
+		numbers[*value.Name] = value.Number
+	}
+	for _, value := range b.Values {
+		if number, ok := numbers[*value.Name]; !ok || number != value.Number {
+			return false
+		}
+	}
+	return true
+}
+
+// Writes the Query and Mutation roots of the combined schema to the operations file.
+// Its name is expanded like the combined output file name with split_by_target
+func (plugin *Plugin) generateOperationsFile(combinedSchema *Schema, outputFileName string) {
+	operationsFileName := plugin.args.OperationsFile
+	if plugin.args.SplitByTarget {
+		name, err := plugin.expandFileName(operationsFileName)
+		if err != nil {
+			plugin.Error(err, "error generating output")
+		}
+		operationsFileName = name
+	}
+	if operationsFileName == outputFileName {
+		plugin.Error(fmt.Errorf("operations file and combined output are both %s", outputFileName), "error generating output")
+	}
+
+	operations := new(Schema)
+	operations.Builder = new(strings.Builder)
+	operations.args = plugin.args
+	operations.queries = combinedSchema.queries
+	operations.mutations = combinedSchema.mutations
+	operations.description = combinedSchema.description
+
+	operations.WriteHeader()
+	operations.WriteDescription()
+	operations.generateOperations()
+
+	plugin.Response.File = append(plugin.Response.File, &pluginpb.CodeGeneratorResponse_File{
+		Name:    utils.String(operationsFileName),
+		Content: utils.String(operations.String()),
+	})
+}
+
+// Tokens of the combined output file name, e.g. {target}.graphql
+var fileNameToken = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// Expands the tokens of the combined output file name: {target} is the target option,
+// {package} the proto package shared by the generated files
+func (plugin *Plugin) expandFileName(template string) (string, error) {
+	var err error
+	name := fileNameToken.ReplaceAllStringFunc(template, func(token string) string {
+		value, tokenErr := plugin.fileNameToken(strings.Trim(token, "{}"))
+		if tokenErr != nil && err == nil {
+			err = fmt.Errorf("can't resolve %s in output file name %s: %w", token, template, tokenErr)
+		}
+		return value
+	})
+	return name, err
+}
+
+// Returns the value of a token of the combined output file name
+func (plugin *Plugin) fileNameToken(token string) (string, error) {
+	switch token {
+	case "target":
+		if plugin.args.Target == "" {
+			return "", errors.New("no target is set")
+		}
+		return plugin.args.Target, nil
+	case "package":
+		var packageName string
+		for _, schema := range plugin.schema {
+			switch pkg := schema.protoFile.GetPackage(); {
+			case pkg == "":
+				return "", fmt.Errorf("%s has no package", schema.protoFile.GetName())
+			case packageName != "" && pkg != packageName:
+				return "", fmt.Errorf("the files have different packages, %s and %s", packageName, pkg)
+			default:
+				packageName = pkg
+			}
+		}
+		if packageName == "" {
+			return "", errors.New("no file is generated")
+		}
+		return packageName, nil
+	}
+	return "", errors.New("unknown token, expected {target} or {package}")
 }
 
 func (plugin *Plugin) generateSeparateOutputs() {
-	for _, schema := range plugin.schema {
+	// Proto files whose output file names collide, e.g. with flatten_names
+	sources := make(map[string]string)
+	var enums []*descriptor.Enumeration
+	if plugin.args.EnumsFile != "" {
+		enums = plugin.moveEnums()
+	}
+
+	for i, schema := range plugin.schema {
+		if source, ok := sources[*schema.fileName]; ok {
+			plugin.Error(fmt.Errorf("%s and %s both generate %s", source, schema.protoFile.GetName(), *schema.fileName),
+				"error generating output")
+		}
+		sources[*schema.fileName] = schema.protoFile.GetName()
+
+		// The first output file defines the roots, the next ones extend them
+		schema.extendRoots = plugin.args.ExtendRoots && i > 0
 		schema.generate()
 		plugin.Response.File = append(plugin.Response.File, &pluginpb.CodeGeneratorResponse_File{
 			Name:    schema.fileName,
 			Content: utils.String(schema.String()),
 		})
 	}
+
+	if len(enums) == 0 {
+		return
+	}
+	if source, ok := sources[plugin.args.EnumsFile]; ok {
+		plugin.Error(fmt.Errorf("enums file and the output of %s are both %s", source, plugin.args.EnumsFile), "error generating output")
+	}
+	enumsFile := new(Schema)
+	enumsFile.Builder = new(strings.Builder)
+	enumsFile.args = plugin.args
+	enumsFile.enums = enums
+	enumsFile.WriteHeader()
+	enumsFile.generateEnums()
+	plugin.Response.File = append(plugin.Response.File, &pluginpb.CodeGeneratorResponse_File{
+		Name:    utils.String(plugin.args.EnumsFile),
+		Content: utils.String(enumsFile.String()),
+	})
+}
+
+// Removes the enums from the schemas of the proto files and returns them for the enums file, in the order of the files.
+// An enum several files reference is written once, enums of the same name must have the same values
+func (plugin *Plugin) moveEnums() []*descriptor.Enumeration {
+	var enums []*descriptor.Enumeration
+	seen := make(map[string]*descriptor.Enumeration)
+	for _, schema := range plugin.schema {
+		for _, enum := range schema.enums {
+			if other, ok := seen[*enum.Name]; ok {
+				if !sameEnumValues(other, enum) {
+					plugin.Error(fmt.Errorf("%s and %s both generate enum %s with different values", other.Source, enum.Source, *enum.Name),
+						"error generating enums file")
+				}
+				continue
+			}
+			seen[*enum.Name] = enum
+			enums = append(enums, enum)
+		}
+		schema.enums = nil
+	}
+	return enums
 }
@@ -0,0 +1,780 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fverse/protoc-graphql/options"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// usersFile returns a proto file with a GetUser query in the given package
+func usersFile(name, pkg string) *descriptorpb.FileDescriptorProto {
+	return testFile(name, pkg,
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("GetUser", "."+pkg+".GetUserRequest", "."+pkg+".User", nil),
+	)
+}
+
+func TestPrepend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scalars.graphql")
+	if err := os.WriteFile(path, []byte("scalar DateTime\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("separate output", func(t *testing.T) {
+		out := generate(t, "prepend="+path, usersFile("users.proto", "users"))["users.graphql"]
+
+		header := "# " + NAME + " " + Version + "\n\n"
+		if !strings.Contains(out, header+"scalar DateTime\n\ntype User {") {
+			t.Errorf("preamble should follow the header, got:\n%s", out)
+		}
+	})
+
+	t.Run("combined output", func(t *testing.T) {
+		out := generate(t, "combine_output,prepend="+path,
+			usersFile("users.proto", "users"),
+			usersFile("people.proto", "people"),
+		)["schema.graphql"]
+
+		if strings.Count(out, "scalar DateTime") != 1 {
+			t.Errorf("preamble should appear once in combined output, got:\n%s", out)
+		}
+	})
+}
+
+func TestTrailingNewline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scalars.graphql")
+	if err := os.WriteFile(path, []byte("scalar DateTime\r\n\r\nscalar Date\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	users := usersFile("users.proto", "users")
+	users.SourceCodeInfo = &descriptorpb.SourceCodeInfo{
+		Location: []*descriptorpb.SourceCodeInfo_Location{
+			{Path: []int32{12}, LeadingComments: proto.String(" The users API.\r\n\r\n Gets users.\r\n")},
+		},
+	}
+
+	sectionOrder := "section_order=operations,section_order=types,section_order=inputs,section_order=enums"
+	for _, parameter := range []string{
+		"",
+		"prepend=" + path,
+		sectionOrder,
+		"combine_output",
+		"combine_output," + sectionOrder,
+		"combine_output,operations_file=operations.graphql",
+		"target=a,target=b,split_by_target",
+		"manifest=manifest.json",
+	} {
+		for name, content := range generate(t, parameter, users, usersFile("people.proto", "people")) {
+			if !strings.HasSuffix(content, "\n") || strings.HasSuffix(content, "\n\n") {
+				t.Errorf("%q: %s should end with exactly one newline, got %q", parameter, name, content[max(0, len(content)-10):])
+			}
+			if strings.Contains(content, "\r") {
+				t.Errorf("%q: %s should have LF line endings, got %q", parameter, name, content)
+			}
+		}
+	}
+}
+
+// eventsFile returns a proto file whose output type has a Timestamp field
+func eventsFile(name, pkg string) *descriptorpb.FileDescriptorProto {
+	return testFile(name, pkg,
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetEventRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Event",
+				messageField("starts_at", 1, ".google.protobuf.Timestamp"),
+				messageField("ends_at", 2, ".google.protobuf.Timestamp"),
+			),
+		},
+		testMethod("GetEvent", "."+pkg+".GetEventRequest", "."+pkg+".Event", nil),
+	)
+}
+
+func TestDumpRequest(t *testing.T) {
+	for _, format := range []struct {
+		name      string
+		unmarshal func([]byte, proto.Message) error
+	}{
+		{"request.binpb", proto.Unmarshal},
+		{"request.txtpb", prototext.Unmarshal},
+	} {
+		t.Run(format.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), format.name)
+			parameter := "dump_request=" + path
+			out := generate(t, parameter, usersFile("users.proto", "users"))
+			if !strings.Contains(out["users.graphql"], "type User {") {
+				t.Errorf("output should be generated, got:\n%s", out["users.graphql"])
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dumped := new(pluginpb.CodeGeneratorRequest)
+			if err := format.unmarshal(content, dumped); err != nil {
+				t.Fatalf("dumped request should parse: %v", err)
+			}
+			want := &pluginpb.CodeGeneratorRequest{
+				Parameter:      proto.String(parameter),
+				FileToGenerate: []string{"users.proto"},
+				ProtoFile:      []*descriptorpb.FileDescriptorProto{usersFile("users.proto", "users")},
+			}
+			if !proto.Equal(dumped, want) {
+				t.Errorf("dumped request differs from the request:\n%v", dumped)
+			}
+		})
+	}
+
+	stderr := generateError(t, "dump_request="+filepath.Join(t.TempDir(), "missing", "request.binpb"), usersFile("users.proto", "users"))
+	if !strings.Contains(stderr, "error writing dump_request file") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	files := []*descriptorpb.FileDescriptorProto{usersFile("users.proto", "users"), usersFile("people.proto", "people")}
+	out := generate(t, "manifest=manifest.json", files...)
+
+	var manifest struct {
+		Files []struct {
+			Name   string `json:"name"`
+			SHA256 string `json:"sha256"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(out["manifest.json"]), &manifest); err != nil {
+		t.Fatalf("manifest should be JSON: %v\n%s", err, out["manifest.json"])
+	}
+	var names []string
+	for _, file := range manifest.Files {
+		names = append(names, file.Name)
+		hash := sha256.Sum256([]byte(out[file.Name]))
+		if file.SHA256 != hex.EncodeToString(hash[:]) {
+			t.Errorf("hash of %s should be the hash of its content, got %s", file.Name, file.SHA256)
+		}
+	}
+	if want := []string{"users.graphql", "people.graphql"}; !slices.Equal(names, want) {
+		t.Errorf("manifest should list %v, got %v", want, names)
+	}
+
+	// The hashes are stable across runs
+	if again := generate(t, "manifest=manifest.json", files...)["manifest.json"]; again != out["manifest.json"] {
+		t.Errorf("manifest should be stable, got:\n%s\nthen:\n%s", out["manifest.json"], again)
+	}
+
+	out = generate(t, "combine_output,operations_file=operations.graphql,manifest=manifest.json", files...)
+	for _, name := range []string{"schema.graphql", "operations.graphql"} {
+		if !strings.Contains(out["manifest.json"], `"name": "`+name+`"`) {
+			t.Errorf("manifest should list %s, got:\n%s", name, out["manifest.json"])
+		}
+	}
+
+	stderr := generateError(t, "manifest=users.graphql", files...)
+	if !strings.Contains(stderr, "error generating manifest: manifest and a generated file are both users.graphql") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+}
+
+func TestScalarDeclarations(t *testing.T) {
+	const scalarOption = "scalar=google.protobuf.Timestamp:DateTime"
+
+	t.Run("separate output", func(t *testing.T) {
+		out := generate(t, scalarOption, eventsFile("events.proto", "events"), usersFile("users.proto", "users"))
+
+		if strings.Count(out["events.graphql"], "scalar DateTime\n") != 1 {
+			t.Errorf("events.graphql should declare DateTime once, got:\n%s", out["events.graphql"])
+		}
+		if !strings.Contains(out["events.graphql"], "  startsAt: DateTime\n") {
+			t.Errorf("Timestamp fields should map to DateTime, got:\n%s", out["events.graphql"])
+		}
+		if strings.Contains(out["users.graphql"], "scalar DateTime") {
+			t.Errorf("users.graphql should not declare an unused scalar, got:\n%s", out["users.graphql"])
+		}
+	})
+
+	t.Run("combined output", func(t *testing.T) {
+		out := generate(t, "combine_output,"+scalarOption,
+			eventsFile("events.proto", "events"),
+			eventsFile("meetings.proto", "meetings"),
+		)["schema.graphql"]
+
+		if strings.Count(out, "scalar DateTime\n") != 1 {
+			t.Errorf("combined output should declare DateTime once, got:\n%s", out)
+		}
+	})
+
+	t.Run("unmapped", func(t *testing.T) {
+		out := generate(t, "", eventsFile("events.proto", "events"))["events.graphql"]
+
+		if strings.Contains(out, "scalar ") || !strings.Contains(out, "  startsAt: String\n") {
+			t.Errorf("Timestamp should map to String without the scalar option, got:\n%s", out)
+		}
+	})
+}
+
+func TestScalarSpecifiedBy(t *testing.T) {
+	file := testFile("events.proto", "events",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetEventRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Event",
+				messageField("starts_at", 1, ".google.protobuf.Timestamp"),
+				messageField("data", 2, ".google.protobuf.Struct"),
+			),
+		},
+		testMethod("GetEvent", ".events.GetEventRequest", ".events.Event", nil),
+	)
+
+	const parameter = "scalar=google.protobuf.Timestamp:DateTime,scalar=google.protobuf.Struct:JSON," +
+		"scalar_spec=DateTime=https://scalars.graphql.org/andimarek/date-time"
+	out := generate(t, parameter, file)["events.graphql"]
+
+	want := "scalar DateTime @specifiedBy(url: \"https://scalars.graphql.org/andimarek/date-time\")\nscalar JSON\n\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("configured scalars should be specified by their URL, got:\n%s", out)
+	}
+}
+
+func TestDoubleScalar(t *testing.T) {
+	measurement := testMessage("Measurement",
+		scalarField("ratio", 1, descriptorpb.FieldDescriptorProto_TYPE_FLOAT),
+		scalarField("value", 2, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE),
+		scalarField("error", 3, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE),
+	)
+	file := func(name, pkg string) *descriptorpb.FileDescriptorProto {
+		return testFile(name, pkg,
+			[]*descriptorpb.DescriptorProto{
+				testMessage("GetMeasurementRequest", scalarField("min", 1, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE)),
+				measurement,
+			},
+			testMethod("GetMeasurement", "."+pkg+".GetMeasurementRequest", "."+pkg+".Measurement", nil),
+		)
+	}
+
+	t.Run("custom scalar", func(t *testing.T) {
+		out := generate(t, "double_scalar=Float64", file("sensors.proto", "sensors"))["sensors.graphql"]
+		for _, want := range []string{
+			"scalar Float64\n\n",
+			"type Measurement {\n  ratio: Float\n  value: Float64\n  error: Float64\n}",
+			"input IGetMeasurementRequest {\n  min: Float64\n}",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("output should contain %q, got:\n%s", want, out)
+			}
+		}
+		if strings.Count(out, "scalar Float64") != 1 {
+			t.Errorf("Float64 should be declared once, got:\n%s", out)
+		}
+	})
+
+	t.Run("combined output", func(t *testing.T) {
+		out := generate(t, "combine_output,double_scalar=Float64", file("sensors.proto", "sensors"), file("meters.proto", "meters"))["schema.graphql"]
+		if strings.Count(out, "scalar Float64") != 1 {
+			t.Errorf("Float64 should be declared once in combined output, got:\n%s", out)
+		}
+	})
+
+	t.Run("default", func(t *testing.T) {
+		for _, parameter := range []string{"", "double_scalar=Float"} {
+			out := generate(t, parameter, file("sensors.proto", "sensors"))["sensors.graphql"]
+			if strings.Contains(out, "scalar ") || !strings.Contains(out, "  value: Float\n") {
+				t.Errorf("%q: doubles should be Float, got:\n%s", parameter, out)
+			}
+		}
+	})
+}
+
+func TestOutputFileNames(t *testing.T) {
+	tests := []struct {
+		parameter string
+		want      string
+	}{
+		{"", "a/b/c/users.graphql"},
+		{"strip_path_prefix=a/b", "c/users.graphql"},
+		{"strip_path_prefix=a/b/", "c/users.graphql"},
+		{"strip_path_prefix=x", "a/b/c/users.graphql"},
+		// Prefixes only strip whole path components
+		{"strip_path_prefix=a/b/c/use", "a/b/c/users.graphql"},
+		{"flatten_names", "users.graphql"},
+		{"strip_path_prefix=a,flatten_names", "users.graphql"},
+		{"file_ext=graphqls", "a/b/c/users.graphqls"},
+		{"file_ext=.graphqls", "a/b/c/users.graphqls"},
+		{"flatten_names,file_ext=gql", "users.gql"},
+		// An invalid extension keeps the default
+		{"file_ext=.", "a/b/c/users.graphql"},
+	}
+	for _, tt := range tests {
+		out := generate(t, tt.parameter, usersFile("a/b/c/users.proto", "users"))
+		if _, ok := out[tt.want]; !ok || len(out) != 1 {
+			t.Errorf("%q: expected output file %s, got %v", tt.parameter, tt.want, keys(out))
+		}
+	}
+
+	if out := generate(t, "strip_path_prefix=proto", usersFile("protos/users.proto", "users")); out["protos/users.graphql"] == "" {
+		t.Errorf("strip_path_prefix=proto should keep protos/, got %v", keys(out))
+	}
+
+	stderr := generateError(t, "flatten_names", usersFile("a/users.proto", "a"), usersFile("b/users.proto", "b"))
+	if !strings.Contains(stderr, "a/users.proto and b/users.proto both generate users.graphql") {
+		t.Errorf("colliding output file names should be reported, got %q", stderr)
+	}
+}
+
+func TestCombinedOutputFileNameTemplate(t *testing.T) {
+	tests := []struct {
+		parameter string
+		want      string
+	}{
+		{"target=admin,output_filenames={target}.graphql", "admin.graphql"},
+		{"target=admin,output_filenames=schemas/{package}-{target}.graphql", "schemas/users-admin.graphql"},
+		{"output_filenames=api.graphql", "api.graphql"},
+		{"file_ext=graphqls", "schema.graphqls"},
+		{"file_ext=.graphqls", "schema.graphqls"},
+		// Output file names are kept as given
+		{"file_ext=graphqls,output_filenames=api.graphql", "api.graphql"},
+	}
+	for _, tt := range tests {
+		out := generate(t, "combine_output,"+tt.parameter, usersFile("users.proto", "users"))
+		if _, ok := out[tt.want]; !ok || len(out) != 1 {
+			t.Errorf("%q: expected output file %s, got %v", tt.parameter, tt.want, keys(out))
+		}
+	}
+
+	failures := []struct {
+		parameter string
+		want      string
+	}{
+		{"output_filenames={target}.graphql", "can't resolve {target} in output file name {target}.graphql: no target is set"},
+		{"output_filenames={package}.graphql", "the files have different packages, users and people"},
+		{"target=admin,output_filenames={version}.graphql", "can't resolve {version}"},
+	}
+	for _, tt := range failures {
+		stderr := generateError(t, "combine_output,"+tt.parameter, usersFile("users.proto", "users"), usersFile("people.proto", "people"))
+		if !strings.Contains(stderr, tt.want) {
+			t.Errorf("%q: expected error %q, got %q", tt.parameter, tt.want, stderr)
+		}
+	}
+}
+
+func TestOperationsFile(t *testing.T) {
+	orders := testFile("orders.proto", "orders",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("CreateOrderRequest", messageField("item", 1, ".orders.Item")),
+			testMessage("Item", scalarField("sku", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Order", enumField("state", 1, ".orders.State")),
+		},
+		testMethod("CreateOrder", ".orders.CreateOrderRequest", ".orders.Order", &options.MethodOptions{Kind: "mutation"}),
+	)
+	orders.EnumType = []*descriptorpb.EnumDescriptorProto{testEnum("State", "PENDING", 0)}
+
+	out := generate(t, "combine_output,operations_file=operations.graphql", usersFile("users.proto", "users"), orders)
+	if len(out) != 2 {
+		t.Fatalf("expected schema.graphql and operations.graphql, got %v", keys(out))
+	}
+
+	header := "# Code generated by protoc-gen-graphql. DO NOT EDIT\n"
+	types, operations := out["schema.graphql"], out["operations.graphql"]
+	if !strings.HasPrefix(types, header) || !strings.HasPrefix(operations, header) {
+		t.Errorf("both files should start with the header, got:\n%s\n%s", types, operations)
+	}
+	if strings.Contains(types, "type Query") || strings.Contains(types, "type Mutation") {
+		t.Errorf("schema.graphql should not contain operations, got:\n%s", types)
+	}
+	if strings.Contains(operations, "type User") || strings.Contains(operations, "input ") {
+		t.Errorf("operations.graphql should only contain operations, got:\n%s", operations)
+	}
+
+	// Every type referenced by an operation is defined in the combined file
+	references := regexp.MustCompile(`:\s*\[?([A-Za-z_]\w*)`).FindAllStringSubmatch(operations, -1)
+	if len(references) != 4 {
+		t.Errorf("expected the input and payload of two operations, got %v in:\n%s", references, operations)
+	}
+	for _, reference := range references {
+		name := reference[1]
+		if !regexp.MustCompile(`(?m)^(type|input|enum|scalar) ` + name + `\b`).MatchString(types) {
+			t.Errorf("%s is referenced by operations.graphql but not defined in schema.graphql:\n%s", name, types)
+		}
+	}
+
+	stderr := generateError(t, "combine_output,operations_file=schema.graphql", usersFile("users.proto", "users"))
+	if !strings.Contains(stderr, "operations file and combined output are both schema.graphql") {
+		t.Errorf("operations file colliding with the combined output should be reported, got %q", stderr)
+	}
+}
+
+func TestEnumsFile(t *testing.T) {
+	// The orders and shipments files both reference the enum State of orders.proto
+	orders := testFile("orders.proto", "orders",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetOrderRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Order", enumField("state", 1, ".orders.State")),
+		},
+		testMethod("GetOrder", ".orders.GetOrderRequest", ".orders.Order", nil),
+	)
+	orders.EnumType = []*descriptorpb.EnumDescriptorProto{testEnum("State", "PENDING", 0, "SHIPPED", 1)}
+	shipments := testFile("shipments.proto", "shipments",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetShipmentRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Shipment",
+				enumField("order_state", 1, ".orders.State"),
+				enumField("carrier", 2, ".shipments.Carrier"),
+			),
+		},
+		testMethod("GetShipment", ".shipments.GetShipmentRequest", ".shipments.Shipment", nil),
+	)
+	shipments.Dependency = []string{"orders.proto"}
+	shipments.EnumType = []*descriptorpb.EnumDescriptorProto{testEnum("Carrier", "POST", 0)}
+
+	out := generate(t, "enums_file=enums.graphql", orders, shipments)
+	if len(out) != 3 {
+		t.Fatalf("expected orders.graphql, shipments.graphql and enums.graphql, got %v", keys(out))
+	}
+	header := "# Code generated by protoc-gen-graphql. DO NOT EDIT\n# " + NAME + " " + Version + "\n\n"
+	if want := header + "enum State {\n  PENDING\n  SHIPPED\n}\n\nenum Carrier {\n  POST\n}\n"; out["enums.graphql"] != want {
+		t.Errorf("enums.graphql = %q, want %q", out["enums.graphql"], want)
+	}
+	for name, want := range map[string]string{
+		"orders.graphql":    "type Order {\n  state: State\n}",
+		"shipments.graphql": "type Shipment {\n  orderState: State\n  carrier: Carrier\n}",
+	} {
+		if !strings.Contains(out[name], want) {
+			t.Errorf("%s should contain %q, got:\n%s", name, want, out[name])
+		}
+		if strings.Contains(out[name], "enum ") {
+			t.Errorf("%s should not define enums, got:\n%s", name, out[name])
+		}
+	}
+
+	// Without enums, no enums file is written
+	if out := generate(t, "enums_file=enums.graphql", usersFile("users.proto", "users")); len(out) != 1 {
+		t.Errorf("expected only users.graphql, got %v", keys(out))
+	}
+
+	stderr := generateError(t, "enums_file=orders.graphql", orders, shipments)
+	if want := "enums file and the output of orders.proto are both orders.graphql"; !strings.Contains(stderr, want) {
+		t.Errorf("error should contain %q, got %q", want, stderr)
+	}
+
+	shipments.EnumType = []*descriptorpb.EnumDescriptorProto{testEnum("Carrier", "POST", 0), testEnum("State", "LOST", 0)}
+	shipments.MessageType[1].Field = append(shipments.MessageType[1].Field, enumField("state", 3, ".shipments.State"))
+	stderr = generateError(t, "enums_file=enums.graphql", orders, shipments)
+	if want := "error generating enums file: orders.proto:State and shipments.proto:State both generate enum State with different values"; !strings.Contains(stderr, want) {
+		t.Errorf("error should contain %q, got %q", want, stderr)
+	}
+}
+
+func TestSplitByTarget(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Report", scalarField("total", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32)),
+			testMessage("Profile", scalarField("bio", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+		testMethod("GetReport", ".users.GetUserRequest", ".users.Report", &options.MethodOptions{Target: "admin"}),
+		testMethod("GetProfile", ".users.GetUserRequest", ".users.Profile", &options.MethodOptions{Target: "client"}),
+	)
+
+	out := generate(t, "target=admin,target=client,split_by_target", file)
+	if len(out) != 2 {
+		t.Fatalf("expected admin.graphql and client.graphql, got %v", keys(out))
+	}
+	for name, want := range map[string][]string{
+		"admin.graphql":  {"type User {", "type Report {", "getUser(input: IGetUserRequest!): User!", "getReport(input: IGetUserRequest!): Report!"},
+		"client.graphql": {"type User {", "type Profile {", "getUser(input: IGetUserRequest!): User!", "getProfile(input: IGetUserRequest!): Profile!"},
+	} {
+		for _, want := range want {
+			if !strings.Contains(out[name], want) {
+				t.Errorf("%s should contain %q, got:\n%s", name, want, out[name])
+			}
+		}
+	}
+	if strings.Contains(out["admin.graphql"], "Profile") || strings.Contains(out["client.graphql"], "Report") {
+		t.Errorf("each file should only contain its target's operations and types, got:\n%s\n%s", out["admin.graphql"], out["client.graphql"])
+	}
+
+	out = generate(t, "target=admin,target=client,split_by_target,output_filenames=schemas/{target}.graphql,operations_file={target}-operations.graphql", file)
+	for _, name := range []string{"schemas/admin.graphql", "schemas/client.graphql", "admin-operations.graphql", "client-operations.graphql"} {
+		if _, ok := out[name]; !ok {
+			t.Errorf("expected output file %s, got %v", name, keys(out))
+		}
+	}
+
+	failures := []struct {
+		parameter string
+		want      string
+	}{
+		{"target=admin,target=client", "invalid target: got admin,client, generate several targets with split_by_target"},
+		{"split_by_target", "invalid split_by_target: no target is set"},
+		{"target=admin,target=client,split_by_target,output_filenames=api.graphql", "targets admin and client both generate api.graphql"},
+	}
+	for _, tt := range failures {
+		stderr := generateError(t, tt.parameter, file)
+		if !strings.Contains(stderr, tt.want) {
+			t.Errorf("%q: expected error %q, got %q", tt.parameter, tt.want, stderr)
+		}
+	}
+}
+
+func TestAnnotateFile(t *testing.T) {
+	orders := testFile("orders.proto", "orders",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetOrderRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Order", enumField("state", 1, ".orders.State")),
+		},
+		testMethod("GetOrder", ".orders.GetOrderRequest", ".orders.Order", nil),
+	)
+	orders.EnumType = []*descriptorpb.EnumDescriptorProto{testEnum("State", "PENDING", 0)}
+
+	// The User of people.proto is deduplicated, so the type keeps the file of its first definition
+	out := generate(t, "combine_output,annotate_file", usersFile("users.proto", "users"), usersFile("people.proto", "people"), orders)["schema.graphql"]
+	for _, want := range []string{
+		"# source: users.proto\ntype User {",
+		"# source: users.proto\ninput IGetUserRequest {",
+		"# source: orders.proto\ntype Order {",
+		"# source: orders.proto\ninput IGetOrderRequest {",
+		"# source: orders.proto\nenum State {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "people.proto") {
+		t.Errorf("deduplicated types should not be annotated with their other files, got:\n%s", out)
+	}
+
+	out = generate(t, "combine_output,annotate_file,annotate_source", orders)["schema.graphql"]
+	if !strings.Contains(out, "# source: orders.proto\n# from orders.proto:Order\ntype Order {") {
+		t.Errorf("the file should come before the source of the type, got:\n%s", out)
+	}
+}
+
+func TestAnnotatePackage(t *testing.T) {
+	orders := testFile("acme/orders.proto", "acme.orders",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetOrderRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Order", enumField("state", 1, ".acme.orders.State")),
+		},
+		testMethod("GetOrder", ".acme.orders.GetOrderRequest", ".acme.orders.Order", nil),
+	)
+	orders.EnumType = []*descriptorpb.EnumDescriptorProto{testEnum("State", "PENDING", 0)}
+	unpackaged := testFile("users.proto", "",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("GetUser", ".GetUserRequest", ".User", nil),
+	)
+
+	out := generate(t, "combine_output,annotate_package", orders, unpackaged)["schema.graphql"]
+	for _, want := range []string{
+		"# package: acme.orders\ntype Order {",
+		"# package: acme.orders\ninput IGetOrderRequest {",
+		"# package: acme.orders\nenum State {",
+		"}\n\ntype User {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+
+	out = generate(t, "combine_output,annotate_file,annotate_package", orders)["schema.graphql"]
+	if !strings.Contains(out, "# source: acme/orders.proto\n# package: acme.orders\ntype Order {") {
+		t.Errorf("the package should come after the file, got:\n%s", out)
+	}
+	if out := generate(t, "", orders)["acme/orders.graphql"]; strings.Contains(out, "# package:") {
+		t.Errorf("packages should not be annotated by default, got:\n%s", out)
+	}
+}
+
+func TestImportCycleWarning(t *testing.T) {
+	users := usersFile("users.proto", "users")
+	users.Dependency = []string{"people.proto"}
+	people := usersFile("people.proto", "people")
+	people.Dependency = []string{"users.proto"}
+
+	var out map[string]string
+	_, stderr := captureOutput(t, func() { out = generate(t, "", users, people) })
+
+	if !strings.Contains(stderr, "warning: import cycle users.proto -> people.proto -> users.proto") {
+		t.Errorf("import cycle should be reported, got %q", stderr)
+	}
+	if len(out) != 2 {
+		t.Errorf("both files should still be generated, got %v", keys(out))
+	}
+}
+
+func TestMissingFileToGenerate(t *testing.T) {
+	request := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"users.proto", "billing.proto"},
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{usersFile("users.proto", "users")},
+	}
+	stderr := runError(t, func() { New(request).Execute() })
+	if want := "invalid request: billing.proto is in file_to_generate but the request has no descriptor for it"; !strings.Contains(stderr, want) {
+		t.Errorf("error should contain %q, got %q", want, stderr)
+	}
+}
+
+func TestFailOnWarning(t *testing.T) {
+	file := usersFile("users.proto", "users")
+
+	// Unknown options are warnings
+	_, stderr := captureOutput(t, func() { generate(t, "no_such_option", file) })
+	if !strings.Contains(stderr, `warning: unknown option "no_such_option"`) {
+		t.Fatalf("unknown option should be a warning, got %q", stderr)
+	}
+
+	stderr = generateError(t, "no_such_option,fail_on_warning", file)
+	if !strings.Contains(stderr, "generation failed with fail_on_warning: 1 warning(s) emitted") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+
+	// Warnings count even when they are not printed
+	stderr = generateError(t, "operations_file=operations.graphql,fail_on_warning,quiet", file)
+	if strings.Contains(stderr, "warning: operations_file") || !strings.Contains(stderr, "generation failed with fail_on_warning") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+
+	generate(t, "fail_on_warning", file)
+}
+
+func TestCombinedOutputEnums(t *testing.T) {
+	statusFile := func(name, pkg string, status *descriptorpb.EnumDescriptorProto) *descriptorpb.FileDescriptorProto {
+		file := testFile(name, pkg,
+			[]*descriptorpb.DescriptorProto{
+				testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+				testMessage("User", enumField("status", 1, "."+pkg+".Status")),
+			},
+			testMethod("Get"+strings.ToUpper(pkg[:1])+pkg[1:], "."+pkg+".GetUserRequest", "."+pkg+".User", nil),
+		)
+		file.EnumType = []*descriptorpb.EnumDescriptorProto{status}
+		return file
+	}
+
+	// Enums of the same name with the same values, in any order, merge
+	users := statusFile("users.proto", "users", testEnum("Status", "ACTIVE", 0, "BANNED", 1))
+	people := statusFile("people.proto", "people", testEnum("Status", "BANNED", 1, "ACTIVE", 0))
+	out := generate(t, "combine_output", users, people)["schema.graphql"]
+	if n := strings.Count(out, "enum Status {"); n != 1 {
+		t.Errorf("identical enums should merge into one, got %d in:\n%s", n, out)
+	}
+
+	people = statusFile("people.proto", "people", testEnum("Status", "ACTIVE", 0, "DELETED", 1))
+	stderr := generateError(t, "combine_output", users, people)
+	want := "error combining output: users.proto:Status and people.proto:Status both generate enum Status with different values"
+	if !strings.Contains(stderr, want) {
+		t.Errorf("error should contain %q, got: %s", want, stderr)
+	}
+}
+
+func TestNoDedup(t *testing.T) {
+	files := func() []*descriptorpb.FileDescriptorProto {
+		return []*descriptorpb.FileDescriptorProto{usersFile("users.proto", "users"), usersFile("people.proto", "people")}
+	}
+	heads := []string{"type User {", "input IGetUserRequest {", "  getUser(input: IGetUserRequest!): User!\n"}
+
+	out := generate(t, "combine_output", files()...)["schema.graphql"]
+	for _, head := range heads {
+		if n := strings.Count(out, head); n != 1 {
+			t.Errorf("duplicates of %q should be collapsed, got %d in:\n%s", head, n, out)
+		}
+	}
+
+	out = generate(t, "combine_output,no_dedup", files()...)["schema.graphql"]
+	for _, head := range heads {
+		if n := strings.Count(out, head); n != 2 {
+			t.Errorf("duplicates of %q should be kept with no_dedup, got %d in:\n%s", head, n, out)
+		}
+	}
+}
+
+func TestCombinedOutputOrder(t *testing.T) {
+	profile := testMessage("Profile",
+		scalarField("bio", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+		enumField("visibility", 2, ".users.Profile.Visibility"),
+		messageField("links", 3, ".users.Profile.Link"),
+	)
+	profile.NestedType = []*descriptorpb.DescriptorProto{
+		testMessage("Link", scalarField("url", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+	}
+	profile.EnumType = []*descriptorpb.EnumDescriptorProto{testEnum("Visibility", "PUBLIC", 0, "PRIVATE", 1)}
+
+	users := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User",
+				scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				enumField("status", 2, ".users.Status"),
+				messageField("profile", 3, ".users.Profile"),
+			),
+			profile,
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+		testMethod("UpdateProfile", ".users.Profile", ".users.Profile", &options.MethodOptions{Kind: "mutation"}),
+	)
+	users.EnumType = []*descriptorpb.EnumDescriptorProto{testEnum("Status", "ACTIVE", 0, "BANNED", 1)}
+
+	// Declares User again, the first declaration is kept
+	orders := testFile("orders.proto", "orders",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetOrderRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Order",
+				scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				enumField("state", 2, ".orders.State"),
+				messageField("buyer", 3, ".orders.User"),
+			),
+			testMessage("User", scalarField("email", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("GetOrder", ".orders.GetOrderRequest", ".orders.Order", nil),
+	)
+	orders.EnumType = []*descriptorpb.EnumDescriptorProto{testEnum("State", "PENDING", 0, "SHIPPED", 1)}
+
+	out := generate(t, "combine_output", users, orders)["schema.graphql"]
+	checkGolden(t, "combined/schema.graphql", out)
+}
+
+func keys(m map[string]string) []string {
+	var result []string
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
+
+func TestExtendRoots(t *testing.T) {
+	out := generate(t, "extend_roots", usersFile("users.proto", "users"), usersFile("people.proto", "people"))
+
+	users, people := out["users.graphql"], out["people.graphql"]
+	if !strings.Contains(users, "type Query {\n  getUser(input: IGetUserRequest!): User!\n}") || strings.Contains(users, "extend type") {
+		t.Errorf("the first file should define Query, got:\n%s", users)
+	}
+	if !strings.Contains(people, "extend type Query {\n  getUser(input: IGetUserRequest!): User!\n}") {
+		t.Errorf("the next files should extend Query, got:\n%s", people)
+	}
+	if got := strings.Count(users+people, "type Query {"); got != 2 {
+		t.Errorf("Query should be defined once and extended once, got %d roots", got)
+	}
+	// Roots without operations are not extended
+	if strings.Contains(people, "Mutation") {
+		t.Errorf("an empty Mutation should not be extended, got:\n%s", people)
+	}
+
+	// By default, every file defines its roots
+	out = generate(t, "", usersFile("users.proto", "users"), usersFile("people.proto", "people"))
+	if strings.Contains(out["people.graphql"], "extend type") {
+		t.Errorf("roots should not be extended by default, got:\n%s", out["people.graphql"])
+	}
+}
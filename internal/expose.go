@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// An exposed option resolved to its extension, from the proto files of the request
+type exposedOption struct {
+	ExposedOption
+	extension protoreflect.ExtensionType
+}
+
+// Resolves the extensions of the exposed options from the proto files of the request.
+// The extensions are not known to the plugin, their values are read from the unknown fields of the options
+func (plugin *Plugin) resolveExposedOptions() {
+	if len(plugin.args.ExposeOptions) == 0 {
+		return
+	}
+
+	files := new(protoregistry.Files)
+	resolver := fallbackResolver{files}
+	for _, protoFile := range plugin.Request.ProtoFile {
+		if _, err := files.FindFileByPath(protoFile.GetName()); err == nil {
+			continue
+		}
+		file, err := protodesc.FileOptions{AllowUnresolvable: true}.New(protoFile, resolver)
+		if err != nil {
+			plugin.Error(err, "error resolving custom options of", protoFile.GetName())
+			continue
+		}
+		if err := files.RegisterFile(file); err != nil {
+			plugin.Error(err, "error resolving custom options of", protoFile.GetName())
+		}
+	}
+
+	plugin.exposedTypes = new(protoregistry.Types)
+	for _, option := range plugin.args.ExposeOptions {
+		d, err := resolver.FindDescriptorByName(protoreflect.FullName(option.Extension))
+		extension, ok := d.(protoreflect.ExtensionDescriptor)
+		if err != nil || !ok {
+			plugin.Error(fmt.Errorf("no extension named %s in the proto files", option.Extension), "can't expose option")
+			continue
+		}
+		switch extension.ContainingMessage().FullName() {
+		case "google.protobuf.MethodOptions", "google.protobuf.MessageOptions", "google.protobuf.FieldOptions":
+		default:
+			plugin.Error(fmt.Errorf("%s extends %s, only method, message and field options can be exposed",
+				option.Extension, extension.ContainingMessage().FullName()), "can't expose option")
+			continue
+		}
+
+		extensionType := dynamicpb.NewExtensionType(extension)
+		if err := plugin.exposedTypes.RegisterExtension(extensionType); err != nil {
+			plugin.Error(err, "can't expose option", option.Extension)
+			continue
+		}
+		plugin.exposedOptions = append(plugin.exposedOptions, &exposedOption{option, extensionType})
+	}
+}
+
+// Returns the directives and the description the exposed options set on a method, message or field
+func (plugin *Plugin) exposedValues(opts proto.Message) ([]string, string) {
+	if len(plugin.exposedOptions) == 0 || !opts.ProtoReflect().IsValid() {
+		return nil, ""
+	}
+
+	// Parse the options again with the extensions known, so their values are no longer unknown fields
+	data, err := proto.Marshal(opts)
+	if err != nil {
+		plugin.Error(err, "error reading custom options")
+	}
+	resolved := opts.ProtoReflect().New().Interface()
+	if err := (proto.UnmarshalOptions{Resolver: plugin.exposedTypes}).Unmarshal(data, resolved); err != nil {
+		plugin.Error(err, "error reading custom options")
+	}
+
+	var directives, descriptions []string
+	for _, option := range plugin.exposedOptions {
+		if !proto.HasExtension(resolved, option.extension) {
+			continue
+		}
+		field := option.extension.TypeDescriptor()
+		value := resolved.ProtoReflect().Get(field)
+		if option.Target == ExposeDescription {
+			descriptions = append(descriptions, option.Extension+": "+descriptionText(value, field))
+		} else {
+			directives = append(directives, option.Target+directiveArguments(value, field))
+		}
+	}
+	return directives, strings.Join(descriptions, "\n")
+}
+
+// Adds the directives and the description of the exposed options of a method, message or field
+// to the directives and the description it already has
+func (schema *Schema) withExposedOptions(directives []string, description string, opts proto.Message) ([]string, string) {
+	exposedDirectives, exposedDescription := schema.plugin.exposedValues(opts)
+	return append(directives, exposedDirectives...), joinDescriptions(description, exposedDescription)
+}
+
+// Returns the arguments of a directive exposing an option value. Message values are written as one argument per set field,
+// other values as a single value argument
+func directiveArguments(value protoreflect.Value, field protoreflect.FieldDescriptor) string {
+	if field.IsList() || field.Message() == nil {
+		return "(value: " + valueLiteral(value, field) + ")"
+	}
+	arguments := messageFields(value.Message())
+	if arguments == "" {
+		return ""
+	}
+	return "(" + arguments + ")"
+}
+
+// Returns the text of an exposed option in a description, strings are written as is
+func descriptionText(value protoreflect.Value, field protoreflect.FieldDescriptor) string {
+	if !field.IsList() && field.Kind() == protoreflect.StringKind {
+		return value.String()
+	}
+	return valueLiteral(value, field)
+}
+
+// Returns an option value as a GraphQL value literal, e.g. "GET", 3, [1, 2] or {path: "/users"}
+func valueLiteral(value protoreflect.Value, field protoreflect.FieldDescriptor) string {
+	if field.IsList() {
+		list := value.List()
+		items := make([]string, list.Len())
+		for i := range items {
+			items[i] = singularLiteral(list.Get(i), field)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return singularLiteral(value, field)
+}
+
+func singularLiteral(value protoreflect.Value, field protoreflect.FieldDescriptor) string {
+	switch field.Kind() {
+	case protoreflect.StringKind:
+		return quoteString(value.String())
+	case protoreflect.BytesKind:
+		return quoteString(base64.StdEncoding.EncodeToString(value.Bytes()))
+	case protoreflect.EnumKind:
+		if enumValue := field.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name())
+		}
+		return fmt.Sprint(value.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return "{" + messageFields(value.Message()) + "}"
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
+
+// Returns the set fields of a message as GraphQL arguments or object fields, in field order, e.g. `path: "/users", body: "*"`
+func messageFields(message protoreflect.Message) string {
+	var fields []string
+	descriptors := message.Descriptor().Fields()
+	for i := 0; i < descriptors.Len(); i++ {
+		field := descriptors.Get(i)
+		if !message.Has(field) || field.IsMap() {
+			continue
+		}
+		fields = append(fields, string(field.Name())+": "+valueLiteral(message.Get(field), field))
+	}
+	return strings.Join(fields, ", ")
+}
+
+// Resolves descriptors from the proto files of the request, then from the descriptors built into the plugin,
+// such as descriptor.proto which custom options import
+type fallbackResolver struct {
+	files *protoregistry.Files
+}
+
+func (r fallbackResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if file, err := r.files.FindFileByPath(path); err == nil {
+		return file, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (r fallbackResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if d, err := r.files.FindDescriptorByName(name); err == nil {
+		return d, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
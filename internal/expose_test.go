@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fverse/protoc-graphql/options"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Proto file defining the custom options acme.http on methods, acme.owner on messages and acme.sensitive on fields
+func acmeOptionsFile() *descriptorpb.FileDescriptorProto {
+	extension := func(name string, number int32, t descriptorpb.FieldDescriptorProto_Type, extendee string) *descriptorpb.FieldDescriptorProto {
+		field := scalarField(name, number, t)
+		field.Extendee = proto.String(extendee)
+		return field
+	}
+	http := extension("http", 60000, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.MethodOptions")
+	http.TypeName = proto.String(".acme.HttpRule")
+	tags := scalarField("tags", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	tags.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	file := testFile("acme/options.proto", "acme", []*descriptorpb.DescriptorProto{
+		testMessage("HttpRule",
+			scalarField("get", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			scalarField("post", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			tags,
+		),
+	})
+	file.Dependency = []string{"google/protobuf/descriptor.proto"}
+	file.Extension = []*descriptorpb.FieldDescriptorProto{
+		http,
+		extension("owner", 60001, descriptorpb.FieldDescriptorProto_TYPE_STRING, ".google.protobuf.MessageOptions"),
+		extension("sensitive", 60002, descriptorpb.FieldDescriptorProto_TYPE_BOOL, ".google.protobuf.FieldOptions"),
+	}
+	return file
+}
+
+func TestExposeOption(t *testing.T) {
+	// Custom options are unknown fields of the options, as the plugin doesn't know their extensions
+	rule := protowire.AppendTag(nil, 1, protowire.BytesType)
+	rule = protowire.AppendString(rule, "/users/{id}")
+	for _, tag := range []string{"users", `"public"`} {
+		rule = protowire.AppendTag(rule, 3, protowire.BytesType)
+		rule = protowire.AppendString(rule, tag)
+	}
+	http := protowire.AppendTag(nil, 60000, protowire.BytesType)
+	http = protowire.AppendBytes(http, rule)
+
+	method := testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil)
+	method.Options = &descriptorpb.MethodOptions{}
+	method.Options.ProtoReflect().SetUnknown(http)
+
+	owner := protowire.AppendTag(nil, 60001, protowire.BytesType)
+	owner = protowire.AppendString(owner, "identity team")
+	user := testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING))
+	user.Options = &descriptorpb.MessageOptions{}
+	user.Options.ProtoReflect().SetUnknown(owner)
+
+	sensitive := protowire.AppendTag(nil, 60002, protowire.VarintType)
+	sensitive = protowire.AppendVarint(sensitive, 1)
+	email := scalarField("email", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	email.Options = &descriptorpb.FieldOptions{}
+	email.Options.ProtoReflect().SetUnknown(sensitive)
+	user.Field = append(user.Field, email)
+
+	users := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			user,
+		},
+		method,
+	)
+	users.Dependency = []string{"acme/options.proto"}
+
+	param := "expose_option=acme.http=@http,expose_option=acme.owner=description,expose_option=acme.sensitive=@sensitive"
+	out := generate(t, param, acmeOptionsFile(), users)["users.graphql"]
+	for _, want := range []string{
+		`getUser(input: IGetUserRequest!): User! @http(get: "/users/{id}", tags: ["users", "\"public\""])`,
+		"\"acme.owner: identity team\"\ntype User {",
+		"  email: String @sensitive(value: true)\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+
+	// Options that are not exposed are not written
+	out = generate(t, "", acmeOptionsFile(), users)["users.graphql"]
+	if strings.Contains(out, "@http") || strings.Contains(out, "acme.owner") {
+		t.Errorf("custom options should not be exposed by default, got:\n%s", out)
+	}
+
+	stderr := generateError(t, "expose_option=acme.missing=@missing", acmeOptionsFile(), users)
+	if !strings.Contains(stderr, "can't expose option: no extension named acme.missing") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+
+	// Every invalid option is reported, and skipped, if exiting doesn't stop the plugin
+	exited := 0
+	exit = func(int) { exited++ }
+	defer func() { exit = os.Exit }()
+	var plugin *Plugin
+	_, stderr = captureOutput(t, func() {
+		plugin = New(&pluginpb.CodeGeneratorRequest{
+			Parameter: proto.String("expose_option=acme.missing=@missing,expose_option=users.User=@user,expose_option=acme.owner=@owner"),
+			ProtoFile: []*descriptorpb.FileDescriptorProto{acmeOptionsFile(), users},
+		})
+		plugin.resolveExposedOptions()
+	})
+	if exited != 2 || !strings.Contains(stderr, "no extension named acme.missing") || !strings.Contains(stderr, "no extension named users.User") {
+		t.Errorf("both invalid options should be reported, exited %d times, got: %s", exited, stderr)
+	}
+	if len(plugin.exposedOptions) != 1 || plugin.exposedOptions[0].Extension != "acme.owner" {
+		t.Errorf("only the valid option should be exposed, got %v", plugin.exposedOptions)
+	}
+}
+
+// Encodes a google.api.HttpRule from its fields, field numbers to values, and sets it as the google.api.http option of method
+func setHttpRule(method *descriptorpb.MethodDescriptorProto, fields ...any) {
+	var rule []byte
+	for i := 0; i < len(fields); i += 2 {
+		rule = protowire.AppendTag(rule, protowire.Number(fields[i].(int)), protowire.BytesType)
+		switch value := fields[i+1].(type) {
+		case string:
+			rule = protowire.AppendString(rule, value)
+		case []byte:
+			rule = protowire.AppendBytes(rule, value)
+		}
+	}
+	if method.Options == nil {
+		method.Options = &descriptorpb.MethodOptions{}
+	}
+	option := protowire.AppendTag(method.Options.ProtoReflect().GetUnknown(), httpRuleNumber, protowire.BytesType)
+	method.Options.ProtoReflect().SetUnknown(protowire.AppendBytes(option, rule))
+}
+
+func TestExposeHttp(t *testing.T) {
+	getUser := testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil)
+	binding := protowire.AppendTag(nil, httpRuleGet, protowire.BytesType)
+	binding = protowire.AppendString(binding, "/v1/people/{id}")
+	setHttpRule(getUser, httpRuleGet, "/v1/users/{id}", httpRuleAdditionalBindings, binding)
+
+	createUser := testMethod("CreateUser", ".users.User", ".users.User", &options.MethodOptions{Kind: "mutation"})
+	setHttpRule(createUser, httpRulePost, "/v1/users", httpRuleBody, "*")
+
+	custom := protowire.AppendTag(nil, 1, protowire.BytesType)
+	custom = protowire.AppendString(custom, "HEAD")
+	custom = protowire.AppendTag(custom, 2, protowire.BytesType)
+	custom = protowire.AppendString(custom, "/v1/users/{id}")
+	checkUser := testMethod("CheckUser", ".users.GetUserRequest", ".users.User", nil)
+	setHttpRule(checkUser, httpRuleCustom, custom)
+
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		getUser, createUser, checkUser,
+		testMethod("ListUsers", ".users.GetUserRequest", ".users.User", nil),
+	)
+
+	out := generate(t, "expose_http", file)["users.graphql"]
+	for _, want := range []string{
+		"  \"\"\"\n  HTTP: GET /v1/users/{id}\n  HTTP: GET /v1/people/{id}\n  \"\"\"\n  getUser(",
+		"type Mutation {\n  \"HTTP: POST /v1/users (body: *)\"\n  createUser(",
+		"  \"HTTP: HEAD /v1/users/{id}\"\n  checkUser(",
+		"User!\n  listUsers(",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+
+	out = generate(t, "expose_http,annotate_operations", file)["users.graphql"]
+	if want := "Backed by Service.CreateUser (unary)\n\n  HTTP: POST /v1/users (body: *)"; !strings.Contains(out, want) {
+		t.Errorf("output should contain %q, got:\n%s", want, out)
+	}
+
+	if out := generate(t, "", file)["users.graphql"]; strings.Contains(out, "HTTP:") {
+		t.Errorf("HTTP mappings should not be exposed by default, got:\n%s", out)
+	}
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fverse/protoc-graphql/options"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Version of the federation spec the directives are imported from, unless federation_version is set
+const defaultFederationVersion = "2.3"
+
+// Federation directives, in the order they are imported
+const (
+	directiveTag          = "@tag"
+	directiveInaccessible = "@inaccessible"
+)
+
+var federationDirectives = []string{directiveTag, directiveInaccessible}
+
+// Returns the federation directives of a message, from the gql_type_tag and gql_type_inaccessible options
+func typeDirectives(messageOptions *descriptorpb.MessageOptions) []string {
+	var tags []string
+	if proto.HasExtension(messageOptions, options.E_GqlTypeTag) {
+		tags = proto.GetExtension(messageOptions, options.E_GqlTypeTag).([]string)
+	}
+	inaccessible := false
+	if proto.HasExtension(messageOptions, options.E_GqlTypeInaccessible) {
+		inaccessible = proto.GetExtension(messageOptions, options.E_GqlTypeInaccessible).(bool)
+	}
+	return directives(tags, inaccessible)
+}
+
+// Returns the federation directives of a field, from the gql_tag and gql_inaccessible options
+func fieldDirectives(fieldOptions *descriptorpb.FieldOptions) []string {
+	var tags []string
+	if proto.HasExtension(fieldOptions, options.E_GqlTag) {
+		tags = proto.GetExtension(fieldOptions, options.E_GqlTag).([]string)
+	}
+	inaccessible := false
+	if proto.HasExtension(fieldOptions, options.E_GqlInaccessible) {
+		inaccessible = proto.GetExtension(fieldOptions, options.E_GqlInaccessible).(bool)
+	}
+	return directives(tags, inaccessible)
+}
+
+func directives(tags []string, inaccessible bool) []string {
+	var result []string
+	for _, tag := range tags {
+		result = append(result, fmt.Sprintf("%s(name: %q)", directiveTag, tag))
+	}
+	if inaccessible {
+		result = append(result, directiveInaccessible)
+	}
+	return result
+}
+
+// Collects the federation directives used by the schema's types and fields
+func (schema *Schema) collectFederationDirectives() {
+	used := make(map[string]bool)
+	add := func(directives []string) {
+		for _, directive := range directives {
+			name, _, _ := strings.Cut(directive, "(")
+			used[name] = true
+		}
+	}
+	for _, objectType := range schema.objectTypes {
+		add(objectType.Directives)
+		for _, field := range objectType.Fields {
+			add(field.Directives)
+		}
+	}
+	for _, inputType := range schema.inputTypes {
+		add(inputType.Directives)
+		for _, field := range inputType.Fields {
+			add(field.Directives)
+		}
+	}
+
+	for _, directive := range federationDirectives {
+		if used[directive] {
+			schema.federationImports = append(schema.federationImports, directive)
+		}
+	}
+}
+
+// Imports the federation directives used by the schema
+func (schema *Schema) generateFederationLink() {
+	if len(schema.federationImports) == 0 {
+		return
+	}
+	imports := make([]string, len(schema.federationImports))
+	for i, directive := range schema.federationImports {
+		imports[i] = fmt.Sprintf("%q", directive)
+	}
+	schema.Write("extend schema")
+	schema.NewLine()
+	schema.Indent()
+	schema.Write(fmt.Sprintf("@link(url: %q, import: [%s])", schema.federationURL(), strings.Join(imports, ", ")))
+	schema.NewLine(2)
+}
+
+// Returns the URL of the federation spec selected by the federation_version option
+func (schema *Schema) federationURL() string {
+	version := defaultFederationVersion
+	if schema.args.FederationVersion != "" {
+		version = schema.args.FederationVersion
+	}
+	return "https://specs.apollo.dev/federation/v" + version
+}
+
+// Writes the directives of a type or field, preceded by a space
+func (schema *Schema) writeDirectives(directives []string) {
+	for _, directive := range directives {
+		schema.Space()
+		schema.Write(directive)
+	}
+}
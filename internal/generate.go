@@ -2,6 +2,7 @@ package internal
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/fverse/protoc-graphql/internal/descriptor"
 	"github.com/fverse/protoc-graphql/internal/syntax"
@@ -11,10 +12,17 @@ import (
 // generateType generates a GraphQL output type definition
 // Only generates GraphQL `type` for output-reachable messages
 func (schema *Schema) generateType(object *descriptor.ObjectType) {
-	schema.WriteTypeName(syntax.ObjectType, object.Name)
+	schema.annotateType(object.Source, object.Package)
+	schema.writeDescriptionString(object.Description, false)
+	name := *object.Name
+	if len(object.Interfaces) > 0 {
+		name += " " + string(syntax.Implements) + " " + strings.Join(object.Interfaces, " & ")
+	}
+	schema.WriteTypeName(syntax.ObjectType, &name, object.Directives...)
 
 	for _, field := range object.Fields {
-		schema.Space(2)
+		schema.writeFieldDescription(field)
+		schema.Indent()
 		schema.Write(*field.Name + string(syntax.Colon))
 		schema.Space()
 
@@ -22,19 +30,25 @@ func (schema *Schema) generateType(object *descriptor.ObjectType) {
 			schema.Write(string(syntax.LBracket))
 			schema.Write(field.Type.String())
 
-			if !field.Optional {
+			if schema.nonNullOutput(field) {
 				schema.Write(string(syntax.Bang))
 			}
 
 			schema.Write(string(syntax.RBracket))
+			if field.ListRequired && !schema.args.AllNullable {
+				schema.Write(string(syntax.Bang))
+			}
 		} else {
 			schema.Write(field.Type.String())
 
-			if !field.Optional {
+			if schema.nonNullOutput(field) {
 				schema.Write(string(syntax.Bang))
 			}
 		}
 
+		schema.writeValuesDirective(field)
+		schema.writeDirectives(field.Directives)
+		schema.annotateField(field)
 		schema.NewLine()
 	}
 	schema.Write(string(syntax.RBrace))
@@ -44,36 +58,132 @@ func (schema *Schema) generateType(object *descriptor.ObjectType) {
 // generateInputType generates a GraphQL input type definition
 // Only generates GraphQL `input` for input-reachable messages
 func (schema *Schema) generateInputType(inputType *descriptor.InputType) {
-	schema.WriteString(fmt.Sprintf("input I%s {\n", *inputType.Name))
+	schema.annotateType(inputType.Source, inputType.Package)
+	schema.writeDescriptionString(inputType.Description, false)
+	schema.WriteTypeName(syntax.Input, utils.String(schema.inputTypeName(*inputType.Name)), inputType.Directives...)
 
 	for _, field := range inputType.Fields {
-		schema.Space(2)
+		schema.writeFieldDescription(field)
+		schema.Indent()
 		schema.Write(*field.Name + string(syntax.Colon))
 		schema.Space()
 
-		if field.IsList {
-			schema.Write(string(syntax.LBracket))
-		}
+		schema.Write(schema.inputFieldType(field))
+		schema.writeValuesDirective(field)
+		schema.writeDirectives(field.Directives)
+		schema.annotateField(field)
+		schema.NewLine()
+	}
 
-		if field.NonPrimitive {
-			schema.Write("I" + field.Type.String())
-		} else {
-			schema.Write(field.Type.String())
-		}
+	schema.Write(string(syntax.RBrace))
+	schema.NewLine(2)
+}
 
-		if !field.Optional {
-			schema.Write(string(syntax.Bang))
+// inputFieldType returns the type of an input field or argument, referencing the input variant of messages
+func (schema *Schema) inputFieldType(field *descriptor.Field) string {
+	fieldType := field.Type.String()
+	if field.NonPrimitive {
+		fieldType = schema.inputTypeName(fieldType)
+	}
+	if !field.Optional {
+		fieldType += string(syntax.Bang)
+	}
+	if field.IsList {
+		fieldType = string(syntax.LBracket) + fieldType + string(syntax.RBracket)
+		if field.ListRequired {
+			fieldType += string(syntax.Bang)
 		}
+	}
+	return fieldType
+}
 
-		if field.IsList {
-			schema.Write(string(syntax.RBracket))
+// arguments returns the argument list of a flattened query or mutation, e.g. "id: String!, filter: IFilter".
+// With more arguments than arg_wrap, or if an argument has a description, each argument is on its own line,
+// indented below the operation, after its description
+func (schema *Schema) arguments(fields []*descriptor.Field) string {
+	arguments := make([]string, 0, len(fields))
+	described := false
+	for _, field := range fields {
+		argument := *field.Name + string(syntax.Colon) + " " + schema.inputFieldType(field)
+		if len(field.AllowedValues) > 0 {
+			argument += " " + valuesDirective(field.AllowedValues)
+		}
+		if isNonEmpty(field) {
+			argument += " " + nonEmptyDirective
 		}
+		arguments = append(arguments, argument)
+		described = described || field.Description != ""
+	}
+	if wrap := schema.args.ArgWrap; described || wrap > 0 && len(arguments) > wrap {
+		indentation := schema.indentation() + schema.indentation()
+		var b strings.Builder
+		b.WriteString("\n")
+		for i, argument := range arguments {
+			b.WriteString(formatDescription(fields[i].Description, indentation))
+			b.WriteString(indentation + argument + "\n")
+		}
+		b.WriteString(schema.indentation())
+		return b.String()
+	}
+	return strings.Join(arguments, ", ")
+}
 
-		schema.NewLine()
+// writeFieldDescription writes the description of a field on the lines before it
+func (schema *Schema) writeFieldDescription(field *descriptor.Field) {
+	schema.writeDescriptionString(field.Description, true)
+}
+
+// annotateFile writes a comment naming the proto file of a type, input or enum, if annotate_file is set
+func (schema *Schema) annotateFile(source string) {
+	if !schema.args.AnnotateFile || source == "" {
+		return
 	}
+	file, _, _ := strings.Cut(source, ":")
+	schema.Comment("source: " + file)
+	schema.NewLine()
+}
 
-	schema.Write(string(syntax.RBrace))
-	schema.NewLine(2)
+// annotatePackage writes a comment naming the proto package of a type, input or enum, if annotate_package is set.
+// Types of files without a package have none
+func (schema *Schema) annotatePackage(pkg string) {
+	if !schema.args.AnnotatePackage || pkg == "" {
+		return
+	}
+	schema.Comment("package: " + pkg)
+	schema.NewLine()
+}
+
+// annotateType writes comments naming the proto file and package of a type, if annotate_file and annotate_package are set,
+// and one naming its proto message, if annotate_source is set
+func (schema *Schema) annotateType(source, pkg string) {
+	schema.annotateFile(source)
+	schema.annotatePackage(pkg)
+	if !schema.args.AnnotateSource || source == "" {
+		return
+	}
+	schema.Comment("from " + source)
+	schema.NewLine()
+}
+
+// annotateField writes a trailing comment with the proto field number, if annotate_source is set.
+// Signed integer fields also note their encoding. Placeholder fields have no proto field
+func (schema *Schema) annotateField(field *descriptor.Field) {
+	if !schema.args.AnnotateSource || field.Number == 0 {
+		return
+	}
+	schema.Space()
+	schema.Comment(fmt.Sprintf("proto field %d", field.Number) + signedEncoding(field.SignedType))
+}
+
+// Returns the note of annotate_source on the encoding of a signed integer proto type, e.g. sint32
+func signedEncoding(signedType string) string {
+	switch {
+	case strings.HasPrefix(signedType, "sint"):
+		return ", " + signedType + ": signed, zigzag encoded"
+	case strings.HasPrefix(signedType, "sfixed"):
+		return ", " + signedType + ": signed, fixed width"
+	}
+	return ""
 }
 
 func (schema *Schema) generateTypes() {
@@ -90,14 +200,36 @@ func (schema *Schema) generateInputTypes() {
 	}
 }
 
+// Generate custom scalar declarations
+func (schema *Schema) generateScalars() {
+	if len(schema.scalars) == 0 {
+		return
+	}
+	for _, scalar := range schema.scalars {
+		schema.Write("scalar " + scalar)
+		if url, ok := schema.args.ScalarSpecs[scalar]; ok {
+			schema.Write(fmt.Sprintf(" @specifiedBy(url: %q)", url))
+		}
+		schema.NewLine()
+	}
+	schema.NewLine()
+}
+
 // Generate enums
 func (schema *Schema) generateEnums() {
 	for _, enum := range schema.enums {
+		schema.annotateFile(enum.Source)
+		schema.annotatePackage(enum.Package)
+		schema.writeDescriptionString(enum.Description, false)
 		schema.WriteTypeName(syntax.Enum, enum.Name)
 
 		for _, value := range enum.Values {
-			schema.Space(3)
-			schema.Write(*value)
+			schema.writeDescriptionString(value.Description, true)
+			schema.Indent()
+			schema.Write(*value.Name)
+			if value.Deprecation != "" {
+				schema.Write(fmt.Sprintf(" @deprecated(reason: %q)", value.Deprecation))
+			}
 			schema.NewLine()
 		}
 		schema.Write(string(syntax.RBrace))
@@ -107,20 +239,32 @@ func (schema *Schema) generateEnums() {
 
 // Generate queries
 func (schema *Schema) generateQueries() {
-	schema.Write("type Query {\n")
+	if schema.extendRoots && len(schema.queries) == 0 {
+		return
+	}
+	schema.writeRootType("Query")
+	schema.generateNodeQuery()
 
 	for _, query := range schema.queries {
+		schema.writeDescriptionString(query.Description, true)
+		schema.Indent()
 		if query.Input.Empty {
-			schema.Write(fmt.Sprintf("  %s: %s!\n", utils.LowercaseFirst(*query.Name), *query.Payload))
+			schema.Write(fmt.Sprintf("%s: %s", utils.LowercaseFirst(*query.Name),
+				schema.payloadType(query.Payload, query.NullablePayload)))
+		} else if len(query.Arguments) > 0 {
+			schema.Write(fmt.Sprintf("%s(%s): %s", utils.LowercaseFirst(*query.Name),
+				schema.arguments(query.Arguments), schema.payloadType(query.Payload, query.NullablePayload)))
 		} else {
 			if query.Input.Optional {
-				schema.Write(fmt.Sprintf("  %s(%s: %s): %s!\n", utils.LowercaseFirst(*query.Name),
-					query.Input.Param, query.Input.Type, *query.Payload))
+				schema.Write(fmt.Sprintf("%s(%s: %s): %s", utils.LowercaseFirst(*query.Name),
+					query.Input.Param, query.Input.Type, schema.payloadType(query.Payload, query.NullablePayload)))
 			} else {
-				schema.Write(fmt.Sprintf("  %s(%s: %s!): %s!\n", utils.LowercaseFirst(*query.Name),
-					query.Input.Param, query.Input.Type, *query.Payload))
+				schema.Write(fmt.Sprintf("%s(%s: %s!): %s", utils.LowercaseFirst(*query.Name),
+					query.Input.Param, query.Input.Type, schema.payloadType(query.Payload, query.NullablePayload)))
 			}
 		}
+		schema.writeDirectives(query.Directives)
+		schema.NewLine()
 		// q(input: InputType): ObjectType
 	}
 	schema.Write("}")
@@ -129,40 +273,130 @@ func (schema *Schema) generateQueries() {
 }
 
 func (schema *Schema) generateMutations() {
-	schema.Write("type Mutation {\n")
+	if schema.extendRoots && len(schema.mutations) == 0 {
+		return
+	}
+	schema.writeRootType("Mutation")
 
 	for _, mutation := range schema.mutations {
+		schema.writeDescriptionString(mutation.Description, true)
+		schema.Indent()
 		if mutation.Input.Empty {
-			schema.Write(fmt.Sprintf("  %s: %s\n", utils.LowercaseFirst(*mutation.Name), *mutation.Payload))
+			schema.Write(fmt.Sprintf("%s: %s", utils.LowercaseFirst(*mutation.Name),
+				schema.payloadType(mutation.Payload, mutation.NullablePayload)))
+		} else if len(mutation.Arguments) > 0 {
+			schema.Write(fmt.Sprintf("%s(%s): %s", utils.LowercaseFirst(*mutation.Name),
+				schema.arguments(mutation.Arguments), schema.payloadType(mutation.Payload, mutation.NullablePayload)))
 		} else {
 			if mutation.Input.Optional {
-				schema.Write(fmt.Sprintf("  %s(%s: %s): %s!\n", utils.LowercaseFirst(*mutation.Name),
-					mutation.Input.Param, mutation.Input.Type, *mutation.Payload))
+				schema.Write(fmt.Sprintf("%s(%s: %s): %s", utils.LowercaseFirst(*mutation.Name),
+					mutation.Input.Param, mutation.Input.Type, schema.payloadType(mutation.Payload, mutation.NullablePayload)))
 			} else {
-				schema.Write(fmt.Sprintf("  %s(%s: %s!): %s!\n", utils.LowercaseFirst(*mutation.Name),
-					mutation.Input.Param, mutation.Input.Type, *mutation.Payload))
+				schema.Write(fmt.Sprintf("%s(%s: %s!): %s", utils.LowercaseFirst(*mutation.Name),
+					mutation.Input.Param, mutation.Input.Type, schema.payloadType(mutation.Payload, mutation.NullablePayload)))
 			}
 		}
+		schema.writeDirectives(mutation.Directives)
+		schema.NewLine()
 		// q(input: InputType): ObjectType
 	}
 	schema.Write("}")
 	schema.NewLine()
 }
 
+// Writes the opening of a root type, `extend type Query {` if an earlier output file defines it
+func (schema *Schema) writeRootType(name string) {
+	if schema.extendRoots {
+		schema.Write(string(syntax.Extend))
+		schema.Space()
+	}
+	schema.Write(string(syntax.ObjectType) + " " + name + " {\n")
+}
+
+// payloadType returns the type of a query or mutation payload, non-null unless nullable or all_nullable is set
+func (schema *Schema) payloadType(payload *string, nullable bool) string {
+	if nullable || schema.args.AllNullable {
+		return *payload
+	}
+	return *payload + string(syntax.Bang)
+}
+
+// nonNullOutput checks if a field of an output type is written non-null. With all_nullable, no output field is
+func (schema *Schema) nonNullOutput(field *descriptor.Field) bool {
+	return !field.Optional && !schema.args.AllNullable
+}
+
+// generate writes the schema, its sections in the order of the section_order option
 func (schema *Schema) generate() {
+	schema.generateHeader(true)
+	sections := schema.args.Sections()
+	for i, section := range sections {
+		schema.generateSection(section)
+		// The roots end without a blank line, unless other sections follow them
+		if section == SectionOperations && i < len(sections)-1 {
+			schema.NewLine()
+		}
+	}
+}
+
+// generateDefinitions writes the schema without its Query and Mutation roots, which go to the operations file
+func (schema *Schema) generateDefinitions() {
+	schema.generateHeader(false)
+	for _, section := range schema.args.Sections() {
+		if section != SectionOperations {
+			schema.generateSection(section)
+		}
+	}
+}
+
+// generateSection writes a section of the schema selected by the section_order option
+func (schema *Schema) generateSection(section string) {
+	switch section {
+	case SectionTypes:
+		schema.generateTypes()
+	case SectionInputs:
+		schema.generateInputTypes()
+	case SectionEnums:
+		schema.generateEnums()
+	case SectionOperations:
+		schema.generateOperations()
+	}
+}
+
+// generateHeader writes the header, the documentation and the preamble of the schema,
+// and the declarations its sections depend on. roots is set if the schema also writes the Query and Mutation roots,
+// else the schema definition carrying the documentation goes to the operations file
+func (schema *Schema) generateHeader(roots bool) {
 	// Write the header content to the string builder
 	schema.WriteHeader()
 
-	// Generate output types )
-	schema.generateTypes()
+	// Write the documentation of the proto file
+	if roots {
+		schema.WriteDescription()
+	}
+
+	// Write the handwritten preamble, so generated types can reference it
+	schema.WritePreamble()
+
+	// Import the federation directives used by the types
+	schema.generateFederationLink()
+
+	// Declare the custom scalars used by the types
+	schema.generateScalars()
 
-	// Generate input types
-	schema.generateInputTypes()
+	// Declare the @values directive of enum_as_string_with_values
+	schema.generateValuesDirective()
 
-	// Generate enums
-	schema.generateEnums()
+	// Declare the @constraint directive of gql_non_empty
+	schema.generateConstraintDirective()
+
+	// Declare the Node interface implemented by the types
+	schema.generateNodeInterface()
+}
 
-	// // Generate queries
+// generateOperations writes the Query and Mutation roots of the schema
+func (schema *Schema) generateOperations() {
+	// Generate queries
 	schema.generateQueries()
 
 	// Generate mutations
@@ -170,10 +404,11 @@ func (schema *Schema) generate() {
 }
 
 // Writes the type's name
-func (schema *Schema) WriteTypeName(keyWord syntax.Keyword, name *string) {
+func (schema *Schema) WriteTypeName(keyWord syntax.Keyword, name *string, directives ...string) {
 	schema.Write(string(keyWord))
 	schema.Space()
 	schema.Write(*name)
+	schema.writeDirectives(directives)
 	schema.Space()
 	schema.Write(string(syntax.LBrace))
 	schema.NewLine()
@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fverse/protoc-graphql/options"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// checkGolden compares the output with the golden file in testdata
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("output does not match %s\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// shopFile returns a proto file with a type, an input, an enum, a query and a mutation
+func shopFile() *descriptorpb.FileDescriptorProto {
+	file := testFile("shop.proto", "shop",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetProductRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Product",
+				scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				enumField("category", 2, ".shop.Category"),
+			),
+		},
+		testMethod("GetProduct", ".shop.GetProductRequest", ".shop.Product", nil),
+		testMethod("UpdateProduct", ".shop.Product", ".shop.Product", &options.MethodOptions{Kind: "mutation"}),
+	)
+	file.EnumType = []*descriptorpb.EnumDescriptorProto{
+		testEnum("Category", "CATEGORY_UNSPECIFIED", 0, "CATEGORY_BOOKS", 1),
+	}
+	return file
+}
+
+func TestIndent(t *testing.T) {
+	tests := []struct {
+		parameter string
+		golden    string
+	}{
+		{"", "indent/2.graphql"},
+		{"indent=2", "indent/2.graphql"},
+		{"indent=4", "indent/4.graphql"},
+		{"indent=tab", "indent/tab.graphql"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.parameter, func(t *testing.T) {
+			out := generate(t, tt.parameter, shopFile())["shop.graphql"]
+			checkGolden(t, tt.golden, out)
+		})
+	}
+}
+
+func TestArgWrap(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("ListUsersRequest",
+				scalarField("limit", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+				scalarField("offset", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+				scalarField("order_by", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				messageField("filter", 4, ".users.Filter"),
+			),
+			testMessage("Filter", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+		testMethod("ListUsers", ".users.ListUsersRequest", ".users.User", nil),
+		testMethod("ImportUsers", ".users.ListUsersRequest", ".users.User", &options.MethodOptions{Kind: "mutation"}),
+	)
+
+	tests := []struct {
+		parameter string
+		golden    string
+	}{
+		{"flatten_args", "argwrap/inline.graphql"},
+		{"flatten_args,arg_wrap=4", "argwrap/inline.graphql"},
+		{"flatten_args,arg_wrap=3", "argwrap/wrapped.graphql"},
+		{"flatten_args,arg_wrap=3,indent=4", "argwrap/wrapped_indent.graphql"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.parameter, func(t *testing.T) {
+			checkGolden(t, tt.golden, generate(t, tt.parameter, file)["users.graphql"])
+		})
+	}
+}
+
+func TestFileDescription(t *testing.T) {
+	documented := shopFile()
+	documented.SourceCodeInfo = &descriptorpb.SourceCodeInfo{
+		Location: []*descriptorpb.SourceCodeInfo_Location{
+			{Path: []int32{12}, LeadingComments: proto.String(" The shop API.\n\n Lists and updates products.\n")},
+		},
+	}
+	checkGolden(t, "description/documented.graphql", generate(t, "", documented)["shop.graphql"])
+
+	// Without a comment on the syntax statement, the package comment is used
+	packageDocumented := shopFile()
+	packageDocumented.SourceCodeInfo = &descriptorpb.SourceCodeInfo{
+		Location: []*descriptorpb.SourceCodeInfo_Location{
+			{Path: []int32{2}, LeadingComments: proto.String(" The shop API.\n\n Lists and updates products.\n")},
+		},
+	}
+	checkGolden(t, "description/documented.graphql", generate(t, "", packageDocumented)["shop.graphql"])
+
+	// Single-line documentation is a string, and moves to the operations file with the roots
+	packageDocumented.SourceCodeInfo.Location[0].LeadingComments = proto.String(" The \"shop\" API.\n")
+	out := generate(t, "combine_output,operations_file=operations.graphql", packageDocumented)
+	want := "\"The \\\"shop\\\" API.\"\nschema {\n  query: Query\n  mutation: Mutation\n}\n\ntype Query {\n"
+	if !strings.Contains(out["operations.graphql"], want) {
+		t.Errorf("operations file should contain\n%s\ngot:\n%s", want, out["operations.graphql"])
+	}
+	if strings.Contains(out["schema.graphql"], "shop") {
+		t.Errorf("schema file should not be documented, got:\n%s", out["schema.graphql"])
+	}
+
+	// Files extending the roots of an earlier file can't define the schema, their documentation is a comment
+	second := usersFile("users.proto", "users")
+	second.SourceCodeInfo = &descriptorpb.SourceCodeInfo{
+		Location: []*descriptorpb.SourceCodeInfo_Location{{Path: []int32{12}, LeadingComments: proto.String(" The user API.\n")}},
+	}
+	out = generate(t, "extend_roots", shopFile(), second)
+	if users := out["users.graphql"]; !strings.Contains(users, "# The user API.\n\n") || strings.Contains(users, "schema {") {
+		t.Errorf("users.graphql should document the file with a comment, got:\n%s", users)
+	}
+
+	checkGolden(t, "indent/2.graphql", generate(t, "", shopFile())["shop.graphql"])
+}
+
+func TestFederationDirectives(t *testing.T) {
+	email := scalarField("email", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	email.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(email.Options, options.E_GqlTag, []string{"public", "partner"})
+
+	internalNote := testMessage("InternalNote", scalarField("text", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING))
+	internalNote.Options = &descriptorpb.MessageOptions{}
+	proto.SetExtension(internalNote.Options, options.E_GqlTypeInaccessible, true)
+
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User",
+				scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				email,
+				messageField("note", 3, ".users.InternalNote"),
+			),
+			internalNote,
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+	checkGolden(t, "federation/directives.graphql", generate(t, "", file)["users.graphql"])
+
+	// Only the directives actually used are imported, from the selected version
+	tagged := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", email),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+	out := generate(t, "federation_version=2.5", tagged)["users.graphql"]
+	want := "extend schema\n  @link(url: \"https://specs.apollo.dev/federation/v2.5\", import: [\"@tag\"])\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("schema should import only @tag from federation v2.5, got:\n%s", out)
+	}
+
+	// Schemas without federation options import nothing
+	checkGolden(t, "indent/2.graphql", generate(t, "", shopFile())["shop.graphql"])
+}
@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Field number of the google.api.http method option. The plugin doesn't import google/api/annotations.proto,
+// the option is read from the unknown fields of the method options
+const httpRuleNumber = 72295728
+
+// Field numbers of google.api.HttpRule
+const (
+	httpRuleGet                = 2
+	httpRulePut                = 3
+	httpRulePost               = 4
+	httpRuleDelete             = 5
+	httpRulePatch              = 6
+	httpRuleBody               = 7
+	httpRuleCustom             = 8
+	httpRuleAdditionalBindings = 11
+)
+
+// A REST mapping of a gRPC method, from a google.api.HttpRule
+type httpBinding struct {
+	method, path, body string
+}
+
+// Returns the description of an operation listing the REST mappings of its gRPC method, one per line,
+// e.g. "HTTP: GET /v1/users/{id}", if expose_http is set and the method has a google.api.http option
+func (schema *Schema) httpDescription(method *descriptorpb.MethodDescriptorProto) string {
+	if !schema.args.ExposeHttp || method.GetOptions() == nil {
+		return ""
+	}
+	var lines []string
+	err := rangeBytesFields(method.GetOptions().ProtoReflect().GetUnknown(), func(number protowire.Number, value []byte) error {
+		if number != httpRuleNumber {
+			return nil
+		}
+		bindings, err := parseHttpRule(value)
+		for _, binding := range bindings {
+			line := fmt.Sprintf("HTTP: %s %s", binding.method, binding.path)
+			if binding.body != "" {
+				line += fmt.Sprintf(" (body: %s)", binding.body)
+			}
+			lines = append(lines, line)
+		}
+		return err
+	})
+	if err != nil {
+		schema.Error(err, "error reading google.api.http option of", method.GetName())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Parses an encoded google.api.HttpRule into its binding, followed by its additional bindings
+func parseHttpRule(data []byte) ([]httpBinding, error) {
+	var binding httpBinding
+	var additional []httpBinding
+	err := rangeBytesFields(data, func(number protowire.Number, value []byte) error {
+		switch number {
+		case httpRuleGet:
+			binding.method, binding.path = "GET", string(value)
+		case httpRulePut:
+			binding.method, binding.path = "PUT", string(value)
+		case httpRulePost:
+			binding.method, binding.path = "POST", string(value)
+		case httpRuleDelete:
+			binding.method, binding.path = "DELETE", string(value)
+		case httpRulePatch:
+			binding.method, binding.path = "PATCH", string(value)
+		case httpRuleBody:
+			binding.body = string(value)
+		case httpRuleCustom:
+			// google.api.CustomHttpPattern, its kind (1) and path (2)
+			return rangeBytesFields(value, func(number protowire.Number, value []byte) error {
+				switch number {
+				case 1:
+					binding.method = string(value)
+				case 2:
+					binding.path = string(value)
+				}
+				return nil
+			})
+		case httpRuleAdditionalBindings:
+			bindings, err := parseHttpRule(value)
+			additional = append(additional, bindings...)
+			return err
+		}
+		return nil
+	})
+	if err != nil || binding.method == "" {
+		return additional, err
+	}
+	return append([]httpBinding{binding}, additional...), nil
+}
+
+// Calls fn with the number and value of each length-delimited field of an encoded message, skipping other fields
+func rangeBytesFields(data []byte, fn func(number protowire.Number, value []byte) error) error {
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		if wireType != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(number, wireType, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+		value, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		if err := fn(number, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
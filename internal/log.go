@@ -1,49 +1,105 @@
 package internal
 
 import (
-	"fmt"
+	"io"
 	"log"
 	"os"
-	"path/filepath"
 )
 
+// Level is the severity of a log message
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// Returns the label printed in front of messages of the level
+func (level Level) String() string {
+	switch level {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// Logger writes leveled messages to stderr. Stdout carries the protobuf
+// response in plugin mode, so the logger never writes to it.
 type Logger struct {
 	*log.Logger
-	file *os.File
+	level Level
+	// Number of warnings, including the ones not printed at the level
+	warnings int
 }
 
-func NewLogger() *Logger {
-	err := os.MkdirAll("logs", 0755)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating logs directory: %v\n", err)
-		return nil
-	}
-
-	logFile, err := os.OpenFile(
-		filepath.Join("logs", "codegen.log"),
-		os.O_CREATE|os.O_WRONLY|os.O_APPEND,
-		0644,
-	)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating debug log file: %v\n", err)
-		return nil
-	}
+// NewLogger creates a Logger that prints messages at or above the given level to stderr
+func NewLogger(level Level) *Logger {
+	return newLogger(os.Stderr, level)
+}
 
-	logger := log.New(logFile, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile)
+func newLogger(w io.Writer, level Level) *Logger {
 	return &Logger{
-		Logger: logger,
-		file:   logFile,
+		Logger: log.New(w, "", 0),
+		level:  level,
 	}
 }
 
-func (l *Logger) Close() {
-	if l.file != nil {
-		l.file.Close()
+// Sets the minimum level of the printed messages
+func (l *Logger) SetLevel(level Level) {
+	l.level = level
+}
+
+// Checks if messages of the given level are printed
+func (l *Logger) Enabled(level Level) bool {
+	return l != nil && l.Logger != nil && level >= l.level
+}
+
+func (l *Logger) print(level Level, format string, v ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	prefix := NAME + ": "
+	if level != LevelInfo {
+		prefix += level.String() + ": "
 	}
+	l.Logger.Printf(prefix+format, v...)
 }
 
+// Log prints a debug message
 func (l *Logger) Log(format string, v ...interface{}) {
-	if l.Logger != nil {
-		l.Logger.Printf(format, v...)
+	l.print(LevelDebug, format, v...)
+}
+
+// Info prints an informational message
+func (l *Logger) Info(format string, v ...interface{}) {
+	l.print(LevelInfo, format, v...)
+}
+
+// Warn prints a warning. A nil logger, as parsing args without a logger uses, ignores it
+func (l *Logger) Warn(format string, v ...interface{}) {
+	if l == nil {
+		return
+	}
+	l.warnings++
+	l.print(LevelWarn, format, v...)
+}
+
+// Warnings returns the number of warnings, printed or not
+func (l *Logger) Warnings() int {
+	if l == nil {
+		return 0
 	}
+	return l.warnings
+}
+
+// Error prints an error. Errors are printed at every level
+func (l *Logger) Error(format string, v ...interface{}) {
+	l.print(LevelError, format, v...)
 }
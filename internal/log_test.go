@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoggerLevels(t *testing.T) {
+	tests := []struct {
+		level Level
+		want  []string
+		skip  []string
+	}{
+		{LevelDebug, []string{"debug: d", "i", "warning: w", "error: e"}, nil},
+		{LevelInfo, []string{"i", "warning: w", "error: e"}, []string{"debug: d"}},
+		{LevelError, []string{"error: e"}, []string{"debug: d", ": i", "warning: w"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := newLogger(&buf, tt.level)
+			logger.Log("d")
+			logger.Info("i")
+			logger.Warn("w")
+			logger.Error("e")
+
+			out := buf.String()
+			for _, s := range tt.want {
+				if !strings.Contains(out, NAME+": "+s+"\n") {
+					t.Errorf("output should contain %q, got:\n%s", s, out)
+				}
+			}
+			for _, s := range tt.skip {
+				if strings.Contains(out, s+"\n") {
+					t.Errorf("output should NOT contain %q, got:\n%s", s, out)
+				}
+			}
+		})
+	}
+}
+
+func TestArgsLogLevel(t *testing.T) {
+	tests := []struct {
+		params string
+		want   Level
+	}{
+		{"", LevelInfo},
+		{"verbose", LevelDebug},
+		{"quiet", LevelError},
+		{"verbose,quiet", LevelError},
+	}
+
+	for _, tt := range tests {
+		if got := ParseArgs(tt.params, nil).LogLevel(); got != tt.want {
+			t.Errorf("ParseArgs(%q).LogLevel() = %v, want %v", tt.params, got, tt.want)
+		}
+	}
+
+	// Warnings of args parsed without a logger are dropped
+	ParseArgs("bogus_option", nil)
+	if got := (*Logger)(nil).Warnings(); got != 0 {
+		t.Errorf("a nil logger should count no warnings, got %d", got)
+	}
+}
+
+// captureOutput runs fn and returns everything it wrote to stdout and stderr
+func captureOutput(t *testing.T, fn func()) (string, string) {
+	t.Helper()
+
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderrR, stderrW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = stdoutW, stderrW
+	defer func() {
+		os.Stdout, os.Stderr = stdout, stderr
+	}()
+
+	fn()
+
+	stdoutW.Close()
+	stderrW.Close()
+	out, _ := io.ReadAll(stdoutR)
+	errOut, _ := io.ReadAll(stderrR)
+	return string(out), string(errOut)
+}
+
+func TestQuietLoggerKeepsStdoutClean(t *testing.T) {
+	stdout, stderr := captureOutput(t, func() {
+		logger := NewLogger(ParseArgs("quiet", nil).LogLevel())
+		logger.Log("debug message")
+		logger.Info("info message")
+		logger.Warn("warning message")
+		logger.Error("error message")
+	})
+
+	if stdout != "" {
+		t.Errorf("stdout should be empty, got %q", stdout)
+	}
+	if stderr != NAME+": error: error message\n" {
+		t.Errorf("stderr should contain only the error, got %q", stderr)
+	}
+}
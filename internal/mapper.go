@@ -0,0 +1,27 @@
+package internal
+
+import (
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// TypeMapper maps proto fields to GraphQL types. Programs embedding the plugin register them through pkg/plugin.
+// MapField returns false to leave a field to the built-in mappings
+type TypeMapper interface {
+	MapField(field *descriptorpb.FieldDescriptorProto) (gqlType string, ok bool)
+}
+
+// AddTypeMapper registers a type mapper, before Execute. The first mapper mapping a field takes precedence
+// over the built-in mappings. The type is a scalar, declared unless built in, and keeps the list and nullability of the field
+func (plugin *Plugin) AddTypeMapper(mapper TypeMapper) {
+	plugin.typeMappers = append(plugin.typeMappers, mapper)
+}
+
+// Returns the GraphQL type of a field from the first type mapper mapping it
+func (plugin *Plugin) mapField(field *descriptorpb.FieldDescriptorProto) (string, bool) {
+	for _, mapper := range plugin.typeMappers {
+		if gqlType, ok := mapper.MapField(field); ok {
+			return gqlType, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fverse/protoc-graphql/internal/descriptor"
+	"github.com/fverse/protoc-graphql/pkg/utils"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Suffix of the payload types of mutation_payloads, unless payload_suffix is set
+const defaultPayloadSuffix = "Payload"
+
+// Wraps the payload of a mutation in a generated payload type if mutation_payloads is set, e.g. CreateUserPayload
+// with a user field for a CreateUser mutation returning User. The result field has the nullability of the payload,
+// the wrapper is non-null. With client_mutation_id, the wrapper also has a clientMutationId field
+func (schema *Schema) wrapMutationPayload(service *descriptorpb.ServiceDescriptorProto, mutation *descriptor.Mutation) {
+	if !schema.args.MutationPayloads {
+		return
+	}
+	suffix := defaultPayloadSuffix
+	if schema.args.PayloadSuffix != "" {
+		suffix = schema.args.PayloadSuffix
+	}
+	name := schema.boundedName(schema.prefixed(*mutation.Name + suffix))
+	for _, objectType := range schema.objectTypes {
+		if *objectType.Name == name {
+			schema.Error(fmt.Errorf("%s and the payload of mutation %s both generate %s", objectType.Source, *mutation.Name, name),
+				"error generating method", *mutation.Name)
+		}
+	}
+
+	// The result field is named after the type of the result, e.g. user for User and [User!]
+	resultType := strings.Trim(*mutation.Payload, "[]!")
+	result := &descriptor.Field{
+		Name:     utils.String(utils.LowercaseFirst(strings.TrimPrefix(resultType, schema.args.TypePrefix))),
+		Type:     (*descriptor.GraphQLType)(mutation.Payload),
+		Optional: mutation.NullablePayload,
+	}
+	payload := &descriptor.ObjectType{
+		Name:    utils.String(name),
+		Fields:  []*descriptor.Field{result},
+		Source:  schema.protoFile.GetName() + ":" + service.GetName() + "." + *mutation.Name,
+		Package: schema.protoFile.GetPackage(),
+	}
+	if schema.args.ClientMutationId {
+		payload.Fields = append(payload.Fields, &descriptor.Field{
+			Name:     utils.String("clientMutationId"),
+			Type:     (*descriptor.GraphQLType)(utils.String(string(descriptor.String))),
+			Optional: true,
+		})
+	}
+	schema.objectTypes = append(schema.objectTypes, payload)
+
+	mutation.Payload = payload.Name
+	mutation.NullablePayload = false
+}
@@ -1,10 +1,12 @@
 package internal
 
 import (
-	"log"
 	"os"
 	"strings"
 
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/pluginpb"
 )
 
@@ -13,6 +15,9 @@ const NAME = "protoc-gen-graphql"
 // Version is set via ldflags at build time
 var Version = "dev"
 
+// Exits the process, replaced in tests
+var exit = os.Exit
+
 type Plugin struct {
 	Request  *pluginpb.CodeGeneratorRequest
 	Response *pluginpb.CodeGeneratorResponse
@@ -21,19 +26,36 @@ type Plugin struct {
 	Logger *Logger
 
 	schema []*Schema
+
+	// Content of the prepend file
+	preamble string
+
+	// Sections of the docs file by the name of their heading, and the names of the sections used by a schema
+	docs     map[string]string
+	docsUsed map[string]bool
+
+	// Custom options exposed with expose_option, and the registry of their extensions
+	exposedOptions []*exposedOption
+	exposedTypes   *protoregistry.Types
+
+	// Type mappers registered with AddTypeMapper
+	typeMappers []TypeMapper
 }
 
-// Sets the support optional field option
-func (plugin *Plugin) SetSupportOptionalField() {
-	opt := uint64(pluginpb.CodeGeneratorResponse_Feature_value["FEATURE_PROTO3_OPTIONAL"])
-	plugin.Response.SupportedFeatures = &opt
+// Sets the features supported by the plugin: proto3 optional fields, and the editions up to 2023
+func (plugin *Plugin) SetSupportedFeatures() {
+	features := uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL | pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS)
+	plugin.Response.SupportedFeatures = &features
+	plugin.Response.MinimumEdition = proto.Int32(int32(descriptorpb.Edition_EDITION_PROTO2))
+	plugin.Response.MaximumEdition = proto.Int32(int32(descriptorpb.Edition_EDITION_2023))
 }
 
 // New creates a new Plugin
 func New(request *pluginpb.CodeGeneratorRequest) *Plugin {
-	logger := NewLogger()
+	logger := NewLogger(LevelInfo)
 	args := ParseArgs(request.GetParameter(), logger)
-	logger.Log("args2: %+v", args)
+	logger.SetLevel(args.LogLevel())
+	logger.Log("args: %+v", args)
 
 	return &Plugin{
 		Request:  request,
@@ -50,12 +72,12 @@ func (p *Plugin) Version() string {
 // Prints an error, and exits.
 func (p *Plugin) Error(err error, msgs ...string) {
 	s := strings.Join(msgs, " ") + ": " + err.Error()
-	log.Print(NAME+": error: ", s)
-	os.Exit(1)
+	p.Logger.Error("%s", s)
+	exit(1)
 }
 
 // Prints a message
 func (p *Plugin) Info(msg ...string) {
 	s := strings.Join(msg, " ")
-	log.Print(NAME+": ", s)
+	p.Logger.Info("%s", s)
 }
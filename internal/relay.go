@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/fverse/protoc-graphql/internal/descriptor"
+	"github.com/fverse/protoc-graphql/internal/syntax"
+	"github.com/fverse/protoc-graphql/options"
+	"github.com/fverse/protoc-graphql/pkg/utils"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Interface of Relay global object identification, implemented by the types with an id with relay_node
+const nodeInterface = "Node"
+
+// Makes an object type implement the Node interface if relay_node is set and the message has an id:
+// the field with the gql_id option, else the field named id. The id field becomes `id: ID!`.
+// The fields of the type are the fields generated from the message, in the same order
+func (schema *Schema) implementNode(objectType *descriptor.ObjectType, message *descriptorpb.DescriptorProto) {
+	if !schema.args.RelayNode || len(message.Field) == 0 {
+		return
+	}
+
+	index, explicit := -1, false
+	for i, field := range message.Field {
+		if boolFieldOption(field.GetOptions(), options.E_GqlId) {
+			index, explicit = i, true
+			break
+		}
+	}
+	if index == -1 {
+		for i, field := range objectType.Fields {
+			if *field.Name == "id" {
+				index = i
+				break
+			}
+		}
+	}
+	if index == -1 {
+		return
+	}
+
+	id := objectType.Fields[index]
+	switch message.Field[index].GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_TYPE_GROUP, descriptorpb.FieldDescriptorProto_TYPE_ENUM:
+		if explicit {
+			schema.Error(fmt.Errorf("gql_id field %s must be a scalar", message.Field[index].GetName()),
+				"error generating type", *objectType.Name)
+		}
+		return
+	}
+	if id.IsList {
+		if explicit {
+			schema.Error(fmt.Errorf("gql_id field %s can't be repeated", message.Field[index].GetName()),
+				"error generating type", *objectType.Name)
+		}
+		return
+	}
+	for i, field := range objectType.Fields {
+		if i != index && *field.Name == "id" {
+			schema.Error(fmt.Errorf("gql_id field %s conflicts with the field id", message.Field[index].GetName()),
+				"error generating type", *objectType.Name)
+		}
+	}
+
+	idType := descriptor.ID
+	id.Name = utils.String("id")
+	id.Type = &idType
+	id.Optional = false
+	id.CustomScalar = false
+	objectType.Interfaces = append(objectType.Interfaces, nodeInterface)
+}
+
+// Writes the Node interface, if relay_node is set
+func (schema *Schema) generateNodeInterface() {
+	if !schema.args.RelayNode {
+		return
+	}
+	schema.WriteTypeName(syntax.Interface, utils.String(nodeInterface))
+	schema.Indent()
+	if schema.args.AllNullable {
+		// The ids of the types implementing Node are nullable too
+		schema.Write("id: ID")
+	} else {
+		schema.Write("id: ID!")
+	}
+	schema.NewLine()
+	schema.Write(string(syntax.RBrace))
+	schema.NewLine(2)
+}
+
+// Writes the node root query fetching any Node by id, if relay_node is set.
+// Schemas extending the roots of an earlier output file don't repeat it
+func (schema *Schema) generateNodeQuery() {
+	if !schema.args.RelayNode || schema.extendRoots {
+		return
+	}
+	schema.Indent()
+	schema.Write("node(id: ID!): " + nodeInterface)
+	schema.NewLine()
+}
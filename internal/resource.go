@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"strings"
+
+	"github.com/fverse/protoc-graphql/internal/descriptor"
+	"github.com/fverse/protoc-graphql/options"
+	"github.com/fverse/protoc-graphql/pkg/utils"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Records the get<Type>(id: ID!) query of a type with a scalar id field, if resource_queries is set,
+// unless the message sets gql_resource_query to false. The queries are added after the queries of the RPCs
+func (schema *Schema) resourceQuery(objectType *descriptor.ObjectType, message *descriptorpb.DescriptorProto) {
+	if !schema.args.ResourceQueries {
+		return
+	}
+	if proto.HasExtension(message.GetOptions(), options.E_GqlResourceQuery) &&
+		!proto.GetExtension(message.GetOptions(), options.E_GqlResourceQuery).(bool) {
+		return
+	}
+	hasID := false
+	for _, field := range objectType.Fields {
+		hasID = hasID || *field.Name == "id" && !field.NonPrimitive && !field.IsList
+	}
+	if !hasID {
+		return
+	}
+
+	idType := descriptor.ID
+	query := new(descriptor.Query)
+	query.Name = utils.String("get" + strings.TrimPrefix(*objectType.Name, schema.args.TypePrefix))
+	query.Input = &options.GqlInput{}
+	query.Arguments = []*descriptor.Field{{Name: utils.String("id"), Type: &idType}}
+	query.Payload = objectType.Name
+	// The resource may not exist
+	query.NullablePayload = true
+	schema.resourceQueries = append(schema.resourceQueries, query)
+}
+
+// Adds the queries of resource_queries, except those named like a query of an RPC, which takes precedence
+func (schema *Schema) addResourceQueries() {
+	names := make(map[string]bool)
+	for _, query := range schema.queries {
+		names[utils.LowercaseFirst(*query.Name)] = true
+	}
+	for _, query := range schema.resourceQueries {
+		if names[utils.LowercaseFirst(*query.Name)] {
+			schema.Logger.Log("the RPC query %s takes precedence over the resource query", utils.LowercaseFirst(*query.Name))
+			continue
+		}
+		schema.queries = append(schema.queries, query)
+	}
+}
@@ -1,8 +1,12 @@
 package internal
 
 import (
-	"log"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/fverse/protoc-graphql/internal/analyzer"
@@ -11,20 +15,34 @@ import (
 	"github.com/fverse/protoc-graphql/options"
 	"github.com/fverse/protoc-graphql/pkg/utils"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 type Schema struct {
 	*strings.Builder
 
+	plugin *Plugin
+
 	// Plugin's parsed command line arguments
 	args   *Args
 	Logger *Logger
 
+	// Transform applied to proto field names, selected by the field_case option
+	fieldCase func(string) string
+	// Transform applied to message and enum names, selected by the type_case option
+	typeCase func(string) string
+
 	protoFile   *descriptorpb.FileDescriptorProto
 	packageName *string
 	fileName    *string
 
+	// Handwritten SDL written after the header
+	preamble string
+
+	// Documentation of the proto file, from the comment before its syntax or package statement
+	description string
+
 	// Type analyzer for dependency-based filtering
 	typeAnalyzer *analyzer.TypeAnalyzer
 
@@ -33,6 +51,27 @@ type Schema struct {
 	inputTypes  []*descriptor.InputType
 	mutations   []*descriptor.Mutation
 	queries     []*descriptor.Query
+
+	// Custom scalars referenced by the schema's types, in order of first use
+	scalars []string
+
+	// Federation directives used by the schema's types and fields, imported with @link
+	federationImports []string
+
+	// If true, fields of the schema use the @values directive of enum_as_string_with_values, which is declared
+	declareValues bool
+
+	// If true, fields of the schema use the @constraint directive of gql_non_empty, which is declared
+	declareConstraint bool
+
+	// Queries of resource_queries, added after the queries of the RPCs
+	resourceQueries []*descriptor.Query
+
+	// If true, an earlier output file defines the Query and Mutation roots, which this schema extends
+	extendRoots bool
+
+	// Fields already warned about generating a reserved name, as a field generates a type, an input and arguments
+	reservedWarned map[*descriptorpb.FieldDescriptorProto]bool
 }
 
 // Checks the keepCase option for the fields
@@ -44,92 +83,497 @@ func keepCase(fieldOptions *descriptorpb.FieldOptions) bool {
 	return false
 }
 
+// Checks the gql_as_scalar option for the enums
+func gqlAsScalar(enumOptions *descriptorpb.EnumOptions) bool {
+	if proto.HasExtension(enumOptions, options.E_GqlAsScalar) {
+		return proto.GetExtension(enumOptions, options.E_GqlAsScalar).(bool)
+	}
+	return false
+}
+
+// Checks the gql_type_name option for the messages
+func gqlTypeName(messageOptions *descriptorpb.MessageOptions) string {
+	if proto.HasExtension(messageOptions, options.E_GqlTypeName) {
+		return proto.GetExtension(messageOptions, options.E_GqlTypeName).(string)
+	}
+	return ""
+}
+
+// Returns the GraphQL name of the type and input generated for a message, from its fully qualified name.
+// The gql_type_name option takes precedence over the proto name transformed by type_case
+func (schema *Schema) typeName(fullName string) string {
+	if message := schema.typeAnalyzer.Message(fullName); message != nil {
+		if name := gqlTypeName(message.GetOptions()); name != "" {
+			return schema.boundedName(schema.prefixed(name))
+		}
+	}
+	return schema.boundedName(schema.prefixed(schema.baseTypeName(fullName, true)))
+}
+
+// Returns the GraphQL name of a proto enum, from its fully qualified name. Enums are named like messages,
+// without strip_suffix
+func (schema *Schema) enumName(fullName string) string {
+	return schema.boundedName(schema.prefixed(schema.baseTypeName(fullName, false)))
+}
+
+// Returns the name of a proto message or enum before type_prefix, from its fully qualified name:
+// its simple name, preceded by its package with keep_prefix, e.g. UsersStatus for users.Status, cased by type_case.
+// The simple name of a message is stripped of the suffixes of strip_suffix
+func (schema *Schema) baseTypeName(fullName string, message bool) string {
+	name := fullName[strings.LastIndex(fullName, ".")+1:]
+	if message {
+		name = schema.stripSuffix(name)
+	}
+	if schema.args.KeepPrefix {
+		name = utils.PascalCase(schema.typeAnalyzer.Package(fullName)) + name
+	}
+	return schema.typeCase(name)
+}
+
+// Strips the first suffix of strip_suffix a message name ends with, e.g. GetUser for GetUserResponse,
+// unless the suffix is the whole name
+func (schema *Schema) stripSuffix(name string) string {
+	for _, suffix := range schema.args.StripSuffixes {
+		if stripped, ok := strings.CutSuffix(name, suffix); ok && stripped != "" {
+			return stripped
+		}
+	}
+	return name
+}
+
+// Returns the GraphQL name of a type named by the gql_input or gql_output option.
+// It is a message of the package of the proto file, preceded by the package with keep_prefix
+func (schema *Schema) explicitTypeName(name string) string {
+	name = schema.stripSuffix(name)
+	if schema.args.KeepPrefix {
+		name = utils.PascalCase(schema.protoFile.GetPackage()) + name
+	}
+	return schema.boundedName(schema.prefixed(name))
+}
+
+// Prefixes a generated type, input or enum name with the type_prefix option
+func (schema *Schema) prefixed(name string) string {
+	return schema.args.TypePrefix + name
+}
+
+// Smallest max_name_length, which leaves room for the hash of truncated names
+const minNameLength = 16
+
+// Bounds a generated type, input or enum name to max_name_length. A longer name is truncated and ends with "_"
+// and the first 8 hex digits of the SHA-256 of the whole name, e.g. VeryLongNestedMes_1a2b3c4d, so truncated names stay unique
+func (schema *Schema) boundedName(name string) string {
+	limit := schema.args.MaxNameLength
+	if limit == 0 || len(name) <= limit {
+		return name
+	}
+	hash := sha256.Sum256([]byte(name))
+	return name[:limit-9] + "_" + hex.EncodeToString(hash[:])[:8]
+}
+
+// Checks the gql_name option for the fields
+func gqlName(fieldOptions *descriptorpb.FieldOptions) string {
+	if proto.HasExtension(fieldOptions, options.E_GqlName) {
+		return proto.GetExtension(fieldOptions, options.E_GqlName).(string)
+	}
+	return ""
+}
+
+// Returns the GraphQL name of a field.
+// In order of precedence: the gql_name option, the proto name with keep_case,
+// the json_name with use_json_name, and the proto name transformed by field_case
+func (schema *Schema) fieldName(field *descriptorpb.FieldDescriptorProto) *string {
+	if name := gqlName(field.GetOptions()); name != "" {
+		return &name
+	}
+	if keepCase(field.GetOptions()) {
+		return field.Name
+	}
+	if schema.args.UseJsonName && field.GetJsonName() != "" {
+		return field.JsonName
+	}
+	return utils.String(schema.fieldCase(field.GetName()))
+}
+
+// Introspection fields every GraphQL type has
+var introspectionFields = []string{"__typename", "__schema", "__type"}
+
+// Checks the name of a field against the names GraphQL reserves for introspection, starting with "__".
+// Reserved names are renamed to start with a single "_" with a warning, or fail generation with reserved_names=error
+func (schema *Schema) reservedFieldName(field *descriptorpb.FieldDescriptorProto, name *string) *string {
+	if !strings.HasPrefix(*name, "__") {
+		return name
+	}
+	reason := `names starting with "__" are reserved for introspection`
+	if slices.Contains(introspectionFields, *name) {
+		reason = "it collides with the introspection field " + *name
+	}
+	if schema.args.ReservedNames == ReservedNamesError {
+		schema.Error(fmt.Errorf("field %s generates the reserved name %s, %s", field.GetName(), *name, reason),
+			"error generating field")
+	}
+	sanitized := "_" + strings.TrimLeft(*name, "_")
+	if schema.reservedWarned[field] {
+		return &sanitized
+	}
+	if schema.reservedWarned == nil {
+		schema.reservedWarned = make(map[*descriptorpb.FieldDescriptorProto]bool)
+	}
+	schema.reservedWarned[field] = true
+	schema.Logger.Warn("field %s generates the reserved name %s, %s, renamed to %s", field.GetName(), *name, reason, sanitized)
+	return &sanitized
+}
+
 // Constructs the Object types from message types and fills the schema.objectTypes
 func (schema *Schema) makeObjectTypes(messages []*descriptorpb.DescriptorProto) {
 	schema.makeObjectTypesWithPrefix(messages, "")
 }
 
 // makeObjectTypesWithPrefix constructs object types with a name prefix for nested types
-// Only generates GraphQL `type` for output-reachable messages
+// Only generates GraphQL `type` for output-reachable messages.
+// Types are added in declaration order, a message before its nested messages
 func (schema *Schema) makeObjectTypesWithPrefix(messages []*descriptorpb.DescriptorProto, prefix string) {
 	for _, message := range messages {
 		// Build the fully qualified name for reachability check
-		var fullName string
-		if prefix == "" {
-			if schema.packageName != nil && *schema.packageName != "" {
-				fullName = "." + *schema.packageName + "." + message.GetName()
-			} else {
-				fullName = "." + message.GetName()
-			}
-		} else {
-			fullName = prefix + "." + message.GetName()
-		}
+		fullName := schema.messageFullName(message, prefix)
 
 		// Check if this type is OUTPUT-reachable before processing
 		// Only generate GraphQL `type` for output-reachable messages
-		if !schema.typeAnalyzer.IsOutputReachable(fullName) {
-			continue
+		if schema.typeAnalyzer.IsOutputReachable(fullName) && !schema.isScalar(fullName) && !schema.isUnwrappedOnly(fullName) {
+			schema.makeObjectType(message, fullName)
+		}
+
+		// Construct embedded object types (with updated prefix)
+		schema.makeObjectTypesWithPrefix(message.NestedType, fullName)
+
+		// Construct embedded enums (only if reachable)
+		schema.makeNestedEnums(message, fullName)
+	}
+}
+
+// Constructs the object type of an output-reachable message
+func (schema *Schema) makeObjectType(message *descriptorpb.DescriptorProto, fullName string) {
+	if !schema.isInputType(fullName) {
+		schema.checkFieldVisibility(message, fullName, true)
+	}
+	message = schema.visibleFields(message, true)
+
+	// Generate type fields
+	fields := schema.generateFields(message.Field)
+	schema.checkFieldNameCollisions(message, fields, "error generating type", schema.typeName(fullName))
+
+	// GraphQL types need at least one field, so empty messages are skipped unless keep_empty_messages is set
+	if len(fields) == 0 {
+		if !schema.args.KeepEmptyMessages {
+			schema.checkEmptyType(fullName)
+			return
 		}
+		fields = []*descriptor.Field{placeholderField()}
+	}
 
-		if len(message.Field) > 0 {
-			objectType := new(descriptor.ObjectType)
-			objectType.Name = message.Name
+	objectType := new(descriptor.ObjectType)
+	objectType.Name = utils.String(schema.typeName(fullName))
+	objectType.Source = schema.source(fullName)
+	objectType.Package = schema.typeAnalyzer.Package(fullName)
+	objectType.Directives = typeDirectives(message.GetOptions())
+	objectType.Directives, objectType.Description = schema.withExposedOptions(objectType.Directives, "", message.GetOptions())
+	objectType.Fields = fields
+	schema.describeOneofs(message, fields)
+	schema.nonNullFields(message, fields)
+	schema.implementNode(objectType, message)
+	schema.resourceQuery(objectType, message)
+	schema.objectTypes = append(schema.objectTypes, objectType)
+}
 
-			// Generate type fields
-			objectType.Fields = generateFields(message.Field)
+// Returns the field of types and inputs generated from messages without fields, with keep_empty_messages.
+// GraphQL types and inputs need at least one field
+func placeholderField() *descriptor.Field {
+	boolean := descriptor.Boolean
+	return &descriptor.Field{Name: utils.String("_"), Type: &boolean, Optional: true}
+}
 
-			// Construct embedded object types (with updated prefix)
-			for _, nested := range message.NestedType {
-				schema.makeObjectTypesWithPrefix([]*descriptorpb.DescriptorProto{nested}, fullName)
+// Returns the fully qualified name of a message, prefix is the name of the parent of nested messages
+func (schema *Schema) messageFullName(message *descriptorpb.DescriptorProto, prefix string) string {
+	if prefix != "" {
+		return prefix + "." + message.GetName()
+	}
+	if schema.packageName != nil && *schema.packageName != "" {
+		return "." + *schema.packageName + "." + message.GetName()
+	}
+	return "." + message.GetName()
+}
+
+// Constructs the reachable enums nested in a message, skipping the ones already constructed
+func (schema *Schema) makeNestedEnums(message *descriptorpb.DescriptorProto, fullName string) {
+	for _, enumType := range message.EnumType {
+		if !schema.isEnumGenerated(fullName + "." + enumType.GetName()) {
+			continue
+		}
+		enumExists := false
+		for _, existingEnum := range schema.enums {
+			if *existingEnum.Name == schema.enumName(fullName+"."+enumType.GetName()) {
+				enumExists = true
+				break
 			}
+		}
+		if !enumExists {
+			schema.enums = append(schema.enums, schema.makeEnum(enumType, fullName+"."+enumType.GetName()))
+		}
+	}
+}
 
-			// Construct embedded enums (only if reachable)
-			for _, enumType := range message.EnumType {
-				enumFullName := fullName + "." + enumType.GetName()
-				if schema.typeAnalyzer.IsEnumReachable(enumFullName) {
-					enum := new(descriptor.Enumeration)
-					enum.Name = enumType.Name
-					for _, value := range enumType.Value {
-						enum.Values = append(enum.Values, enumValues(value))
+// Fails generation for an empty type that is still referenced, if error_on_empty_type is set
+func (schema *Schema) checkEmptyType(fullName string) {
+	if !schema.args.ErrorOnEmptyType {
+		return
+	}
+	referrers := schema.referrers(fullName)
+	if len(referrers) == 0 {
+		return
+	}
+	name := strings.TrimPrefix(schema.source(fullName), schema.protoFile.GetName()+":")
+	schema.Error(fmt.Errorf("%s has no fields but is referenced by %s", name, strings.Join(referrers, ", ")),
+		"error generating type", name)
+}
+
+// Returns the RPC methods and output-reachable message fields that reference the type
+func (schema *Schema) referrers(fullName string) []string {
+	var referrers []string
+	for _, service := range schema.services() {
+		for _, method := range service.Method {
+			if method.GetOutputType() == fullName && !skipMethod(schema.target(), getMethodOptions(method)) {
+				referrers = append(referrers, service.GetName()+"."+method.GetName())
+			}
+		}
+	}
+
+	var visit func(messages []*descriptorpb.DescriptorProto, prefix string)
+	visit = func(messages []*descriptorpb.DescriptorProto, prefix string) {
+		for _, message := range messages {
+			messageName := prefix + "." + message.GetName()
+			if schema.typeAnalyzer.IsOutputReachable(messageName) {
+				for _, field := range message.Field {
+					if field.GetTypeName() == fullName {
+						referrers = append(referrers, strings.TrimPrefix(messageName, ".")+"."+field.GetName())
 					}
-					schema.enums = append(schema.enums, enum)
 				}
 			}
-			schema.objectTypes = append(schema.objectTypes, objectType)
+			visit(message.NestedType, messageName)
+		}
+	}
+	for _, protoFile := range schema.plugin.Request.ProtoFile {
+		prefix := ""
+		if protoFile.GetPackage() != "" {
+			prefix = "." + protoFile.GetPackage()
+		}
+		visit(protoFile.MessageType, prefix)
+	}
+	return referrers
+}
+
+// Returns the proto file and message name of a fully qualified type name, e.g. "users.proto:User"
+func (schema *Schema) source(fullName string) string {
+	name := strings.TrimPrefix(fullName, ".")
+	if schema.packageName != nil && *schema.packageName != "" {
+		name = strings.TrimPrefix(name, *schema.packageName+".")
+	}
+	return schema.protoFile.GetName() + ":" + name
+}
+
+// Return the enum value of the provided proto enum value, named by its gql_value_name option if set
+func enumValues(value *descriptorpb.EnumValueDescriptorProto) *descriptor.EnumValue {
+	name := value.Name
+	if proto.HasExtension(value.GetOptions(), options.E_GqlValueName) {
+		name = utils.String(proto.GetExtension(value.GetOptions(), options.E_GqlValueName).(string))
+	}
+	return &descriptor.EnumValue{
+		Name:   name,
+		Number: value.GetNumber(),
+	}
+}
+
+// Constructs an enum from a proto enum.
+// Aliases (values sharing a number with an earlier value) are kept, or deprecated with enum_aliases=deprecate
+func (schema *Schema) makeEnum(enumType *descriptorpb.EnumDescriptorProto, fullName string) *descriptor.Enumeration {
+	enum := new(descriptor.Enumeration)
+	enum.Name = utils.String(schema.enumName(fullName))
+	enum.Source = schema.source(fullName)
+	enum.Package = schema.typeAnalyzer.Package(fullName)
+
+	aliased := make(map[int32]string)
+	// Proto values of the generated value names, which gql_value_name may make collide
+	sources := make(map[string]string)
+	for _, value := range enumType.Value {
+		enumValue := enumValues(value)
+		if !graphqlName.MatchString(*enumValue.Name) {
+			schema.Error(fmt.Errorf("gql_value_name %q of %s is not a valid GraphQL name", *enumValue.Name, value.GetName()),
+				"error generating enum", *enum.Name)
+		}
+		if other, ok := sources[*enumValue.Name]; ok {
+			schema.Error(fmt.Errorf("values %s and %s both generate the value %s", other, value.GetName(), *enumValue.Name),
+				"error generating enum", *enum.Name)
+		}
+		sources[*enumValue.Name] = value.GetName()
+		if first, ok := aliased[value.GetNumber()]; ok {
+			if schema.args.EnumAliases == EnumAliasesDeprecate {
+				enumValue.Deprecation = "Alias of " + first
+			}
+		} else {
+			aliased[value.GetNumber()] = *enumValue.Name
 		}
+		enum.Values = append(enum.Values, enumValue)
 	}
+	schema.sortEnumValues(enum.Values)
+	return enum
 }
 
-// Return the string value of the provided enum value
-func enumValues(value *descriptorpb.EnumValueDescriptorProto) *string {
-	return value.Name
+// Sorts enum values as selected by the enum_value_order option. Values are kept in declaration order by default.
+// Sorting by number keeps the zero value, the proto default, first. Aliases keep their declaration order
+func (schema *Schema) sortEnumValues(values []*descriptor.EnumValue) {
+	switch schema.args.EnumValueOrder {
+	case EnumValueOrderNumber:
+		slices.SortStableFunc(values, func(a, b *descriptor.EnumValue) int {
+			switch {
+			case a.Number == b.Number:
+				return 0
+			case a.Number == 0:
+				return -1
+			case b.Number == 0:
+				return 1
+			case a.Number < b.Number:
+				return -1
+			default:
+				return 1
+			}
+		})
+	case EnumValueOrderName:
+		slices.SortStableFunc(values, func(a, b *descriptor.EnumValue) int {
+			return strings.Compare(*a.Name, *b.Name)
+		})
+	}
 }
 
 // Constructs the fields of an object type
-func generateFields(fields []*descriptorpb.FieldDescriptorProto) []*descriptor.Field {
+func (schema *Schema) generateFields(fields []*descriptorpb.FieldDescriptorProto) []*descriptor.Field {
 	result := make([]*descriptor.Field, 0, len(fields))
 
 	for _, field := range fields {
 		f := &descriptor.Field{
-			Name: field.Name,
+			Name:        field.Name,
+			Number:      field.GetNumber(),
+			Description: fieldExamples(field.GetOptions()),
+			Directives:  fieldDirectives(field.GetOptions()),
+		}
+		f.Directives, f.Description = schema.withExposedOptions(f.Directives, f.Description, field.GetOptions())
+		if schema.args.AnnotateProtoType {
+			f.Description = joinDescriptions(f.Description, "(proto: "+protoType(field)+")")
 		}
 		// Obtain the type of field
 		f.GetType(field)
 
+		// Enums generated as scalars are referenced as String,
+		// message and enum references use the names of their definitions
+		if field.GetType() == descriptorpb.FieldDescriptorProto_TYPE_ENUM && schema.isScalarEnum(field.GetTypeName()) {
+			stringType := descriptor.String
+			f.Type = &stringType
+			if schema.args.EnumAsStringWithValues {
+				f.AllowedValues = schema.enumValueNames(field.GetTypeName())
+			}
+		} else if f.NonPrimitive {
+			f.Type = (*descriptor.GraphQLType)(utils.String(schema.typeName(field.GetTypeName())))
+		} else if field.GetType() == descriptorpb.FieldDescriptorProto_TYPE_ENUM {
+			f.Type = (*descriptor.GraphQLType)(utils.String(schema.enumName(field.GetTypeName())))
+		}
+
+		// Map double fields to the scalar of the double_scalar option, float fields stay Float
+		if field.GetType() == descriptorpb.FieldDescriptorProto_TYPE_DOUBLE && schema.args.DoubleScalar != "" {
+			f.Type = (*descriptor.GraphQLType)(utils.String(schema.args.DoubleScalar))
+			f.CustomScalar = !isBuiltinScalar(schema.args.DoubleScalar)
+		}
+
+		// Map message types configured with the scalar option to custom scalars,
+		// and string fields matched by name with scalar_by_name
+		if scalar, ok := schema.scalar(field.GetTypeName()); ok {
+			f.Type = (*descriptor.GraphQLType)(utils.String(scalar))
+			f.NonPrimitive = false
+			f.CustomScalar = true
+		} else if scalar, ok := schema.scalarByName(field); ok {
+			f.Type = (*descriptor.GraphQLType)(utils.String(scalar))
+			f.CustomScalar = !isBuiltinScalar(scalar)
+		}
+
+		// Registered type mappers take precedence over the built-in mappings
+		if gqlType, ok := schema.plugin.mapField(field); ok {
+			f.Type = (*descriptor.GraphQLType)(utils.String(gqlType))
+			f.NonPrimitive = false
+			f.CustomScalar = !isBuiltinScalar(gqlType)
+		}
+
+		schema.checkExcludedReference(field, f)
+
 		// Sets wether the field is optional or not
-		f.IsRequired(field)
+		f.IsRequired(field, schema.typeAnalyzer.FieldPresence(field))
 
 		// Sets wether the field is required or not
 		f.IsRepeated(field)
 
-		if !keepCase(field.GetOptions()) {
-			f.Name = utils.String(utils.CamelCase(*field.Name))
-		}
+		// Repeated fields with gql_non_empty have at least one element
+		schema.nonEmptyConstraint(field, f)
+
+		f.Name = schema.reservedFieldName(field, schema.fieldName(field))
 		result = append(result, f)
 	}
 	return result
 }
 
+// Checks if a field is a member of a oneof. proto3 optional fields are the single member of a synthetic oneof,
+// which is not a oneof of the proto file, so they are not
+func inOneof(field *descriptorpb.FieldDescriptorProto) bool {
+	return field.OneofIndex != nil && !field.GetProto3Optional()
+}
+
+// Makes the fields of an output type non-null with nullable=none, unless the proto lets them be unset:
+// fields with implicit presence, like proto3 fields without the optional keyword, that are neither repeated nor in a oneof.
+// Inputs keep their nullability. fields are the fields generated from the message, in the same order
+func (schema *Schema) nonNullFields(message *descriptorpb.DescriptorProto, fields []*descriptor.Field) {
+	if schema.args.Nullable != NullableNone {
+		return
+	}
+	for i, field := range message.Field {
+		if field.OneofIndex != nil || field.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED ||
+			schema.typeAnalyzer.FieldPresence(field) != descriptorpb.FeatureSet_IMPLICIT {
+			continue
+		}
+		fields[i].Optional = false
+	}
+}
+
+// Describes the fields of a message that belong to a oneof, if oneof=describe is set.
+// fields are the fields generated from the message, in the same order.
+// The synthetic oneofs of proto3 optional fields are not described
+func (schema *Schema) describeOneofs(message *descriptorpb.DescriptorProto, fields []*descriptor.Field) {
+	if schema.args.Oneof != OneofDescribe {
+		return
+	}
+	for i, field := range message.Field {
+		if !inOneof(field) {
+			continue
+		}
+		oneof := message.OneofDecl[field.GetOneofIndex()].GetName()
+		fields[i].Description = joinDescriptions(fmt.Sprintf("Member of oneof %s, only one may be set", oneof), fields[i].Description)
+	}
+}
+
+// Returns the services of the proto file selected by the service option, all services by default
+func (schema *Schema) services() []*descriptorpb.ServiceDescriptorProto {
+	var services []*descriptorpb.ServiceDescriptorProto
+	for _, service := range schema.protoFile.Service {
+		if analyzer.IncludesService(schema.args.Services, service) {
+			services = append(services, service)
+		}
+	}
+	return services
+}
+
 func getMethodOptions(method *descriptorpb.MethodDescriptorProto) *options.MethodOptions {
 	opts := method.GetOptions()
 	if proto.HasExtension(opts, options.E_Method) {
@@ -139,17 +583,43 @@ func getMethodOptions(method *descriptorpb.MethodDescriptorProto) *options.Metho
 	return &options.MethodOptions{}
 }
 
-func getGqlOutputType(outputType string, mo *string, packageName *string) *string {
+func (schema *Schema) getGqlOutputType(outputType string, mo *string) *string {
 	if outputType != "" {
 		outputType = utils.UppercaseFirst(outputType)
+		if isPrimitive(&outputType) {
+			outputType = primitiveName(outputType)
+		} else {
+			outputType = schema.explicitTypeName(outputType)
+		}
 		return &outputType
 	}
-	outputType = strings.TrimPrefix(*mo, "."+*packageName+".")
+	// Responses mapped to a scalar, e.g. of an excluded package, return the scalar
+	if scalar, ok := schema.scalar(*mo); ok {
+		return &scalar
+	}
+	outputType = schema.typeName(*mo)
 	return &outputType
 }
 
-func isBoolean(t *string) bool {
-	return strings.Contains(*t, "Bool")
+// Returns the input type of a request message, or the scalar it is mapped to, e.g. of an excluded package
+func (schema *Schema) requestTypeName(fullName string) string {
+	if scalar, ok := schema.scalar(fullName); ok {
+		return scalar
+	}
+	return schema.inputTypeName(schema.typeName(fullName))
+}
+
+// Returns the payload of a query or mutation unwrapped to the field, and whether it is nullable.
+// Lists are never null, their items are non-null if the field is required
+func unwrappedPayload(field *descriptor.Field) (*string, bool) {
+	if field.IsList {
+		item := field.Type.String()
+		if !field.Optional {
+			item += string(syntax.Bang)
+		}
+		return utils.String(string(syntax.LBracket) + item + string(syntax.RBracket)), false
+	}
+	return utils.String(field.Type.String()), field.Optional
 }
 
 func isEmpty(t *string) bool {
@@ -157,32 +627,58 @@ func isEmpty(t *string) bool {
 	return *t == "Empty"
 }
 
-func isArray(t *options.GqlInput, length int) bool {
-	f := t.Type[:1]
-	l := t.Type[length-1:]
-	return f == "[" && l == "]"
+// Returns the item type of a list type such as "[User]", and whether the type is a list.
+// Unbalanced brackets, empty lists and nested lists are an error
+func isArray(t string) (string, bool, error) {
+	if !strings.ContainsAny(t, "[]") {
+		return t, false, nil
+	}
+	if strings.HasPrefix(t, "[") && strings.HasSuffix(t, "]") && len(t) > 2 {
+		if item := t[1 : len(t)-1]; !strings.ContainsAny(item, "[]") {
+			return item, true, nil
+		}
+	}
+	return "", false, fmt.Errorf("malformed type %q, expected a type name or a list such as [User]", t)
 }
 
-func parseType(input *options.GqlInput) {
+func parseType(input *options.GqlInput) error {
 	if input.Type == "" {
-		return
+		return nil
 	}
 
-	if isArray(input, len(input.Type)) {
-		input.Array = true
-		input.Type = utils.UppercaseFirst(input.Type[1 : len(input.Type)-1])
-	} else {
-		input.Type = utils.UppercaseFirst(input.Type)
+	item, array, err := isArray(input.Type)
+	if err != nil {
+		return err
 	}
+	input.Array = array
+	input.Type = utils.UppercaseFirst(item)
 
 	if isPrimitive(&input.Type) {
 		input.Primitive = true
-		if input.Type == "Bool" {
-			input.Type = "Boolean"
-		}
+		input.Type = primitiveName(input.Type)
 	} else if isEmpty(&input.Type) {
 		input.Empty = true
 	}
+	return nil
+}
+
+// Checks if a scalar is built into GraphQL and must not be declared
+func isBuiltinScalar(name string) bool {
+	switch name {
+	case "String", "Boolean", "Int", "Float", "ID":
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns the GraphQL name of a primitive type of the gql_input and gql_output options, which accept "Bool" for "Boolean".
+// Only the exact name is an alias, types named e.g. "BoolFlag" are messages
+func primitiveName(t string) string {
+	if t == "Bool" {
+		return string(descriptor.Boolean)
+	}
+	return t
 }
 
 func isPrimitive(t *string) bool {
@@ -194,16 +690,31 @@ func isPrimitive(t *string) bool {
 	}
 }
 
-func getGqlInputParam(input *options.GqlInput) string {
-	if param := input.GetParam(); param != "" {
-		return param
+// Returns the name of the input parameter of a method: the gql_input param, else the input_param_name option,
+// else "input". Names that are not valid GraphQL names are an error
+func (schema *Schema) getGqlInputParam(input *options.GqlInput, method *descriptorpb.MethodDescriptorProto) string {
+	param := input.GetParam()
+	if param == "" {
+		param = schema.args.InputParamName
+	}
+	if param == "" {
+		return string(syntax.Input)
 	}
-	return string(syntax.Input)
+	if !graphqlName.MatchString(param) {
+		schema.Error(fmt.Errorf("invalid input param name %q, expected a GraphQL name", param),
+			"error generating method", method.GetName())
+	}
+	return param
 }
 
-func getGqlInputType(input *options.GqlInput, mi *string, packageName *string) *options.GqlInput {
+func (schema *Schema) getGqlInputType(input *options.GqlInput, method *descriptorpb.MethodDescriptorProto) *options.GqlInput {
+	mi := utils.String(analyzer.RequestType(method))
+	if getMethodOptions(method).GqlArgsType != "" && schema.typeAnalyzer.Message(*mi) == nil {
+		schema.Error(fmt.Errorf("gql_args_type %s is not a message", getMethodOptions(method).GqlArgsType),
+			"error generating method", method.GetName())
+	}
 	// Extract the message type name without package prefix
-	messageType := strings.TrimPrefix(*mi, "."+*packageName+".")
+	messageType := strings.TrimPrefix(*mi, "."+*schema.packageName+".")
 
 	if input == nil {
 		// Check if the message type is Empty
@@ -214,62 +725,83 @@ func getGqlInputType(input *options.GqlInput, mi *string, packageName *string) *
 			}
 		} else {
 			input = &options.GqlInput{
-				Type: "I" + messageType,
+				Type: schema.requestTypeName(*mi),
 			}
 		}
 	} else if input.Type != "" {
-		parseType(input)
+		if err := parseType(input); err != nil {
+			schema.Error(err, "error generating method", method.GetName())
+		}
 		if !input.Primitive && !input.Empty {
-			input.Type = "I" + input.Type
+			input.Type = schema.inputTypeName(schema.explicitTypeName(input.Type))
+			if input.Array {
+				input.Type = "[" + input.Type + "]"
+			}
 		} else if input.Array {
 			input.Type = "[" + input.Type + "]"
-		} else {
-			input.Type = "I" + messageType
+		} else if input.Empty {
+			input.Type = schema.requestTypeName(*mi)
 		}
+		// Primitive types that aren't lists are used as set, e.g. String
 	} else {
 		// Check if the message type is Empty
 		if messageType == "Empty" {
 			input.Type = "Empty"
 			input.Empty = true
 		} else {
-			input.Type = "I" + messageType
+			input.Type = schema.requestTypeName(*mi)
 		}
 	}
 
-	input.Param = getGqlInputParam(input)
+	input.Param = schema.getGqlInputParam(input, method)
 	return input
 }
 
-// checkCompilerTarget checks if the CLI target matches the method's target.
-func checkCompilerTarget(compilerTarget *string, options *options.MethodOptions) bool {
-	// "all" or "*" acts as wildcard
-	if *compilerTarget == "all" || *compilerTarget == "*" {
-		return true
+// Returns the target selecting the generated RPCs, from the target, default_target and exclude_untargeted options
+func (schema *Schema) target() analyzer.Target {
+	return analyzer.Target{
+		Name:              schema.args.Target,
+		Default:           schema.args.DefaultTarget,
+		ExcludeUntargeted: schema.args.ExcludeUntargeted,
 	}
-	return *compilerTarget == options.Target
 }
 
-// skipMethod determines if a method should be skipped based on target matching.
-func skipMethod(compilerTarget *string, options *options.MethodOptions) bool {
-	// Skip if method is explicitly marked to skip
-	if options.Skip {
-		return true
-	}
-	// Don't skip if CLI target matches method target
-	if checkCompilerTarget(compilerTarget, options) {
-		return false
+// skipMethod determines if a method should be skipped, with the skip option or based on target matching.
+func skipMethod(target analyzer.Target, options *options.MethodOptions) bool {
+	return !analyzer.IncludesMethod(target, options)
+}
+
+// Kinds of the method option
+const (
+	kindQuery    = "query"
+	kindMutation = "mutation"
+)
+
+// Returns the kind of the method, matched case-insensitively and ignoring surrounding whitespace.
+// Methods without a kind or with an unknown kind are generated as queries, unknown kinds print a warning with strict.
+func (schema *Schema) methodKind(service *descriptorpb.ServiceDescriptorProto, method *descriptorpb.MethodDescriptorProto,
+	methodOptions *options.MethodOptions) string {
+	kind := strings.ToLower(strings.TrimSpace(methodOptions.Kind))
+	switch kind {
+	case kindQuery, kindMutation:
+		return kind
+	case "":
+		return kindQuery
 	}
-	// Method target "all" or "*" matches any CLI target
-	if options.Target == "all" || options.Target == "*" {
-		return false
+
+	if schema.args.Strict {
+		schema.Logger.Warn("unknown kind %q of method %s.%s, generating a query", methodOptions.Kind,
+			service.GetName(), method.GetName())
+	} else {
+		schema.Logger.Log("unknown kind %q of method %s.%s, generating a query", methodOptions.Kind,
+			service.GetName(), method.GetName())
 	}
-	// Skip: no match
-	return true
+	return kindQuery
 }
 
 // Constructs the Object types from message types and fills the schema.objectTypes
 func (schema *Schema) AddQueriesAndMutations() {
-	for _, service := range schema.protoFile.Service {
+	for _, service := range schema.services() {
 		for _, method := range service.Method {
 
 			// NewLogger().Log("target: %v", schema.args.Target)
@@ -279,25 +811,355 @@ func (schema *Schema) AddQueriesAndMutations() {
 
 			schema.Logger.Log("target: %s", schema.args.Target)
 
-			if skipMethod(&schema.args.Target, methodOptions) {
+			if skipMethod(schema.target(), methodOptions) {
 				continue
 			}
 
-			if methodOptions.Kind == "mutation" || methodOptions.Kind == "Mutation" {
+			if schema.methodKind(service, method, methodOptions) == kindMutation {
 				mutation := new(descriptor.Mutation)
 				mutation.Name = method.Name
-				mutation.Input = getGqlInputType(methodOptions.GqlInput, method.InputType, schema.packageName)
-				mutation.Payload = getGqlOutputType(methodOptions.GqlOutput, method.OutputType, schema.packageName)
+				schema.checkExcludedMethod(method, methodOptions)
+				mutation.Input = schema.getGqlInputType(methodOptions.GqlInput, method)
+				mutation.Arguments = schema.flattenArguments(mutation.Input, method)
+				mutation.Payload = schema.getGqlOutputType(methodOptions.GqlOutput, method.OutputType)
+				if field := schema.unwrappedField(method); field != nil {
+					mutation.Payload, mutation.NullablePayload = unwrappedPayload(field)
+				}
+				schema.wrapMutationPayload(service, mutation)
+				mutation.Directives, mutation.Description = schema.withExposedOptions(nil, "", method.GetOptions())
+				mutation.Description = joinDescriptions(mutation.Description, schema.operationSource(service, method),
+					schema.httpDescription(method))
 				schema.mutations = append(schema.mutations, mutation)
 			} else {
 				query := new(descriptor.Query)
 				query.Name = method.Name
-				query.Input = getGqlInputType(methodOptions.GqlInput, method.InputType, schema.packageName)
-				query.Payload = getGqlOutputType(methodOptions.GqlOutput, method.OutputType, schema.packageName)
+				schema.checkExcludedMethod(method, methodOptions)
+				query.Input = schema.getGqlInputType(methodOptions.GqlInput, method)
+				query.Arguments = schema.flattenArguments(query.Input, method)
+				query.Payload = schema.getGqlOutputType(methodOptions.GqlOutput, method.OutputType)
+				if field := schema.unwrappedField(method); field != nil {
+					query.Payload, query.NullablePayload = unwrappedPayload(field)
+				}
+				query.Directives, query.Description = schema.withExposedOptions(nil, "", method.GetOptions())
+				query.Description = joinDescriptions(query.Description, schema.operationSource(service, method),
+					schema.httpDescription(method))
 				schema.queries = append(schema.queries, query)
 			}
 		}
 	}
+	schema.addResourceQueries()
+}
+
+// Returns the description of an operation naming the gRPC method backing it and its streaming kind,
+// e.g. "Backed by UserService.GetUser (unary)", if annotate_operations is set
+func (schema *Schema) operationSource(service *descriptorpb.ServiceDescriptorProto, method *descriptorpb.MethodDescriptorProto) string {
+	if !schema.args.AnnotateOperations {
+		return ""
+	}
+	streaming := "unary"
+	switch {
+	case method.GetClientStreaming() && method.GetServerStreaming():
+		streaming = "bidirectional streaming"
+	case method.GetClientStreaming():
+		streaming = "client streaming"
+	case method.GetServerStreaming():
+		streaming = "server streaming"
+	}
+	return fmt.Sprintf("Backed by %s.%s (%s)", service.GetName(), method.GetName(), streaming)
+}
+
+// Checks if the request message of the method is flattened to arguments, with flatten_args.
+// Methods with an explicit gql_input type and Empty requests keep their input
+func (schema *Schema) isFlattened(method *descriptorpb.MethodDescriptorProto) bool {
+	if !schema.args.FlattenArgs || schema.typeAnalyzer.Message(analyzer.RequestType(method)) == nil {
+		return false
+	}
+	input := getMethodOptions(method).GqlInput
+	if input.GetType() != "" {
+		return false
+	}
+	return strings.TrimPrefix(analyzer.RequestType(method), "."+schema.protoFile.GetPackage()+".") != "Empty"
+}
+
+// Returns the arguments of a flattened method, from the fields of its request message.
+// Message fields are not flattened further, they become arguments of the input type of the message.
+// Requests without fields generate no arguments
+func (schema *Schema) flattenArguments(input *options.GqlInput, method *descriptorpb.MethodDescriptorProto) []*descriptor.Field {
+	if !schema.isFlattened(method) {
+		return nil
+	}
+	request := schema.visibleFields(schema.typeAnalyzer.Message(analyzer.RequestType(method)), false)
+	arguments := schema.generateFields(request.Field)
+	schema.checkFieldNameCollisions(request, arguments, "error generating method", method.GetName())
+	schema.mapInputFields(request, arguments)
+	schema.inputNullability(request, arguments)
+	if len(arguments) == 0 {
+		input.Empty = true
+	}
+	return arguments
+}
+
+// Checks if the message is only used as a flattened request, so no input type is generated for it.
+// It is still generated when a field of an input-reachable message references it
+func (schema *Schema) isFlattenedOnly(fullName string) bool {
+	flattened := false
+	for _, service := range schema.services() {
+		for _, method := range service.Method {
+			if analyzer.RequestType(method) != fullName || skipMethod(schema.target(), getMethodOptions(method)) {
+				continue
+			}
+			if !schema.isFlattened(method) {
+				return false
+			}
+			flattened = true
+		}
+	}
+	return flattened && !schema.referencedByField(fullName, schema.typeAnalyzer.InputReachableTypes())
+}
+
+// Returns the field a single-field output message is unwrapped to with unwrap_single_field, or nil.
+// Only messages with exactly one field outside a oneof are unwrapped, methods with an explicit gql_output are kept
+func (schema *Schema) unwrappedField(method *descriptorpb.MethodDescriptorProto) *descriptor.Field {
+	if !schema.args.UnwrapSingleField || getMethodOptions(method).GqlOutput != "" || schema.isScalar(method.GetOutputType()) {
+		return nil
+	}
+	message := schema.typeAnalyzer.Message(method.GetOutputType())
+	if message == nil {
+		return nil
+	}
+	message = schema.visibleFields(message, true)
+	if len(message.Field) != 1 || inOneof(message.Field[0]) {
+		return nil
+	}
+	return schema.generateFields(message.Field)[0]
+}
+
+// Checks if the message is only used as an unwrapped payload, so no type is generated for it.
+// It is still generated when a field of an output-reachable message references it
+func (schema *Schema) isUnwrappedOnly(fullName string) bool {
+	unwrapped := false
+	for _, service := range schema.services() {
+		for _, method := range service.Method {
+			if method.GetOutputType() != fullName || skipMethod(schema.target(), getMethodOptions(method)) {
+				continue
+			}
+			if schema.unwrappedField(method) == nil {
+				return false
+			}
+			unwrapped = true
+		}
+	}
+	return unwrapped && !schema.referencedByField(fullName, schema.typeAnalyzer.OutputReachableTypes())
+}
+
+// Checks if a field of one of the messages references the type
+func (schema *Schema) referencedByField(fullName string, messages []string) bool {
+	for _, name := range messages {
+		message := schema.typeAnalyzer.Message(name)
+		if message == nil {
+			continue
+		}
+		for _, field := range message.Field {
+			if field.GetTypeName() == fullName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the custom scalar a message is mapped to with the scalar option
+func (schema *Schema) scalar(fullName string) (string, bool) {
+	scalar, ok := schema.args.Scalars[strings.TrimPrefix(fullName, ".")]
+	return scalar, ok
+}
+
+// Returns the proto type of a field as written in proto files, e.g. "int64", "repeated bytes" or "google.protobuf.Timestamp"
+func protoType(field *descriptorpb.FieldDescriptorProto) string {
+	name := strings.ToLower(strings.TrimPrefix(field.GetType().String(), "TYPE_"))
+	switch field.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_TYPE_GROUP, descriptorpb.FieldDescriptorProto_TYPE_ENUM:
+		name = strings.TrimPrefix(field.GetTypeName(), ".")
+	}
+	if field.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED {
+		name = "repeated " + name
+	}
+	return name
+}
+
+// Fails generation if the field references a message or enum of a package excluded with exclude_package,
+// which is not generated. References mapped to scalars are kept
+func (schema *Schema) checkExcludedReference(field *descriptorpb.FieldDescriptorProto, f *descriptor.Field) {
+	isEnum := field.GetType() == descriptorpb.FieldDescriptorProto_TYPE_ENUM && !schema.isScalarEnum(field.GetTypeName())
+	if !f.NonPrimitive && !isEnum || !schema.typeAnalyzer.IsExcluded(field.GetTypeName()) {
+		return
+	}
+	schema.Error(fmt.Errorf("field %s references %s of an excluded package, map it to a scalar with the scalar option",
+		field.GetName(), strings.TrimPrefix(field.GetTypeName(), ".")), "error generating field")
+}
+
+// Fails generation if the request or response of a method is a message of an excluded package, as its type is not generated.
+// Types mapped to a scalar, explicit gql_input and gql_output types and Empty requests don't reference it
+func (schema *Schema) checkExcludedMethod(method *descriptorpb.MethodDescriptorProto, methodOptions *options.MethodOptions) {
+	check := func(fullName string) {
+		if !schema.typeAnalyzer.IsExcluded(fullName) || schema.isScalar(fullName) {
+			return
+		}
+		schema.Error(fmt.Errorf("method %s references %s of an excluded package, map it to a scalar with the scalar option",
+			method.GetName(), strings.TrimPrefix(fullName, ".")), "error generating method")
+	}
+	request := analyzer.RequestType(method)
+	if methodOptions.GqlInput.GetType() == "" && strings.TrimPrefix(request, "."+schema.protoFile.GetPackage()+".") != "Empty" {
+		check(request)
+	}
+	if methodOptions.GqlOutput == "" {
+		check(method.GetOutputType())
+	}
+}
+
+// Returns the custom scalar a string field is mapped to with scalar_by_name: the scalar of the first token
+// the proto field name ends with, or contains with scalar_name_match=substring, ignoring case
+func (schema *Schema) scalarByName(field *descriptorpb.FieldDescriptorProto) (string, bool) {
+	if field.GetType() != descriptorpb.FieldDescriptorProto_TYPE_STRING {
+		return "", false
+	}
+	name := strings.ToLower(field.GetName())
+	for _, named := range schema.args.ScalarsByName {
+		token := strings.ToLower(named.Token)
+		if schema.args.ScalarNameMatch == ScalarNameMatchSubstring && strings.Contains(name, token) ||
+			strings.HasSuffix(name, token) {
+			return named.Scalar, true
+		}
+	}
+	return "", false
+}
+
+// Checks if the message is mapped to a custom scalar, so no type or input is generated for it
+func (schema *Schema) isScalar(fullName string) bool {
+	_, ok := schema.scalar(fullName)
+	return ok
+}
+
+// Returns the name of the input type generated for a message, affixed according to the input_naming and affix options.
+// Inputs are prefixed with "I" by default, input_naming=suffix appends "Input" unless another affix is set.
+// The affixed name is bounded by max_name_length
+func (schema *Schema) inputTypeName(name string) string {
+	// The type_prefix comes before the affix, e.g. BillingIUser
+	prefix := schema.args.TypePrefix
+	name = strings.TrimPrefix(name, prefix)
+
+	switch schema.args.InputNaming {
+	case InputNamingSuffix:
+		if schema.args.Affix != "" {
+			return schema.boundedName(prefix + name + schema.args.Affix)
+		}
+		return schema.boundedName(prefix + name + "Input")
+	default:
+		if schema.args.Affix != "" {
+			return schema.boundedName(prefix + schema.args.Affix + name)
+		}
+		return schema.boundedName(prefix + "I" + name)
+	}
+}
+
+// Checks if the enum is generated as String, with the gql_as_scalar option or the enum_as_scalar
+// and enum_as_string_with_values plugin options
+func (schema *Schema) isScalarEnum(fullName string) bool {
+	if schema.args.EnumAsStringWithValues || schema.args.EnumsAsScalars[strings.TrimPrefix(fullName, ".")] {
+		return true
+	}
+	enum := schema.typeAnalyzer.Enum(fullName)
+	return enum != nil && gqlAsScalar(enum.GetOptions())
+}
+
+// Checks if a GraphQL enum is generated for the proto enum.
+// Enums generated as scalars stay reachable, but their definition is skipped, as for enums of excluded packages
+func (schema *Schema) isEnumGenerated(fullName string) bool {
+	return schema.typeAnalyzer.IsEnumReachable(fullName) && !schema.isScalarEnum(fullName) && !schema.typeAnalyzer.IsExcluded(fullName)
+}
+
+// Checks if an input type is generated for the message.
+// With all_inputs, every output-reachable message also gets an input type.
+// Messages mapped to custom scalars never get an input type.
+func (schema *Schema) isInputType(fullName string) bool {
+	if schema.isScalar(fullName) || schema.isJSONMapEntry(fullName) {
+		return false
+	}
+	if schema.typeAnalyzer.IsInputReachable(fullName) {
+		return true
+	}
+	return schema.args.AllInputs && schema.typeAnalyzer.IsOutputReachable(fullName)
+}
+
+// Checks if a field is a proto map field, a repeated field of a map entry message
+func (schema *Schema) isMapField(field *descriptorpb.FieldDescriptorProto) bool {
+	if field.GetType() != descriptorpb.FieldDescriptorProto_TYPE_MESSAGE ||
+		field.GetLabel() != descriptorpb.FieldDescriptorProto_LABEL_REPEATED {
+		return false
+	}
+	message := schema.typeAnalyzer.Message(field.GetTypeName())
+	return message != nil && message.GetOptions().GetMapEntry()
+}
+
+// Name of the scalar of string keyed input maps, with input_maps=json
+const jsonScalar = "JSON"
+
+// Checks if a message is the entry of a string keyed map, generated as a JSON scalar in inputs with input_maps=json
+func (schema *Schema) isJSONMapEntry(fullName string) bool {
+	if schema.args.InputMaps != InputMapsJSON {
+		return false
+	}
+	message := schema.typeAnalyzer.Message(fullName)
+	if message == nil || !message.GetOptions().GetMapEntry() || len(message.Field) == 0 {
+		return false
+	}
+	return message.Field[0].GetType() == descriptorpb.FieldDescriptorProto_TYPE_STRING
+}
+
+// Adjusts the input fields generated from a message for proto maps.
+// Map fields become JSON scalars if their entries are generated as such, map entries get non-null keys and values.
+// fields are the fields generated from the message, in the same order
+func (schema *Schema) mapInputFields(message *descriptorpb.DescriptorProto, fields []*descriptor.Field) {
+	if message.GetOptions().GetMapEntry() {
+		for _, field := range fields {
+			field.Optional = false
+		}
+		return
+	}
+	for i, field := range message.Field {
+		if schema.isMapField(field) && schema.isJSONMapEntry(field.GetTypeName()) {
+			fields[i].Type = (*descriptor.GraphQLType)(utils.String(jsonScalar))
+			fields[i].NonPrimitive = false
+			fields[i].CustomScalar = true
+			fields[i].IsList = false
+		}
+	}
+}
+
+// Applies the gql_input_optional and gql_input_required options, which override the nullability of fields in inputs
+// and flattened arguments only. fields are the fields generated from the message, in the same order
+func (schema *Schema) inputNullability(message *descriptorpb.DescriptorProto, fields []*descriptor.Field) {
+	for i, field := range message.Field {
+		optional := boolFieldOption(field.GetOptions(), options.E_GqlInputOptional)
+		required := boolFieldOption(field.GetOptions(), options.E_GqlInputRequired)
+		switch {
+		case optional && required:
+			schema.Error(fmt.Errorf("field %s sets both gql_input_optional and gql_input_required", field.GetName()),
+				"error generating input", message.GetName())
+		case optional:
+			fields[i].Optional = true
+		case required:
+			fields[i].Optional = false
+		}
+	}
+}
+
+// Returns the value of a bool field option, false if unset
+func boolFieldOption(fieldOptions *descriptorpb.FieldOptions, extension protoreflect.ExtensionType) bool {
+	if !proto.HasExtension(fieldOptions, extension) {
+		return false
+	}
+	return proto.GetExtension(fieldOptions, extension).(bool)
 }
 
 // Constructs the Input types from message types and fills the schema.inputTypes
@@ -307,71 +1169,157 @@ func (schema *Schema) makeInputTypes(messages []*descriptorpb.DescriptorProto) {
 }
 
 // makeInputTypesWithPrefix constructs input types with a name prefix for nested types
-// Only generates GraphQL `input` for input-reachable messages
+// Only generates GraphQL `input` for input-reachable messages.
+// Inputs are added in declaration order, a message before its nested messages
 func (schema *Schema) makeInputTypesWithPrefix(messages []*descriptorpb.DescriptorProto, prefix string) {
 	for _, message := range messages {
 		// Build the fully qualified name for reachability check
-		var fullName string
-		if prefix == "" {
-			if schema.packageName != nil && *schema.packageName != "" {
-				fullName = "." + *schema.packageName + "." + message.GetName()
-			} else {
-				fullName = "." + message.GetName()
-			}
-		} else {
-			fullName = prefix + "." + message.GetName()
+		fullName := schema.messageFullName(message, prefix)
+
+		// Check if this type is INPUT-reachable and has fields before processing.
+		// Requests flattened to arguments need no input type, their nested types are still generated
+		isInput := schema.isInputType(fullName)
+		if isInput && !schema.typeAnalyzer.IsOutputReachable(fullName) {
+			schema.checkFieldVisibility(message, fullName, false)
 		}
+		visible := schema.visibleFields(message, false)
+		hasFields := len(visible.Field) > 0 || schema.args.KeepEmptyMessages
+		if hasFields && isInput && !schema.isFlattenedOnly(fullName) {
+			// The input only has the fields without gql_output_only
+			message := visible
+			inputType := new(descriptor.InputType)
+			inputType.Name = utils.String(schema.typeName(fullName))
+			inputType.Source = schema.source(fullName)
+			inputType.Package = schema.typeAnalyzer.Package(fullName)
+			inputType.Directives = typeDirectives(message.GetOptions())
+			inputType.Directives, inputType.Description = schema.withExposedOptions(inputType.Directives, "", message.GetOptions())
 
-		// Check if the type is with zero fields, if so skip
-		if len(message.Field) == 0 {
-			continue
+			// Generate input fields
+			inputType.Fields = schema.generateFields(message.Field)
+			schema.checkFieldNameCollisions(message, inputType.Fields, "error generating input", schema.inputTypeName(*inputType.Name))
+			schema.mapInputFields(message, inputType.Fields)
+			schema.inputNullability(message, inputType.Fields)
+			if len(inputType.Fields) == 0 {
+				inputType.Fields = []*descriptor.Field{placeholderField()}
+			}
+			schema.describeOneofs(message, inputType.Fields)
+			schema.inputTypes = append(schema.inputTypes, inputType)
 		}
 
-		// Check if this type is INPUT-reachable before processing
-		// Only generate GraphQL `input` for input-reachable messages
-		if !schema.typeAnalyzer.IsInputReachable(fullName) {
-			continue
+		// Construct embedded input types (with updated prefix)
+		schema.makeInputTypesWithPrefix(message.NestedType, fullName)
+
+		// Construct embedded enums (only if reachable)
+		schema.makeNestedEnums(message, fullName)
+	}
+}
+
+// Fails generation when two messages generate types or inputs with the same name,
+// e.g. a message renamed with gql_type_name to the name of another message
+func (schema *Schema) checkTypeNameCollisions() {
+	sources := make(map[string]string)
+	// Names of the object types, as an input named like a type needs another affix
+	types := make(map[string]bool)
+	check := func(name, source string, input bool) {
+		if other, ok := sources[name]; ok {
+			err := fmt.Errorf("%s and %s both generate %s", other, source, name)
+			if input && types[name] {
+				err = fmt.Errorf("%w, as a type and an input, set affix or input_naming to rename the inputs", err)
+			}
+			schema.Error(err, "error generating type", name)
 		}
+		sources[name] = source
+	}
+	for _, objectType := range schema.objectTypes {
+		check(*objectType.Name, objectType.Source, false)
+		types[*objectType.Name] = true
+	}
+	for _, inputType := range schema.inputTypes {
+		check(schema.inputTypeName(*inputType.Name), inputType.Source, true)
+	}
+}
 
-		if len(message.Field) > 0 {
-			inputType := new(descriptor.InputType)
-			inputType.Name = message.Name
+// Fails generation when two fields of a message generate the same GraphQL field name,
+// e.g. user_id and userId once camel cased. fields are the fields generated from the message, in the same order
+func (schema *Schema) checkFieldNameCollisions(message *descriptorpb.DescriptorProto, fields []*descriptor.Field, msgs ...string) {
+	sources := make(map[string]string)
+	for i, field := range fields {
+		if other, ok := sources[*field.Name]; ok {
+			schema.Error(fmt.Errorf("fields %s and %s of %s both generate the field %s",
+				other, message.Field[i].GetName(), message.GetName(), *field.Name), msgs...)
+		}
+		sources[*field.Name] = message.Field[i].GetName()
+	}
+}
 
-			// Generate input fields
-			inputType.Fields = generateFields(message.Field)
+// Detects cycles of non-null, non-list message fields between input types.
+// Such inputs can't be constructed, so the field closing the cycle is made nullable,
+// or generation fails with recursive_inputs=error
+func (schema *Schema) checkRecursiveInputs() {
+	inputTypes := make(map[string]*descriptor.InputType)
+	for _, inputType := range schema.inputTypes {
+		inputTypes[*inputType.Name] = inputType
+	}
 
-			// Construct embedded input types (with updated prefix)
-			for _, nested := range message.NestedType {
-				schema.makeInputTypesWithPrefix([]*descriptorpb.DescriptorProto{nested}, fullName)
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int)
+	// Fields followed from the input type where the walk started
+	var path []string
+
+	var visit func(inputType *descriptor.InputType)
+	visit = func(inputType *descriptor.InputType) {
+		state[*inputType.Name] = visiting
+		for _, field := range inputType.Fields {
+			if !field.NonPrimitive || field.Optional || field.IsList {
+				continue
+			}
+			next, ok := inputTypes[field.Type.String()]
+			if !ok {
+				continue
 			}
 
-			// Construct embedded enums (only if reachable)
-			for _, enumType := range message.EnumType {
-				enumFullName := fullName + "." + enumType.GetName()
-				if schema.typeAnalyzer.IsEnumReachable(enumFullName) {
-					// Check if enum already exists to avoid duplicates
-					enumExists := false
-					for _, existingEnum := range schema.enums {
-						if *existingEnum.Name == *enumType.Name {
-							enumExists = true
-							break
-						}
-					}
-					if !enumExists {
-						enum := new(descriptor.Enumeration)
-						enum.Name = enumType.Name
-						for _, value := range enumType.Value {
-							enum.Values = append(enum.Values, enumValues(value))
-						}
-						schema.enums = append(schema.enums, enum)
-					}
-				}
+			path = append(path, schema.inputTypeName(*inputType.Name)+"."+*field.Name)
+			switch state[*next.Name] {
+			case visiting:
+				schema.breakInputCycle(field, path, schema.inputTypeName(*next.Name))
+			case 0:
+				visit(next)
 			}
-			schema.inputTypes = append(schema.inputTypes, inputType)
+			path = path[:len(path)-1]
+		}
+		state[*inputType.Name] = visited
+	}
+
+	for _, inputType := range schema.inputTypes {
+		if state[*inputType.Name] == 0 {
+			visit(inputType)
 		}
 	}
 }
 
+// Makes the field closing a cycle of non-null input fields nullable, or fails with recursive_inputs=error
+func (schema *Schema) breakInputCycle(field *descriptor.Field, path []string, start string) {
+	cycle := path
+	for i, element := range path {
+		if strings.HasPrefix(element, start+".") {
+			cycle = path[i:]
+			break
+		}
+	}
+	description := strings.Join(cycle, " -> ") + " -> " + start
+
+	if schema.args.RecursiveInputs == RecursiveInputsError {
+		schema.Error(fmt.Errorf("%s can't be constructed, non-null fields %s form a cycle", start, description),
+			"error generating input", start)
+		return
+	}
+	schema.Logger.Log("making %s nullable, non-null fields %s form a cycle", cycle[len(cycle)-1], description)
+	field.Optional = true
+}
+
 // Construct enums (only reachable ones)
 func (schema *Schema) Enums() {
 	for _, enumType := range schema.protoFile.EnumType {
@@ -384,26 +1332,53 @@ func (schema *Schema) Enums() {
 		}
 
 		// Check if this enum is reachable before processing
-		if !schema.typeAnalyzer.IsEnumReachable(fullName) {
+		if !schema.isEnumGenerated(fullName) {
 			continue
 		}
 
-		enum := new(descriptor.Enumeration)
-		enum.Name = enumType.Name
-		for _, value := range enumType.Value {
-			enum.Values = append(enum.Values, enumValues(value))
+		schema.enums = append(schema.enums, schema.makeEnum(enumType, fullName))
+	}
+}
+
+// Source code info paths of the syntax and package statements of a proto file
+var (
+	syntaxPath  = []int32{12}
+	packagePath = []int32{2}
+)
+
+// Returns the leading comment of the syntax statement, or of the package statement, of a proto file
+func fileDescription(protoFile *descriptorpb.FileDescriptorProto) string {
+	for _, path := range [][]int32{syntaxPath, packagePath} {
+		for _, location := range protoFile.GetSourceCodeInfo().GetLocation() {
+			if slices.Equal(location.Path, path) && location.GetLeadingComments() != "" {
+				return trimComment(location.GetLeadingComments())
+			}
 		}
-		schema.enums = append(schema.enums, enum)
 	}
+	return ""
+}
+
+// Trims a proto comment, removing the space after the comment marker of each line
+func trimComment(comment string) string {
+	lines := strings.Split(strings.TrimSpace(comment), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(strings.TrimRight(line, " \t"), " ")
+	}
+	return strings.Join(lines, "\n")
 }
 
 // Creates new Schema
 func CreateSchema(plugin *Plugin, protoFile *descriptorpb.FileDescriptorProto) *Schema {
 	schema := new(Schema)
 	schema.Builder = new(strings.Builder)
+	schema.plugin = plugin
 	schema.protoFile = protoFile
 	schema.args = plugin.args
 	schema.Logger = plugin.Logger
+	schema.fieldCase = plugin.args.FieldCaseTransform()
+	schema.typeCase = plugin.args.TypeCaseTransform()
+	schema.preamble = plugin.preamble
+	schema.description = fileDescription(protoFile)
 
 	// get package name
 	schema.packageName = protoFile.Package
@@ -413,9 +1388,11 @@ func CreateSchema(plugin *Plugin, protoFile *descriptorpb.FileDescriptorProto) *
 	// Create type analyzer for dependency-based filtering
 	// Pass all proto files for cross-file type resolution
 	schema.typeAnalyzer = analyzer.NewTypeAnalyzer(plugin.Request.ProtoFile)
+	schema.typeAnalyzer.ExcludePackages(schema.args.ExcludePackages)
+	schema.typeAnalyzer.JSONInputMaps(schema.args.InputMaps == InputMapsJSON)
 
 	// Analyze RPC dependencies based on target
-	schema.typeAnalyzer.AnalyzeRPCDependencies(protoFile.Service, schema.args.Target)
+	schema.typeAnalyzer.AnalyzeRPCDependencies(protoFile.Service, schema.target(), schema.args.Services)
 
 	// Construct Object types (only output-reachable types)
 	schema.makeObjectTypes(protoFile.MessageType)
@@ -423,12 +1400,71 @@ func CreateSchema(plugin *Plugin, protoFile *descriptorpb.FileDescriptorProto) *
 	// Construct Input types (only input-reachable types )
 	schema.makeInputTypes(protoFile.MessageType)
 
+	schema.checkTypeNameCollisions()
+
+	schema.checkRecursiveInputs()
+
 	schema.Enums()
 
+	schema.warnUnusedTypes()
+
 	schema.AddQueriesAndMutations()
+
+	schema.applyDocs()
+
+	schema.collectScalars()
+	schema.collectFederationDirectives()
+	schema.collectValuesDirective()
+	schema.collectConstraintDirective()
 	return schema
 }
 
+// Collects the custom scalars referenced by the fields of the schema's types
+func (schema *Schema) collectScalars() {
+	seen := make(map[string]bool)
+	addScalar := func(scalar string) {
+		if !seen[scalar] {
+			seen[scalar] = true
+			schema.scalars = append(schema.scalars, scalar)
+		}
+	}
+	add := func(fields []*descriptor.Field) {
+		for _, field := range fields {
+			if field.CustomScalar {
+				addScalar(field.Type.String())
+			}
+		}
+	}
+	for _, objectType := range schema.objectTypes {
+		add(objectType.Fields)
+	}
+	for _, inputType := range schema.inputTypes {
+		add(inputType.Fields)
+	}
+	// Flattened arguments, whose requests have no input type
+	for _, query := range schema.queries {
+		add(query.Arguments)
+	}
+	for _, mutation := range schema.mutations {
+		add(mutation.Arguments)
+	}
+	// Requests and responses mapped to scalars
+	for _, service := range schema.services() {
+		for _, method := range service.Method {
+			methodOptions := getMethodOptions(method)
+			if skipMethod(schema.target(), methodOptions) {
+				continue
+			}
+			if scalar, ok := schema.scalar(analyzer.RequestType(method)); ok && methodOptions.GqlInput.GetType() == "" {
+				addScalar(scalar)
+			}
+			if scalar, ok := schema.scalar(method.GetOutputType()); ok && methodOptions.GqlOutput == "" {
+				addScalar(scalar)
+			}
+		}
+	}
+}
+
 // Puts a new line in the generated content
 func (schema *Schema) NewLine(length ...int) {
 	if len(length) == 0 {
@@ -451,6 +1487,19 @@ func (schema *Schema) Space(length ...int) {
 	}
 }
 
+// Indents the generated content by one level
+func (schema *Schema) Indent() {
+	schema.Write(schema.indentation())
+}
+
+// Returns the indentation of fields, enum values and operations, two spaces unless the indent option is set
+func (schema *Schema) indentation() string {
+	if schema.args.Indent == "" {
+		return "  "
+	}
+	return schema.args.Indent
+}
+
 // Puts a graphql comment in the generated content
 func (schema *Schema) Comment(s string) {
 	schema.Write("#")
@@ -467,15 +1516,40 @@ func (schema *Schema) Write(s string) {
 }
 
 // Creates a file name based on the given proto file name
+// The strip_path_prefix and flatten_names options shorten the directories of the proto file name
 func (schema *Schema) FileName(filename *string) {
-	ext := filepath.Ext(*filename)
-	schema.fileName = utils.String(strings.TrimSuffix(*filename, ext) + ".graphql")
+	name := *filename
+	if schema.args.StripPathPrefix != "" {
+		name = stripPathPrefix(name, schema.args.StripPathPrefix)
+	}
+	if schema.args.FlattenNames {
+		name = path.Base(name)
+	}
+	ext := filepath.Ext(name)
+	schema.fileName = utils.String(strings.TrimSuffix(name, ext) + schema.args.FileExtension())
 }
 
-// Prints a message
+// Strips a directory prefix from a path, only by whole path components, so "proto" doesn't strip "protos/users.proto"
+func stripPathPrefix(name, prefix string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if name == prefix {
+		return ""
+	}
+	if rest, ok := strings.CutPrefix(name, prefix+"/"); ok {
+		return rest
+	}
+	return name
+}
+
+// Prints an error, and exits.
+func (schema *Schema) Error(err error, msgs ...string) {
+	schema.plugin.Error(err, msgs...)
+}
+
+// Prints a message to stderr
 func (schema *Schema) Print(msg ...string) {
 	s := strings.Join(msg, " ")
-	log.Print(s)
+	schema.Logger.Info("%s", s)
 }
 
 // Write the header content
@@ -485,3 +1559,41 @@ func (schema *Schema) WriteHeader() {
 	schema.NewLine()
 	schema.NewLine()
 }
+
+// Write the documentation of the proto file, if any, as the description of an explicit schema definition
+// naming the Query and Mutation roots. Schemas extending the roots of an earlier output file can't define
+// the schema again, and GraphQL has no descriptions on extensions, so their documentation is written as comments
+func (schema *Schema) WriteDescription() {
+	if schema.description == "" {
+		return
+	}
+	if !schema.extendRoots {
+		schema.writeDescriptionString(schema.description, false)
+		schema.Write("schema {\n")
+		schema.Indent()
+		schema.Write("query: Query\n")
+		schema.Indent()
+		schema.Write("mutation: Mutation\n")
+		schema.Write(string(syntax.RBrace))
+		schema.NewLine(2)
+		return
+	}
+	for _, line := range strings.Split(schema.description, "\n") {
+		if line == "" {
+			schema.Write("#\n")
+			continue
+		}
+		schema.Comment(line)
+		schema.NewLine()
+	}
+	schema.NewLine()
+}
+
+// Write the prepended handwritten SDL, if any
+func (schema *Schema) WritePreamble() {
+	if schema.preamble == "" {
+		return
+	}
+	schema.Write(schema.preamble)
+	schema.NewLine(2)
+}
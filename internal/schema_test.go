@@ -0,0 +1,2352 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fverse/protoc-graphql/options"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestAnnotateSource(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User",
+				scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				scalarField("age", 3, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+			),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+
+	plain := generate(t, "", file)["users.graphql"]
+	if strings.Contains(plain, "# from") || strings.Contains(plain, "# proto field") {
+		t.Errorf("annotations should only be generated with annotate_source, got:\n%s", plain)
+	}
+
+	annotated := generate(t, "annotate_source", file)["users.graphql"]
+	for _, want := range []string{
+		"# from users.proto:User\ntype User {\n",
+		"  name: String # proto field 1\n",
+		"  age: Int # proto field 3\n",
+		"# from users.proto:GetUserRequest\ninput IGetUserRequest {\n",
+		"  id: String # proto field 1\n",
+	} {
+		if !strings.Contains(annotated, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, annotated)
+		}
+	}
+}
+
+func TestAnnotateOperations(t *testing.T) {
+	watch := testMethod("WatchUser", ".users.GetUserRequest", ".users.User", nil)
+	watch.ServerStreaming = proto.Bool(true)
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+		watch,
+		testMethod("DeleteUser", ".users.GetUserRequest", ".users.User", &options.MethodOptions{Kind: "mutation"}),
+	)
+
+	if out := generate(t, "", file)["users.graphql"]; strings.Contains(out, "Backed by") {
+		t.Errorf("operations should only be annotated with annotate_operations, got:\n%s", out)
+	}
+
+	out := generate(t, "annotate_operations", file)["users.graphql"]
+	for _, want := range []string{
+		"  \"Backed by Service.GetUser (unary)\"\n  getUser(input: IGetUserRequest!): User!\n",
+		"  \"Backed by Service.WatchUser (server streaming)\"\n  watchUser(input: IGetUserRequest!): User!\n",
+		"  \"Backed by Service.DeleteUser (unary)\"\n  deleteUser(input: IGetUserRequest!): User!\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestErrorOnEmptyType(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("GetUserResponse", messageField("user", 1, ".users.User")),
+			testMessage("User"),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.GetUserResponse", nil),
+		testMethod("GetRawUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+
+	out := generate(t, "", file)["users.graphql"]
+	if strings.Contains(out, "type User {") {
+		t.Errorf("empty User type should be skipped by default, got:\n%s", out)
+	}
+
+	stderr := generateError(t, "error_on_empty_type", file)
+	want := "error generating type User: User has no fields but is referenced by Service.GetRawUser, users.GetUserResponse.user"
+	if !strings.Contains(stderr, want) {
+		t.Errorf("stderr should contain %q, got %q", want, stderr)
+	}
+}
+
+func TestKeepEmptyMessages(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("BanUserRequest"),
+			testMessage("Banned"),
+		},
+		testMethod("BanUser", ".users.BanUserRequest", ".users.Banned", &options.MethodOptions{Kind: "mutation"}),
+	)
+
+	out := generate(t, "keep_empty_messages,annotate_source", file)["users.graphql"]
+	for _, want := range []string{
+		"type Banned {\n  _: Boolean\n}",
+		"input IBanUserRequest {\n  _: Boolean\n}",
+		"banUser(input: IBanUserRequest!): Banned!",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+
+	// An empty type that is kept is not an error
+	generate(t, "keep_empty_messages,error_on_empty_type", file)
+
+	if out := generate(t, "", file)["users.graphql"]; strings.Contains(out, "_: Boolean") {
+		t.Errorf("empty messages should be skipped by default, got:\n%s", out)
+	}
+}
+
+func TestEnumAliases(t *testing.T) {
+	file := testFile("orders.proto", "orders",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetOrderRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Order", enumField("status", 1, ".orders.Status")),
+		},
+		testMethod("GetOrder", ".orders.GetOrderRequest", ".orders.Order", nil),
+	)
+	file.EnumType = []*descriptorpb.EnumDescriptorProto{
+		testEnum("Status", "STATUS_UNKNOWN", 0, "STATUS_STARTED", 1, "STATUS_RUNNING", 1, "STATUS_DONE", 2),
+	}
+	file.EnumType[0].Options = &descriptorpb.EnumOptions{AllowAlias: proto.Bool(true)}
+
+	tests := []struct {
+		parameter string
+		want      string
+	}{
+		{"", "enum Status {\n  STATUS_UNKNOWN\n  STATUS_STARTED\n  STATUS_RUNNING\n  STATUS_DONE\n}"},
+		{"enum_aliases=keep", "enum Status {\n  STATUS_UNKNOWN\n  STATUS_STARTED\n  STATUS_RUNNING\n  STATUS_DONE\n}"},
+		{"enum_aliases=deprecate", "enum Status {\n  STATUS_UNKNOWN\n  STATUS_STARTED\n" +
+			"  STATUS_RUNNING @deprecated(reason: \"Alias of STATUS_STARTED\")\n  STATUS_DONE\n}"},
+	}
+	for _, tt := range tests {
+		out := generate(t, tt.parameter, file)["orders.graphql"]
+		if !strings.Contains(out, tt.want) {
+			t.Errorf("%q: output should contain\n%s\ngot:\n%s", tt.parameter, tt.want, out)
+		}
+		if strings.Count(out, "STATUS_STARTED\n") != 1 {
+			t.Errorf("%q: enum values should not be duplicated, got:\n%s", tt.parameter, out)
+		}
+	}
+
+	// Invalid values are reported and aliases are kept, the default
+	var out string
+	_, stderr := captureOutput(t, func() { out = generate(t, "enum_aliases=deprecated", file)["orders.graphql"] })
+	if want := `invalid enum_aliases "deprecated", expected "keep" or "deprecate"`; !strings.Contains(stderr, want) {
+		t.Errorf("stderr should contain %q, got: %s", want, stderr)
+	}
+	if strings.Contains(out, "@deprecated") {
+		t.Errorf("aliases should be kept, got:\n%s", out)
+	}
+}
+
+func TestEnumValueName(t *testing.T) {
+	status := func(names ...string) *descriptorpb.EnumDescriptorProto {
+		enum := testEnum("Status", "STATUS_UNKNOWN", 0, "STATUS_ACTIVE", 1, "STATUS_ENABLED", 1, "STATUS_BANNED", 2)
+		enum.Options = &descriptorpb.EnumOptions{AllowAlias: proto.Bool(true)}
+		for i, name := range names {
+			if name != "" {
+				enum.Value[i].Options = &descriptorpb.EnumValueOptions{}
+				proto.SetExtension(enum.Value[i].Options, options.E_GqlValueName, name)
+			}
+		}
+		return enum
+	}
+	file := func(enum *descriptorpb.EnumDescriptorProto) *descriptorpb.FileDescriptorProto {
+		file := testFile("orders.proto", "orders",
+			[]*descriptorpb.DescriptorProto{
+				testMessage("GetOrderRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+				testMessage("Order", enumField("status", 1, ".orders.Status")),
+			},
+			testMethod("GetOrder", ".orders.GetOrderRequest", ".orders.Order", nil),
+		)
+		file.EnumType = []*descriptorpb.EnumDescriptorProto{enum}
+		return file
+	}
+
+	// Only the renamed value loses its prefix, and its aliases name it
+	out := generate(t, "enum_aliases=deprecate", file(status("", "ACTIVE")))["orders.graphql"]
+	want := "enum Status {\n  STATUS_UNKNOWN\n  ACTIVE\n" +
+		"  STATUS_ENABLED @deprecated(reason: \"Alias of ACTIVE\")\n  STATUS_BANNED\n}"
+	if !strings.Contains(out, want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+	}
+
+	// Enums generated as String list the renamed values
+	out = generate(t, "enum_as_string_with_values", file(status("", "ACTIVE")))["orders.graphql"]
+	want = `status: String @values(values: ["STATUS_UNKNOWN", "ACTIVE", "STATUS_ENABLED", "STATUS_BANNED"])`
+	if !strings.Contains(out, want) {
+		t.Errorf("output should contain %q, got:\n%s", want, out)
+	}
+
+	tests := []struct {
+		names []string
+		err   string
+	}{
+		{[]string{"", "STATUS_BANNED"}, "error generating enum Status: values STATUS_ACTIVE and STATUS_BANNED both generate the value STATUS_BANNED"},
+		{[]string{"", "ACTIVE", "ACTIVE"}, "error generating enum Status: values STATUS_ACTIVE and STATUS_ENABLED both generate the value ACTIVE"},
+		{[]string{"", "active-now"}, `error generating enum Status: gql_value_name "active-now" of STATUS_ACTIVE is not a valid GraphQL name`},
+	}
+	for _, tt := range tests {
+		if stderr := generateError(t, "", file(status(tt.names...))); !strings.Contains(stderr, tt.err) {
+			t.Errorf("%v: error should contain %q, got: %s", tt.names, tt.err, stderr)
+		}
+	}
+}
+
+func TestEnumValueOrder(t *testing.T) {
+	file := testFile("orders.proto", "orders",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetOrderRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Order", enumField("status", 1, ".orders.Status")),
+		},
+		testMethod("GetOrder", ".orders.GetOrderRequest", ".orders.Order", nil),
+	)
+	file.EnumType = []*descriptorpb.EnumDescriptorProto{
+		testEnum("Status", "DONE", 7, "NONE", 0, "RUNNING", 2, "BACKLOG", -1, "ARCHIVED", 10),
+	}
+
+	for _, tt := range []struct {
+		parameter string
+		want      string
+	}{
+		{"", "enum Status {\n  DONE\n  NONE\n  RUNNING\n  BACKLOG\n  ARCHIVED\n}"},
+		{"enum_value_order=proto", "enum Status {\n  DONE\n  NONE\n  RUNNING\n  BACKLOG\n  ARCHIVED\n}"},
+		{"enum_value_order=number", "enum Status {\n  NONE\n  BACKLOG\n  RUNNING\n  DONE\n  ARCHIVED\n}"},
+		{"enum_value_order=name", "enum Status {\n  ARCHIVED\n  BACKLOG\n  DONE\n  NONE\n  RUNNING\n}"},
+	} {
+		out := generate(t, tt.parameter, file)["orders.graphql"]
+		if !strings.Contains(out, tt.want) {
+			t.Errorf("%q: output should contain\n%s\ngot:\n%s", tt.parameter, tt.want, out)
+		}
+	}
+
+	_, stderr := captureOutput(t, func() { generate(t, "enum_value_order=value", file) })
+	if !strings.Contains(stderr, `invalid enum_value_order "value"`) {
+		t.Errorf("invalid orders should be reported, got: %s", stderr)
+	}
+}
+
+func TestUseJsonName(t *testing.T) {
+	custom := scalarField("user_name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	custom.JsonName = proto.String("login")
+
+	keptCase := scalarField("API_KEY", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	keptCase.JsonName = proto.String("apiKeyJson")
+	keptCase.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(keptCase.Options, options.E_KeepCase, true)
+
+	renamed := scalarField("display_name", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	renamed.JsonName = proto.String("displayNameJson")
+	renamed.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(renamed.Options, options.E_GqlName, "title")
+
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", custom, keptCase, renamed,
+				scalarField("last_seen", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+
+	tests := []struct {
+		parameter string
+		want      string
+	}{
+		{"", "type User {\n  userName: String\n  API_KEY: String\n  title: String\n  lastSeen: String\n}"},
+		{"use_json_name", "type User {\n  login: String\n  API_KEY: String\n  title: String\n  lastSeen: String\n}"},
+	}
+	for _, tt := range tests {
+		out := generate(t, tt.parameter, file)["users.graphql"]
+		if !strings.Contains(out, tt.want) {
+			t.Errorf("%q: output should contain\n%s\ngot:\n%s", tt.parameter, tt.want, out)
+		}
+	}
+}
+
+func TestMethodKind(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("UserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("CreateUser", ".users.UserRequest", ".users.User", &options.MethodOptions{Kind: "MUTATION"}),
+		testMethod("DeleteUser", ".users.UserRequest", ".users.User", &options.MethodOptions{Kind: " mutation"}),
+		testMethod("GetUser", ".users.UserRequest", ".users.User", &options.MethodOptions{Kind: "Query "}),
+		testMethod("WatchUser", ".users.UserRequest", ".users.User", &options.MethodOptions{Kind: "stream"}),
+	)
+
+	var out map[string]string
+	_, stderr := captureOutput(t, func() {
+		out = generate(t, "", file)
+	})
+	schema := out["users.graphql"]
+
+	for _, want := range []string{
+		"type Query {\n  getUser(input: IUserRequest!): User!\n  watchUser(input: IUserRequest!): User!\n}",
+		"type Mutation {\n  createUser(input: IUserRequest!): User!\n  deleteUser(input: IUserRequest!): User!\n}",
+	} {
+		if !strings.Contains(schema, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, schema)
+		}
+	}
+	if strings.Contains(stderr, "warning") {
+		t.Errorf("unknown kinds should only warn with strict, got %q", stderr)
+	}
+
+	_, stderr = captureOutput(t, func() {
+		generate(t, "strict", file)
+	})
+	want := NAME + ": warning: unknown kind \"stream\" of method Service.WatchUser, generating a query\n"
+	if stderr != want {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+}
+
+func TestFieldCase(t *testing.T) {
+	keptCase := scalarField("API_KEY", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	keptCase.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(keptCase.Options, options.E_KeepCase, true)
+
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", scalarField("last_seen_at", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING), keptCase),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+
+	tests := []struct {
+		parameter string
+		want      string
+	}{
+		{"", "lastSeenAt"},
+		{"field_case=camel", "lastSeenAt"},
+		{"field_case=snake", "last_seen_at"},
+		{"field_case=pascal", "LastSeenAt"},
+		{"field_case=original", "last_seen_at"},
+		{"keep_case", "last_seen_at"},
+		{"keep_case,field_case=pascal", "LastSeenAt"},
+	}
+	for _, tt := range tests {
+		out := generate(t, tt.parameter, file)["users.graphql"]
+		want := "type User {\n  " + tt.want + ": String\n  API_KEY: String\n}"
+		if !strings.Contains(out, want) {
+			t.Errorf("%q: output should contain\n%s\ngot:\n%s", tt.parameter, want, out)
+		}
+	}
+}
+
+func TestTypeCase(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("get_user_request",
+				scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				messageField("filter", 2, ".users._user_filter"),
+			),
+			testMessage("_user_filter", enumField("status", 1, ".users.user_status")),
+			testMessage("_user_profile",
+				messageField("address", 1, ".users.postal_address"),
+				enumField("status", 2, ".users.user_status"),
+			),
+			testMessage("postal_address", scalarField("city", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("GetUser", ".users.get_user_request", ".users._user_profile", nil),
+	)
+	file.EnumType = []*descriptorpb.EnumDescriptorProto{testEnum("user_status", "ACTIVE", 0)}
+
+	out := generate(t, "type_case=pascal", file)["users.graphql"]
+	for _, want := range []string{
+		"type UserProfile {\n  address: PostalAddress\n  status: UserStatus\n}",
+		"type PostalAddress {",
+		"input IGetUserRequest {\n  id: String\n  filter: IUserFilter\n}",
+		"input IUserFilter {\n  status: UserStatus\n}",
+		"enum UserStatus {",
+		"getUser(input: IGetUserRequest!): UserProfile!",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+
+	out = generate(t, "", file)["users.graphql"]
+	for _, want := range []string{"type _user_profile {", "status: user_status", "getUser(input: Iget_user_request!): _user_profile!"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("type names should be kept by default, output should contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestAcronyms(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("get_user_id_request", scalarField("user_id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User",
+				scalarField("http_url", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				scalarField("url_path", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				scalarField("api_user_id", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			),
+		},
+		testMethod("GetUser", ".users.get_user_id_request", ".users.User", nil),
+	)
+
+	tests := []struct {
+		parameter string
+		want      []string
+	}{
+		{"", []string{"type User {\n  httpUrl: String\n  urlPath: String\n  apiUserId: String\n}"}},
+		{"acronyms=ID,acronyms=URL", []string{
+			"type User {\n  httpURL: String\n  urlPath: String\n  apiUserID: String\n}",
+			"input Iget_user_id_request {\n  userID: String\n}",
+		}},
+		{"acronyms=ID,acronyms=URL,acronyms=API,field_case=pascal,type_case=pascal", []string{
+			"type User {\n  HttpURL: String\n  URLPath: String\n  APIUserID: String\n}",
+			"input IGetUserIDRequest {\n  UserID: String\n}",
+			"getUser(input: IGetUserIDRequest!): User!",
+		}},
+		// Snake casing ignores acronyms
+		{"acronyms=ID,field_case=snake", []string{"type User {\n  http_url: String\n  url_path: String\n  api_user_id: String\n}"}},
+	}
+	for _, tt := range tests {
+		out := generate(t, tt.parameter, file)["users.graphql"]
+		for _, want := range tt.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("%q: output should contain\n%s\ngot:\n%s", tt.parameter, want, out)
+			}
+		}
+	}
+}
+
+func TestMaxNameLength(t *testing.T) {
+	// Order.Line.Item.Variant.ShippingPackageDimensionsSpecification, each message referencing the next
+	dimensions := testMessage("ShippingPackageDimensionsSpecification", scalarField("width", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32))
+	variant := testMessage("Variant", messageField("dimensions", 1, ".shop.Order.Line.Item.Variant.ShippingPackageDimensionsSpecification"))
+	variant.NestedType = []*descriptorpb.DescriptorProto{dimensions}
+	item := testMessage("Item", messageField("variant", 1, ".shop.Order.Line.Item.Variant"))
+	item.NestedType = []*descriptorpb.DescriptorProto{variant}
+	line := testMessage("Line", messageField("item", 1, ".shop.Order.Line.Item"))
+	line.NestedType = []*descriptorpb.DescriptorProto{item}
+	order := testMessage("Order", messageField("line", 1, ".shop.Order.Line"))
+	order.NestedType = []*descriptorpb.DescriptorProto{line}
+
+	file := testFile("shop.proto", "shop",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetOrderRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			order,
+		},
+		testMethod("GetOrder", ".shop.GetOrderRequest", ".shop.Order", nil),
+		testMethod("UpdateOrder", ".shop.Order", ".shop.Order", &options.MethodOptions{Kind: "mutation"}),
+	)
+
+	out := generate(t, "max_name_length=24", file)["shop.graphql"]
+	for _, want := range []string{
+		"type Variant {\n  dimensions: ShippingPackage_9c0ff803\n}",
+		"type ShippingPackage_9c0ff803 {",
+		"input IVariant {\n  dimensions: IShippingPackag_7a3d1a5b\n}",
+		"input IShippingPackag_7a3d1a5b {",
+		// Names within the limit are kept
+		"getOrder(input: IGetOrderRequest!): Order!",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+	for _, match := range regexp.MustCompile(`(?m)^(?:type|input) (\w+)`).FindAllStringSubmatch(out, -1) {
+		if len(match[1]) > 24 {
+			t.Errorf("%s is longer than max_name_length", match[1])
+		}
+	}
+
+	// Payload types of mutation_payloads are bounded too
+	file.Service[0].Method = append(file.Service[0].Method,
+		testMethod("UpdateOrderShippingDimensions", ".shop.Order", ".shop.Order", &options.MethodOptions{Kind: "mutation"}))
+	out = generate(t, "max_name_length=24,mutation_payloads", file)["shop.graphql"]
+	for _, want := range []string{
+		"type UpdateOrderPayload {",
+		"type UpdateOrderShip_dd572951 {",
+		"updateOrderShippingDimensions(input: IOrder!): UpdateOrderShip_dd572951!",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("mutation_payloads: output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+
+	if out := generate(t, "", file)["shop.graphql"]; !strings.Contains(out, "type ShippingPackageDimensionsSpecification {") {
+		t.Errorf("names should not be bounded by default, got:\n%s", out)
+	}
+}
+
+func TestEnumAsScalar(t *testing.T) {
+	country := testEnum("Country", "COUNTRY_UNSPECIFIED", 0, "COUNTRY_FR", 1)
+	country.Options = &descriptorpb.EnumOptions{}
+	proto.SetExtension(country.Options, options.E_GqlAsScalar, true)
+
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", enumField("country", 1, ".users.Country")),
+			testMessage("User",
+				enumField("country", 1, ".users.Country"),
+				enumField("status", 2, ".users.Status"),
+			),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+	file.EnumType = []*descriptorpb.EnumDescriptorProto{country, testEnum("Status", "STATUS_UNKNOWN", 0)}
+
+	tests := []struct {
+		parameter string
+		enums     []string
+		scalars   []string
+	}{
+		{"", []string{"Status"}, []string{"Country"}},
+		{"enum_as_scalar=users.Status", nil, []string{"Country", "Status"}},
+	}
+	for _, tt := range tests {
+		out := generate(t, tt.parameter, file)["users.graphql"]
+		for _, enum := range tt.enums {
+			if !strings.Contains(out, "enum "+enum+" {") || !strings.Contains(out, ": "+enum+"\n") {
+				t.Errorf("%q: %s should be generated as an enum, got:\n%s", tt.parameter, enum, out)
+			}
+		}
+		for _, enum := range tt.scalars {
+			if strings.Contains(out, "enum "+enum) || strings.Contains(out, ": "+enum+"\n") {
+				t.Errorf("%q: %s should not be generated as an enum, got:\n%s", tt.parameter, enum, out)
+			}
+		}
+		if !strings.Contains(out, "input IGetUserRequest {\n  country: String\n}") ||
+			!strings.Contains(out, "type User {\n  country: String\n") {
+			t.Errorf("%q: Country fields should be String, got:\n%s", tt.parameter, out)
+		}
+	}
+}
+
+func TestEnumAsStringWithValues(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", enumField("status", 1, ".users.Status")),
+			testMessage("User", enumField("status", 1, ".users.Status")),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+	file.EnumType = []*descriptorpb.EnumDescriptorProto{testEnum("Status", "STATUS_UNSPECIFIED", 0, "BANNED", 2, "ACTIVE", 1)}
+
+	out := generate(t, "enum_as_string_with_values", file)["users.graphql"]
+	for _, want := range []string{
+		"directive @values(values: [String!]!) on FIELD_DEFINITION | INPUT_FIELD_DEFINITION | ARGUMENT_DEFINITION\n\n",
+		"type User {\n  status: String @values(values: [\"STATUS_UNSPECIFIED\", \"BANNED\", \"ACTIVE\"])\n}",
+		"input IGetUserRequest {\n  status: String @values(values: [\"STATUS_UNSPECIFIED\", \"BANNED\", \"ACTIVE\"])\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "enum Status") {
+		t.Errorf("Status should not be generated as an enum, got:\n%s", out)
+	}
+
+	// The values follow enum_value_order, and flattened arguments list them too
+	out = generate(t, "enum_as_string_with_values,enum_value_order=number,flatten_args", file)["users.graphql"]
+	if want := `getUser(status: String @values(values: ["STATUS_UNSPECIFIED", "ACTIVE", "BANNED"])): User!`; !strings.Contains(out, want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+	}
+
+	// Without the option, no directive is declared
+	if out := generate(t, "", file)["users.graphql"]; strings.Contains(out, "@values") {
+		t.Errorf("@values should not be generated by default, got:\n%s", out)
+	}
+}
+
+func TestSharedMessageTypes(t *testing.T) {
+	lines := messageField("lines", 2, ".orders.Line")
+	lines.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	file := testFile("orders.proto", "orders",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("Order", messageField("customer", 1, ".orders.Customer"), lines),
+			testMessage("Customer",
+				scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				messageField("address", 2, ".orders.Address"),
+			),
+			testMessage("Address", scalarField("city", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Line", scalarField("sku", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("CreateOrder", ".orders.Order", ".orders.Order", &options.MethodOptions{Kind: "mutation"}),
+	)
+
+	tests := []struct {
+		parameter string
+		want      []string
+	}{
+		{"", []string{
+			"type Order {\n  customer: Customer\n  lines: [Line]\n}",
+			"type Customer {\n  name: String\n  address: Address\n}",
+			"type Address {\n  city: String\n}",
+			"input IOrder {\n  customer: ICustomer\n  lines: [ILine]\n}",
+			"input ICustomer {\n  name: String\n  address: IAddress\n}",
+			"input IAddress {\n  city: String\n}",
+			"createOrder(input: IOrder!): Order!",
+		}},
+		{"input_naming=suffix", []string{
+			"input OrderInput {\n  customer: CustomerInput\n  lines: [LineInput]\n}",
+			"input CustomerInput {\n  name: String\n  address: AddressInput\n}",
+			"createOrder(input: OrderInput!): Order!",
+		}},
+		{"input_naming=prefix,affix=In", []string{
+			"input InOrder {\n  customer: InCustomer\n  lines: [InLine]\n}",
+			"createOrder(input: InOrder!): Order!",
+		}},
+	}
+	for _, tt := range tests {
+		out := generate(t, tt.parameter, file)["orders.graphql"]
+		for _, want := range tt.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("%q: output should contain\n%s\ngot:\n%s", tt.parameter, want, out)
+			}
+		}
+	}
+}
+
+func TestRecursiveInputs(t *testing.T) {
+	required := func(field *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		field.Options = &descriptorpb.FieldOptions{}
+		proto.SetExtension(field.Options, options.E_Required, true)
+		return field
+	}
+	children := required(messageField("children", 3, ".tree.Node"))
+	children.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	file := testFile("tree.proto", "tree",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("Node",
+				required(scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+				required(messageField("parent", 2, ".tree.Node")),
+				children,
+			),
+		},
+		testMethod("CreateNode", ".tree.Node", ".tree.Node", &options.MethodOptions{Kind: "mutation"}),
+	)
+
+	for _, parameter := range []string{"", "recursive_inputs=nullable"} {
+		out := generate(t, parameter, file)["tree.graphql"]
+		// Lists can be empty, so only the self reference needs to be nullable
+		if want := "input INode {\n  name: String!\n  parent: INode\n  children: [INode!]\n}"; !strings.Contains(out, want) {
+			t.Errorf("%q: output should contain\n%s\ngot:\n%s", parameter, want, out)
+		}
+		if want := "type Node {\n  name: String!\n  parent: Node!\n"; !strings.Contains(out, want) {
+			t.Errorf("%q: output types should keep non-null fields, output should contain\n%s\ngot:\n%s", parameter, want, out)
+		}
+	}
+
+	stderr := generateError(t, "recursive_inputs=error", file)
+	if !strings.Contains(stderr, "error generating input INode: INode can't be constructed, non-null fields INode.parent -> INode form a cycle") {
+		t.Errorf("recursive inputs should be reported, got %q", stderr)
+	}
+
+	// Invalid values are reported and the default applies
+	var out string
+	_, stderr = captureOutput(t, func() { out = generate(t, "recursive_inputs=strict", file)["tree.graphql"] })
+	if want := `invalid recursive_inputs "strict", expected "nullable" or "error"`; !strings.Contains(stderr, want) {
+		t.Errorf("stderr should contain %q, got: %s", want, stderr)
+	}
+	if want := "input INode {\n  name: String!\n  parent: INode\n"; !strings.Contains(out, want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+	}
+	if args := ParseArgs("recursive_inputs=strict", NewLogger(LevelError)); args.RecursiveInputs != "" {
+		t.Errorf("invalid recursive_inputs should be reset, got %q", args.RecursiveInputs)
+	}
+}
+
+func TestInputNullability(t *testing.T) {
+	withOptions := func(field *descriptorpb.FieldDescriptorProto, set map[protoreflect.ExtensionType]bool) *descriptorpb.FieldDescriptorProto {
+		field.Options = &descriptorpb.FieldOptions{}
+		for extension, value := range set {
+			proto.SetExtension(field.Options, extension, value)
+		}
+		return field
+	}
+	file := func(email *descriptorpb.FieldDescriptorProto) *descriptorpb.FileDescriptorProto {
+		user := testMessage("User",
+			withOptions(scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING), map[protoreflect.ExtensionType]bool{
+				options.E_Required:         true,
+				options.E_GqlInputOptional: true,
+			}),
+			email,
+		)
+		return testFile("users.proto", "users",
+			[]*descriptorpb.DescriptorProto{user},
+			testMethod("SaveUser", ".users.User", ".users.User", &options.MethodOptions{Kind: "mutation"}),
+		)
+	}
+
+	email := func(set map[protoreflect.ExtensionType]bool) *descriptorpb.FieldDescriptorProto {
+		return withOptions(scalarField("email", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING), set)
+	}
+	out := generate(t, "", file(email(map[protoreflect.ExtensionType]bool{options.E_GqlInputRequired: true})))["users.graphql"]
+	for _, want := range []string{
+		"type User {\n  id: String!\n  email: String\n}",
+		"input IUser {\n  id: String\n  email: String!\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+
+	// Flattened arguments are inputs too
+	out = generate(t, "flatten_args", file(email(map[protoreflect.ExtensionType]bool{options.E_GqlInputRequired: true})))["users.graphql"]
+	if !strings.Contains(out, "saveUser(id: String, email: String!): User!") {
+		t.Errorf("flattened arguments should use the input nullability, got:\n%s", out)
+	}
+
+	stderr := generateError(t, "", file(email(map[protoreflect.ExtensionType]bool{
+		options.E_GqlInputOptional: true,
+		options.E_GqlInputRequired: true,
+	})))
+	if !strings.Contains(stderr, "error generating input User: field email sets both gql_input_optional and gql_input_required") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+}
+
+func TestFlattenArgs(t *testing.T) {
+	limit := scalarField("limit", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32)
+	limit.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(limit.Options, options.E_Required, true)
+
+	request := testMessage("ListUsersRequest", limit,
+		messageField("filter", 2, ".users.Filter"),
+		messageField("sort", 3, ".users.ListUsersRequest.Sort"),
+	)
+	request.NestedType = []*descriptorpb.DescriptorProto{
+		testMessage("Sort", scalarField("field", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+	}
+
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			request,
+			testMessage("Filter", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Users", scalarField("count", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32)),
+			testMessage("DeleteUsersRequest"),
+		},
+		testMethod("ListUsers", ".users.ListUsersRequest", ".users.Users", nil),
+		testMethod("DeleteUsers", ".users.DeleteUsersRequest", ".users.Users", &options.MethodOptions{Kind: "mutation"}),
+		testMethod("CountUsers", ".users.Filter", ".users.Users", &options.MethodOptions{
+			GqlInput: &options.GqlInput{Type: "Filter", Param: "filter"},
+		}),
+	)
+
+	out := generate(t, "flatten_args", file)["users.graphql"]
+	for _, want := range []string{
+		"listUsers(limit: Int!, filter: IFilter, sort: ISort): Users!",
+		"deleteUsers: Users!\n",
+		"countUsers(filter: IFilter!): Users!",
+		"input IFilter {\n  name: String\n}",
+		"input ISort {\n  field: String\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "input IListUsersRequest") {
+		t.Errorf("flattened requests should not get an input type, got:\n%s", out)
+	}
+
+	out = generate(t, "", file)["users.graphql"]
+	if !strings.Contains(out, "listUsers(input: IListUsersRequest!): Users!") {
+		t.Errorf("requests should not be flattened by default, got:\n%s", out)
+	}
+}
+
+func TestInputParamName(t *testing.T) {
+	file := func(param string) *descriptorpb.FileDescriptorProto {
+		return testFile("users.proto", "users",
+			[]*descriptorpb.DescriptorProto{
+				testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+				testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			},
+			testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+			testMethod("FindUser", ".users.GetUserRequest", ".users.User", &options.MethodOptions{
+				GqlInput: &options.GqlInput{Param: param},
+			}),
+		)
+	}
+
+	out := generate(t, "", file("filter"))["users.graphql"]
+	for _, want := range []string{
+		"getUser(input: IGetUserRequest!): User!",
+		"findUser(filter: IGetUserRequest!): User!",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+
+	// The method param overrides the default
+	out = generate(t, "input_param_name=data", file("filter"))["users.graphql"]
+	for _, want := range []string{
+		"getUser(data: IGetUserRequest!): User!",
+		"findUser(filter: IGetUserRequest!): User!",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+
+	stderr := generateError(t, "", file("user-filter"))
+	if !strings.Contains(stderr, `error generating method FindUser: invalid input param name "user-filter"`) {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+	stderr = generateError(t, "input_param_name=1data", file(""))
+	if !strings.Contains(stderr, `error generating method GetUser: invalid input param name "1data"`) {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+}
+
+func TestBoolTypeNames(t *testing.T) {
+	file := testFile("flags.proto", "flags",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("BoolBox", scalarField("value", 1, descriptorpb.FieldDescriptorProto_TYPE_BOOL)),
+			testMessage("BooleanFlag",
+				scalarField("enabled", 1, descriptorpb.FieldDescriptorProto_TYPE_BOOL),
+				messageField("box", 2, ".flags.BoolBox"),
+			),
+		},
+		testMethod("GetFlag", ".flags.BoolBox", ".flags.BooleanFlag", &options.MethodOptions{
+			GqlInput: &options.GqlInput{Type: "BoolBox"},
+		}),
+		testMethod("IsEnabled", ".flags.BooleanFlag", ".flags.BoolBox", &options.MethodOptions{
+			GqlInput:  &options.GqlInput{Type: "[bool]", Param: "enabled"},
+			GqlOutput: "Bool",
+		}),
+	)
+
+	out := generate(t, "", file)["flags.graphql"]
+	for _, want := range []string{
+		"getFlag(input: IBoolBox!): BooleanFlag!",
+		"isEnabled(enabled: [Boolean]!): Boolean!",
+		"type BooleanFlag {\n  enabled: Boolean\n  box: BoolBox\n}",
+		"type BoolBox {\n  value: Boolean\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestGqlInputListTypes(t *testing.T) {
+	file := func(inputType string) *descriptorpb.FileDescriptorProto {
+		return testFile("users.proto", "users",
+			[]*descriptorpb.DescriptorProto{
+				testMessage("GetUsersRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+				testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			},
+			testMethod("GetUsers", ".users.GetUsersRequest", ".users.User", &options.MethodOptions{
+				GqlInput: &options.GqlInput{Type: inputType, Param: "ids"},
+			}),
+		)
+	}
+
+	out := generate(t, "", file("[Foo]"))["users.graphql"]
+	if !strings.Contains(out, "getUsers(ids: [IFoo]!): User!") {
+		t.Errorf("list input types should be generated, got:\n%s", out)
+	}
+
+	// Primitive types are kept, whether or not they are lists
+	for inputType, want := range map[string]string{
+		"String":   "getUsers(ids: String!): User!",
+		"[string]": "getUsers(ids: [String]!): User!",
+	} {
+		primitive := file(inputType)
+		getMethodOptions(primitive.Service[0].Method[0]).GqlInput.Primitive = true
+		if out := generate(t, "", primitive)["users.graphql"]; !strings.Contains(out, want) {
+			t.Errorf("%s: output should contain %q, got:\n%s", inputType, want, out)
+		}
+	}
+
+	for _, inputType := range []string{"[Foo", "Foo]", "[]", "[", "[[Foo]]"} {
+		stderr := generateError(t, "", file(inputType))
+		want := fmt.Sprintf("error generating method GetUsers: malformed type %q", inputType)
+		if !strings.Contains(stderr, want) {
+			t.Errorf("%s: error should contain %q, got: %s", inputType, want, stderr)
+		}
+	}
+}
+
+func TestGqlTypeName(t *testing.T) {
+	renamed := func(message *descriptorpb.DescriptorProto, name string) *descriptorpb.DescriptorProto {
+		message.Options = &descriptorpb.MessageOptions{}
+		proto.SetExtension(message.Options, options.E_GqlTypeName, name)
+		return message
+	}
+
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", messageField("account", 1, ".users.UserAccount")),
+			renamed(testMessage("UserAccount", scalarField("email", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)), "Account"),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+		testMethod("GetAccount", ".users.GetUserRequest", ".users.UserAccount", nil),
+		testMethod("UpdateAccount", ".users.UserAccount", ".users.UserAccount", &options.MethodOptions{Kind: "mutation"}),
+	)
+
+	out := generate(t, "type_case=snake", file)["users.graphql"]
+	for _, want := range []string{
+		"type user {\n  account: Account\n}",
+		"type Account {\n  email: String\n}",
+		"input IAccount {\n  email: String\n}",
+		"getAccount(input: Iget_user_request!): Account!",
+		"updateAccount(input: IAccount!): Account!",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "UserAccount") || strings.Contains(out, "user_account") {
+		t.Errorf("references should use the new name, got:\n%s", out)
+	}
+
+	collision := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", messageField("account", 1, ".users.UserAccount"), messageField("plan", 2, ".users.Account")),
+			renamed(testMessage("UserAccount", scalarField("email", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)), "Account"),
+			testMessage("Account", scalarField("plan", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+	stderr := generateError(t, "", collision)
+	if !strings.Contains(stderr, "error generating type Account: users.proto:UserAccount and users.proto:Account both generate Account") {
+		t.Errorf("colliding type names should be reported, got %q", stderr)
+	}
+
+	// The input of User and the type of UserInput are both UserInput with input_naming=suffix
+	inputCollision := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("UserInput", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("SaveUser", ".users.User", ".users.UserInput", &options.MethodOptions{Kind: "mutation"}),
+	)
+	stderr = generateError(t, "input_naming=suffix", inputCollision)
+	want := "error generating type UserInput: users.proto:UserInput and users.proto:User both generate UserInput, " +
+		"as a type and an input, set affix or input_naming to rename the inputs"
+	if !strings.Contains(stderr, want) {
+		t.Errorf("an input named like a type should be reported, got %q", stderr)
+	}
+	if out := generate(t, "input_naming=suffix,affix=Data", inputCollision)["users.graphql"]; !strings.Contains(out, "input UserData {") {
+		t.Errorf("another affix should resolve the collision, got:\n%s", out)
+	}
+}
+
+func TestUnwrapSingleField(t *testing.T) {
+	required := scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	required.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(required.Options, options.E_Required, true)
+
+	tags := scalarField("tags", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	tags.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	// proto3 optional fields are in a synthetic oneof, unlike the fields of a real oneof
+	email := scalarField("email", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	email.OneofIndex = proto.Int32(0)
+	email.Proto3Optional = proto.Bool(true)
+	emailResponse := testMessage("EmailResponse", email)
+	emailResponse.OneofDecl = []*descriptorpb.OneofDescriptorProto{{Name: proto.String("_email")}}
+	phone := scalarField("phone", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	phone.OneofIndex = proto.Int32(0)
+	contactResponse := testMessage("ContactResponse", phone)
+	contactResponse.OneofDecl = []*descriptorpb.OneofDescriptorProto{{Name: proto.String("contact")}}
+
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("NameResponse", required),
+			testMessage("NicknameResponse", scalarField("nickname", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("TagsResponse", tags),
+			emailResponse,
+			contactResponse,
+			testMessage("UserResponse", messageField("user", 1, ".users.User")),
+			testMessage("User",
+				scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				scalarField("name", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			),
+		},
+		testMethod("GetName", ".users.GetUserRequest", ".users.NameResponse", nil),
+		testMethod("GetNickname", ".users.GetUserRequest", ".users.NicknameResponse", nil),
+		testMethod("GetTags", ".users.GetUserRequest", ".users.TagsResponse", nil),
+		testMethod("GetEmail", ".users.GetUserRequest", ".users.EmailResponse", nil),
+		testMethod("GetContact", ".users.GetUserRequest", ".users.ContactResponse", nil),
+		testMethod("GetUser", ".users.GetUserRequest", ".users.UserResponse", nil),
+		testMethod("GetUsers", ".users.GetUserRequest", ".users.User", nil),
+	)
+
+	out := generate(t, "unwrap_single_field", file)["users.graphql"]
+	for _, want := range []string{
+		"getName(input: IGetUserRequest!): String!\n",
+		"getNickname(input: IGetUserRequest!): String\n",
+		"getTags(input: IGetUserRequest!): [String]!\n",
+		"getEmail(input: IGetUserRequest!): String\n",
+		"getContact(input: IGetUserRequest!): ContactResponse!\n",
+		"type ContactResponse {",
+		"getUser(input: IGetUserRequest!): User\n",
+		"getUsers(input: IGetUserRequest!): User!\n",
+		"type User {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "EmailResponse {") || strings.Contains(out, "NameResponse {") {
+		t.Errorf("unwrapped messages should not get a type, got:\n%s", out)
+	}
+
+	out = generate(t, "", file)["users.graphql"]
+	if !strings.Contains(out, "getName(input: IGetUserRequest!): NameResponse!") || !strings.Contains(out, "type NameResponse {") {
+		t.Errorf("single-field messages should not be unwrapped by default, got:\n%s", out)
+	}
+}
+
+func TestResourceQueries(t *testing.T) {
+	orders := messageField("orders", 3, ".users.Order")
+	orders.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	users := messageField("users", 1, ".users.User")
+	users.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	report := testMessage("Report", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING))
+	report.Options = &descriptorpb.MessageOptions{}
+	proto.SetExtension(report.Options, options.E_GqlResourceQuery, false)
+
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("ListUsersRequest", scalarField("page", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32)),
+			testMessage("ListUsersResponse", users, messageField("report", 2, ".users.Report"), messageField("address", 3, ".users.Address")),
+			testMessage("User",
+				scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				scalarField("name", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				orders,
+			),
+			testMessage("Order", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64)),
+			testMessage("Address", scalarField("city", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			report,
+		},
+		testMethod("ListUsers", ".users.ListUsersRequest", ".users.ListUsersResponse", nil),
+		testMethod("GetOrder", ".users.ListUsersRequest", ".users.Order", nil),
+	)
+
+	out := generate(t, "resource_queries", file)["users.graphql"]
+	// Only types with an id get a query, and the RPC query getOrder takes precedence
+	want := "type Query {\n" +
+		"  listUsers(input: IListUsersRequest!): ListUsersResponse!\n" +
+		"  getOrder(input: IListUsersRequest!): Order!\n" +
+		"  getUser(id: ID!): User\n" +
+		"}"
+	if !strings.Contains(out, want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+	}
+
+	if out := generate(t, "", file)["users.graphql"]; strings.Contains(out, "getUser") {
+		t.Errorf("resource queries should be opt-in, got:\n%s", out)
+	}
+
+	out = generate(t, "resource_queries,type_prefix=Acme", file)["users.graphql"]
+	if !strings.Contains(out, "  getUser(id: ID!): AcmeUser\n") {
+		t.Errorf("resource queries should be named without the type prefix, got:\n%s", out)
+	}
+}
+
+func TestRelayNode(t *testing.T) {
+	key := scalarField("key", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64)
+	key.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(key.Options, options.E_GqlId, true)
+
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("User",
+				scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				scalarField("name", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			),
+			testMessage("Team", key, messageField("owner", 2, ".users.User")),
+			testMessage("Page", messageField("users", 1, ".users.Team")),
+		},
+		testMethod("GetPage", ".users.User", ".users.Page", nil),
+	)
+
+	out := generate(t, "relay_node", file)["users.graphql"]
+	for _, want := range []string{
+		"interface Node {\n  id: ID!\n}",
+		"type User implements Node {\n  id: ID!\n  name: String\n}",
+		"type Team implements Node {\n  id: ID!\n  owner: User\n}",
+		"type Page {\n",
+		"type Query {\n  node(id: ID!): Node\n  getPage(input: IUser!): Page!\n}",
+		// Inputs keep the proto type of the id
+		"input IUser {\n  id: String\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+
+	out = generate(t, "", file)["users.graphql"]
+	if strings.Contains(out, "Node") {
+		t.Errorf("Node should only be generated with relay_node, got:\n%s", out)
+	}
+
+	file.MessageType[1].Field = append(file.MessageType[1].Field, scalarField("id", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING))
+	stderr := generateError(t, "relay_node", file)
+	if !strings.Contains(stderr, "error generating type Team: gql_id field key conflicts with the field id") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+}
+
+func TestOneofDescribe(t *testing.T) {
+	card := scalarField("card", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	card.OneofIndex = proto.Int32(0)
+	iban := scalarField("iban", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	iban.OneofIndex = proto.Int32(0)
+	// proto3 optional fields belong to a synthetic oneof, which is not described
+	note := scalarField("note", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	note.OneofIndex = proto.Int32(1)
+	note.Proto3Optional = proto.Bool(true)
+
+	payment := testMessage("Payment", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING), card, iban, note)
+	payment.OneofDecl = []*descriptorpb.OneofDescriptorProto{{Name: proto.String("method")}, {Name: proto.String("_note")}}
+
+	file := testFile("payments.proto", "payments",
+		[]*descriptorpb.DescriptorProto{payment},
+		testMethod("Pay", ".payments.Payment", ".payments.Payment", &options.MethodOptions{Kind: "mutation"}),
+	)
+
+	out := generate(t, "oneof=describe", file)["payments.graphql"]
+	const described = `  id: String
+  "Member of oneof method, only one may be set"
+  card: String
+  "Member of oneof method, only one may be set"
+  iban: String
+  note: String
+}`
+	if !strings.Contains(out, "type Payment {\n"+described) || !strings.Contains(out, "input IPayment {\n"+described) {
+		t.Errorf("oneof members should be described in types and inputs, got:\n%s", out)
+	}
+
+	if out := generate(t, "", file)["payments.graphql"]; strings.Contains(out, "oneof") {
+		t.Errorf("oneof members should not be described by default, got:\n%s", out)
+	}
+}
+
+// mapEntry returns the entry message of a map field with a string key
+func mapEntry(name string, value *descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	entry := testMessage(name, scalarField("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING), value)
+	entry.Options = &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)}
+	return entry
+}
+
+func TestInputMaps(t *testing.T) {
+	labels := messageField("labels", 1, ".users.UpdateUserRequest.LabelsEntry")
+	labels.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	addresses := messageField("addresses", 2, ".users.UpdateUserRequest.AddressesEntry")
+	addresses.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	request := testMessage("UpdateUserRequest", labels, addresses)
+	request.NestedType = []*descriptorpb.DescriptorProto{
+		mapEntry("LabelsEntry", scalarField("value", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32)),
+		mapEntry("AddressesEntry", messageField("value", 2, ".users.Address")),
+	}
+
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			request,
+			testMessage("Address", scalarField("city", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("UpdateUser", ".users.UpdateUserRequest", ".users.User", &options.MethodOptions{Kind: "mutation"}),
+	)
+
+	t.Run("entries", func(t *testing.T) {
+		out := generate(t, "", file)["users.graphql"]
+		for _, want := range []string{
+			"input IUpdateUserRequest {\n  labels: [ILabelsEntry]\n  addresses: [IAddressesEntry]\n}",
+			"input ILabelsEntry {\n  key: String!\n  value: Int!\n}",
+			"input IAddressesEntry {\n  key: String!\n  value: IAddress!\n}",
+			// Address is only used as a map value
+			"input IAddress {\n  city: String\n}",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("output should contain %q, got:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out := generate(t, "input_maps=json", file)["users.graphql"]
+		if !strings.Contains(out, "scalar JSON\n") ||
+			!strings.Contains(out, "input IUpdateUserRequest {\n  labels: JSON\n  addresses: JSON\n}") {
+			t.Errorf("string keyed maps should be JSON scalars, got:\n%s", out)
+		}
+		if strings.Contains(out, "Entry") || strings.Contains(out, "IAddress") {
+			t.Errorf("map entries and their values should not be generated, got:\n%s", out)
+		}
+	})
+}
+
+func TestEmitUnusedWarnings(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Report", enumField("period", 1, ".users.Period")),
+			testMessage("LegacyUser", scalarField("login", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Draft", scalarField("text", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+		testMethod("GetReport", ".users.GetUserRequest", ".users.Report", &options.MethodOptions{Target: "admin"}),
+		testMethod("GetLegacyUser", ".users.GetUserRequest", ".users.LegacyUser", &options.MethodOptions{Skip: true}),
+	)
+	file.EnumType = []*descriptorpb.EnumDescriptorProto{
+		testEnum("Period", "DAY", 0, "WEEK", 1),
+		testEnum("Color", "RED", 0),
+	}
+
+	_, stderr := captureOutput(t, func() { generate(t, "emit_unused_warnings", file) })
+	want := []string{
+		`warning: users.proto:Report is not generated (wrong target): referenced by Service.GetReport (target "admin")`,
+		`warning: users.proto:LegacyUser is not generated (skipped): referenced by skipped Service.GetLegacyUser`,
+		`warning: users.proto:Draft is not generated (unreachable): not referenced by any RPC`,
+		`warning: users.proto:Period is not generated (wrong target): referenced by Service.GetReport (target "admin")`,
+		`warning: users.proto:Color is not generated (unreachable): not referenced by any RPC`,
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(stderr), "\n") {
+		got = append(got, strings.TrimPrefix(line, NAME+": "))
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("unexpected warnings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// With all targets, only the type of the skipped RPC is missing
+	_, stderr = captureOutput(t, func() { generate(t, "emit_unused_warnings,target=*", file) })
+	if strings.Contains(stderr, "Report") || strings.Contains(stderr, "Period") || !strings.Contains(stderr, "LegacyUser") {
+		t.Errorf("only types of excluded RPCs should be reported, got:\n%s", stderr)
+	}
+
+	// Skipped RPCs don't reference the types of excluded packages either
+	internal := testFile("acme/internal.proto", "acme.internal",
+		[]*descriptorpb.DescriptorProto{testMessage("Audit", scalarField("actor", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING))},
+		testMethod("GetAudit", ".acme.internal.Audit", ".acme.internal.Audit", &options.MethodOptions{Skip: true}),
+	)
+	_, stderr = captureOutput(t, func() { generate(t, "emit_unused_warnings,exclude_package=acme", internal) })
+	if !strings.Contains(stderr, "acme/internal.proto:Audit is not generated (unreachable): not referenced by any RPC") {
+		t.Errorf("types of excluded packages should be unreachable, got:\n%s", stderr)
+	}
+
+	if _, stderr := captureOutput(t, func() { generate(t, "", file) }); stderr != "" {
+		t.Errorf("unused types should not be reported by default, got:\n%s", stderr)
+	}
+}
+
+func TestTypePrefix(t *testing.T) {
+	file := testFile("billing.proto", "billing",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetInvoiceRequest",
+				scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				enumField("status", 2, ".billing.Status"),
+			),
+			testMessage("Invoice",
+				scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				enumField("status", 2, ".billing.Status"),
+				messageField("issued_at", 3, ".google.protobuf.Timestamp"),
+				messageField("duplicate_of", 4, ".billing.Invoice"),
+			),
+		},
+		testMethod("GetInvoice", ".billing.GetInvoiceRequest", ".billing.Invoice", nil),
+	)
+	file.EnumType = []*descriptorpb.EnumDescriptorProto{testEnum("Status", "DRAFT", 0, "PAID", 1)}
+
+	out := generate(t, "type_prefix=Billing,scalar=google.protobuf.Timestamp:DateTime", file)["billing.graphql"]
+	checkGolden(t, "prefix/billing.graphql", out)
+
+	out = generate(t, "type_prefix=Billing,input_naming=suffix", file)["billing.graphql"]
+	if !strings.Contains(out, "input BillingGetInvoiceRequestInput {") ||
+		!strings.Contains(out, "getInvoice(input: BillingGetInvoiceRequestInput!): BillingInvoice!") {
+		t.Errorf("suffixed inputs should be prefixed, got:\n%s", out)
+	}
+}
+
+func TestKeepPrefix(t *testing.T) {
+	file := func(name, pkg string) *descriptorpb.FileDescriptorProto {
+		file := testFile(name, pkg, []*descriptorpb.DescriptorProto{
+			testMessage("Account",
+				scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				enumField("status", 2, "."+pkg+".Status"),
+			),
+			testMessage("GetAccountRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		}, testMethod("GetAccount", "."+pkg+".GetAccountRequest", "."+pkg+".Account", nil))
+		file.EnumType = []*descriptorpb.EnumDescriptorProto{testEnum("Status", "ACTIVE", 0, "CLOSED", 1)}
+		file.MessageType[0].EnumType = []*descriptorpb.EnumDescriptorProto{testEnum("Kind", "PERSONAL", 0)}
+		file.MessageType[0].Field = append(file.MessageType[0].Field, enumField("kind", 3, "."+pkg+".Account.Kind"))
+		return file
+	}
+
+	// Same-named types and enums of different packages don't collide, and references use the prefixed names
+	out := generate(t, "combine_output,keep_prefix,type_prefix=Gql", file("users.proto", "users"), file("billing.proto", "billing"))["schema.graphql"]
+	for _, want := range []string{
+		"type GqlUsersAccount {\n  id: String\n  status: GqlUsersStatus\n  kind: GqlUsersKind\n}",
+		"type GqlBillingAccount {\n  id: String\n  status: GqlBillingStatus\n  kind: GqlBillingKind\n}",
+		"enum GqlUsersStatus {",
+		"enum GqlBillingStatus {",
+		"enum GqlUsersKind {",
+		"enum GqlBillingKind {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+
+	// type_case applies to the prefixed name
+	out = generate(t, "keep_prefix,type_case=snake", file("users.proto", "users"))["users.graphql"]
+	if !strings.Contains(out, "type users_account {\n  id: String\n  status: users_status\n  kind: users_kind\n}") ||
+		!strings.Contains(out, "enum users_status {") {
+		t.Errorf("enum names should follow type_case like message names, got:\n%s", out)
+	}
+}
+
+func TestStripSuffix(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("GetUserResponse", messageField("user", 1, ".users.User")),
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Response", scalarField("code", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32)),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.GetUserResponse", nil),
+		testMethod("Ping", ".users.GetUserRequest", ".users.Response", nil),
+	)
+
+	out := generate(t, "strip_suffix=Request,strip_suffix=Response", file)["users.graphql"]
+	for _, want := range []string{
+		"type GetUser {\n  user: User\n}",
+		"input IGetUser {\n  id: String\n}",
+		"getUser(input: IGetUser!): GetUser!",
+		// A suffix that is the whole name is kept
+		"ping(input: IGetUser!): Response!",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+
+	// Stripping the suffix of UserResponse generates the name of User
+	collision := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("UserResponse", messageField("user", 1, ".users.User")),
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.UserResponse", nil),
+	)
+	stderr := generateError(t, "strip_suffix=Response", collision)
+	if !strings.Contains(stderr, "users.proto:UserResponse and users.proto:User both generate User") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+}
+
+func TestAllInputs(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User",
+				scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				messageField("balance", 2, ".users.Money"),
+				messageField("address", 3, ".users.Address"),
+			),
+			testMessage("Money", scalarField("units", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64)),
+			testMessage("Address", scalarField("city", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+
+	out := generate(t, "scalar=users.Money:Money", file)["users.graphql"]
+	if strings.Contains(out, "input IUser") || strings.Contains(out, "input IAddress") {
+		t.Errorf("output-only messages should not get input types by default, got:\n%s", out)
+	}
+
+	out = generate(t, "all_inputs,scalar=users.Money:Money", file)["users.graphql"]
+	for _, want := range []string{
+		"type User {",
+		"input IUser {\n  name: String\n  balance: Money\n  address: IAddress\n}",
+		"input IAddress {\n  city: String\n}",
+		"input IGetUserRequest {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "input IMoney") || strings.Contains(out, "type Money") {
+		t.Errorf("messages mapped to scalars should not get types or inputs, got:\n%s", out)
+	}
+}
+
+// generate runs the plugin over the given files and returns the generated file contents by name
+func generate(t *testing.T, parameter string, files ...*descriptorpb.FileDescriptorProto) map[string]string {
+	t.Helper()
+
+	request := &pluginpb.CodeGeneratorRequest{
+		Parameter: proto.String(parameter),
+		ProtoFile: files,
+	}
+	for _, file := range files {
+		request.FileToGenerate = append(request.FileToGenerate, file.GetName())
+	}
+
+	plugin := New(request)
+	plugin.Execute()
+
+	result := make(map[string]string)
+	for _, file := range plugin.Response.File {
+		result[file.GetName()] = file.GetContent()
+	}
+	return result
+}
+
+// generateError runs the plugin expecting it to exit with an error and returns what it printed
+func generateError(t *testing.T, parameter string, files ...*descriptorpb.FileDescriptorProto) string {
+	t.Helper()
+	return runError(t, func() { generate(t, parameter, files...) })
+}
+
+// runError calls run expecting the plugin to exit with an error and returns what it printed
+func runError(t *testing.T, run func()) string {
+	t.Helper()
+
+	type exitCode int
+	exit = func(code int) { panic(exitCode(code)) }
+	defer func() { exit = os.Exit }()
+
+	exited := false
+	_, stderr := captureOutput(t, func() {
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(exitCode); !ok {
+					panic(r)
+				}
+				exited = true
+			}
+		}()
+		run()
+	})
+
+	if !exited {
+		t.Fatalf("generation should fail, stderr:\n%s", stderr)
+	}
+	return stderr
+}
+
+func testFile(name, pkg string, messages []*descriptorpb.DescriptorProto, methods ...*descriptorpb.MethodDescriptorProto) *descriptorpb.FileDescriptorProto {
+	file := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String(name),
+		Package:     proto.String(pkg),
+		MessageType: messages,
+	}
+	if len(methods) > 0 {
+		file.Service = []*descriptorpb.ServiceDescriptorProto{
+			{Name: proto.String("Service"), Method: methods},
+		}
+	}
+	return file
+}
+
+func testMessage(name string, fields ...*descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	return &descriptorpb.DescriptorProto{Name: proto.String(name), Field: fields}
+}
+
+func scalarField(name string, number int32, t descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+	return &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Type:   t.Enum(),
+	}
+}
+
+func messageField(name string, number int32, typeName string) *descriptorpb.FieldDescriptorProto {
+	field := scalarField(name, number, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	field.TypeName = proto.String(typeName)
+	return field
+}
+
+func enumField(name string, number int32, typeName string) *descriptorpb.FieldDescriptorProto {
+	field := scalarField(name, number, descriptorpb.FieldDescriptorProto_TYPE_ENUM)
+	field.TypeName = proto.String(typeName)
+	return field
+}
+
+// testEnum builds an enum from alternating value names and numbers
+func testEnum(name string, values ...interface{}) *descriptorpb.EnumDescriptorProto {
+	enum := &descriptorpb.EnumDescriptorProto{Name: proto.String(name)}
+	for i := 0; i < len(values); i += 2 {
+		enum.Value = append(enum.Value, &descriptorpb.EnumValueDescriptorProto{
+			Name:   proto.String(values[i].(string)),
+			Number: proto.Int32(int32(values[i+1].(int))),
+		})
+	}
+	return enum
+}
+
+func testMethod(name, input, output string, methodOptions *options.MethodOptions) *descriptorpb.MethodDescriptorProto {
+	method := &descriptorpb.MethodDescriptorProto{
+		Name:       proto.String(name),
+		InputType:  proto.String(input),
+		OutputType: proto.String(output),
+	}
+	if methodOptions != nil {
+		method.Options = &descriptorpb.MethodOptions{}
+		proto.SetExtension(method.Options, options.E_Method, methodOptions)
+	}
+	return method
+}
+
+func TestServiceFilter(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("BanRequest", scalarField("reason", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("Ban", scalarField("until", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+	file.Service[0].Name = proto.String("UserService")
+	file.Service = append(file.Service, &descriptorpb.ServiceDescriptorProto{
+		Name: proto.String("AdminService"),
+		Method: []*descriptorpb.MethodDescriptorProto{
+			testMethod("BanUser", ".users.BanRequest", ".users.Ban", &options.MethodOptions{Kind: "mutation"}),
+		},
+	})
+
+	out := generate(t, "service=UserService", file)["users.graphql"]
+	for _, want := range []string{"getUser(input: IGetUserRequest!): User!", "type User {"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"banUser", "Ban {", "IBanRequest"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("output should not contain %q of the other service, got:\n%s", unwanted, out)
+		}
+	}
+
+	out = generate(t, "service=UserService,service=AdminService", file)["users.graphql"]
+	if !strings.Contains(out, "getUser(") || !strings.Contains(out, "banUser(input: IBanRequest!): Ban!") {
+		t.Errorf("both services should be generated, got:\n%s", out)
+	}
+
+	_, stderr := captureOutput(t, func() { generate(t, "service=UsersService", file) })
+	if !strings.Contains(stderr, "warning: service UsersService is not defined by the generated proto files") {
+		t.Errorf("unknown services should be reported, got %q", stderr)
+	}
+}
+
+func TestFieldNameCollisions(t *testing.T) {
+	colliding := func(name string) *descriptorpb.DescriptorProto {
+		return testMessage(name,
+			scalarField("user_id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			scalarField("userId", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+		)
+	}
+	user := testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING))
+	request := testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING))
+
+	for _, tt := range []struct {
+		name, param string
+		file        *descriptorpb.FileDescriptorProto
+		want        string
+	}{
+		{"type", "", testFile("users.proto", "users",
+			[]*descriptorpb.DescriptorProto{request, colliding("User")},
+			testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+		), "error generating type User: fields user_id and userId of User both generate the field userId"},
+		{"input", "", testFile("users.proto", "users",
+			[]*descriptorpb.DescriptorProto{colliding("GetUserRequest"), user},
+			testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+		), "error generating input IGetUserRequest: fields user_id and userId of GetUserRequest both generate the field userId"},
+		{"arguments", "flatten_args", testFile("users.proto", "users",
+			[]*descriptorpb.DescriptorProto{colliding("GetUserRequest"), user},
+			testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+		), "error generating method GetUser: fields user_id and userId of GetUserRequest both generate the field userId"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stderr := generateError(t, tt.param, tt.file)
+			if !strings.Contains(stderr, tt.want) {
+				t.Errorf("expected %q, got: %s", tt.want, stderr)
+			}
+		})
+	}
+
+	// Fields with different names once cased are kept
+	out := generate(t, "field_case=original", testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{request, colliding("User")},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	))["users.graphql"]
+	if !strings.Contains(out, "type User {\n  user_id: String\n  userId: String\n}") {
+		t.Errorf("fields with distinct names should be generated, got:\n%s", out)
+	}
+}
+
+func TestSectionOrder(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", enumField("status", 1, ".users.Status")),
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+	file.EnumType = []*descriptorpb.EnumDescriptorProto{testEnum("Status", "ACTIVE", 0)}
+
+	sequence := func(out string, heads ...string) []int {
+		var positions []int
+		for _, head := range heads {
+			positions = append(positions, strings.Index(out, head))
+		}
+		return positions
+	}
+	heads := []string{"type Query {", "type Mutation {", "enum Status {", "type User {", "input IGetUserRequest {"}
+
+	out := generate(t, "section_order=operations,section_order=enums,section_order=types,section_order=inputs", file)["users.graphql"]
+	positions := sequence(out, heads...)
+	if slices.Contains(positions, -1) || !slices.IsSorted(positions) {
+		t.Errorf("sections should be written in the order %v, got:\n%s", heads, out)
+	}
+	if !strings.Contains(out, "type Mutation {\n}\n\nenum Status {") {
+		t.Errorf("sections after the roots should be separated by a blank line, got:\n%s", out)
+	}
+
+	// The default order is types, inputs, enums, then operations
+	out = generate(t, "", file)["users.graphql"]
+	if positions := sequence(out, "type User {", "input IGetUserRequest {", "enum Status {", "type Query {"); !slices.IsSorted(positions) {
+		t.Errorf("sections should be written in the default order, got:\n%s", out)
+	}
+
+	for _, param := range []string{
+		"section_order=operations,section_order=types",
+		"section_order=types,section_order=types,section_order=inputs,section_order=enums",
+		"section_order=types,section_order=inputs,section_order=enums,section_order=queries",
+	} {
+		stderr := generateError(t, param, file)
+		if !strings.Contains(stderr, "invalid section_order: got ") {
+			t.Errorf("%s: unexpected error: %s", param, stderr)
+		}
+	}
+}
+
+func TestSignedIntegers(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User",
+				scalarField("a", 1, descriptorpb.FieldDescriptorProto_TYPE_SINT32),
+				scalarField("b", 2, descriptorpb.FieldDescriptorProto_TYPE_SINT64),
+				scalarField("c", 3, descriptorpb.FieldDescriptorProto_TYPE_SFIXED32),
+				scalarField("d", 4, descriptorpb.FieldDescriptorProto_TYPE_SFIXED64),
+				scalarField("e", 5, descriptorpb.FieldDescriptorProto_TYPE_INT64),
+			),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+
+	out := generate(t, "", file)["users.graphql"]
+	if want := "type User {\n  a: Int\n  b: Int\n  c: Int\n  d: Int\n  e: Int\n}"; !strings.Contains(out, want) {
+		t.Errorf("signed integers should be Int, got:\n%s", out)
+	}
+
+	out = generate(t, "annotate_source", file)["users.graphql"]
+	for _, want := range []string{
+		"  a: Int # proto field 1, sint32: signed, zigzag encoded\n",
+		"  b: Int # proto field 2, sint64: signed, zigzag encoded\n",
+		"  c: Int # proto field 3, sfixed32: signed, fixed width\n",
+		"  d: Int # proto field 4, sfixed64: signed, fixed width\n",
+		"  e: Int # proto field 5\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestNestedInputTypes(t *testing.T) {
+	address := testMessage("Address", scalarField("city", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING))
+	profile := testMessage("Profile", messageField("address", 1, ".users.Profile.Address"))
+	profile.NestedType = []*descriptorpb.DescriptorProto{address}
+	tags := messageField("tags", 2, ".users.Tag")
+	tags.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("CreateUserRequest", messageField("profile", 1, ".users.Profile"), tags),
+			testMessage("User", messageField("profile", 1, ".users.Profile")),
+			profile,
+			testMessage("Tag", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("CreateUser", ".users.CreateUserRequest", ".users.User", &options.MethodOptions{Kind: "mutation"}),
+	)
+
+	// Each level of the chain references the input variant of the next one, while types reference types
+	for _, tt := range []struct {
+		param string
+		want  []string
+	}{
+		{"", []string{
+			"input ICreateUserRequest {\n  profile: IProfile\n  tags: [ITag]\n}",
+			"input IProfile {\n  address: IAddress\n}",
+			"input IAddress {\n  city: String\n}",
+			"type User {\n  profile: Profile\n}",
+			"type Profile {\n  address: Address\n}",
+		}},
+		{"input_naming=suffix,type_prefix=Acme", []string{
+			"input AcmeCreateUserRequestInput {\n  profile: AcmeProfileInput\n  tags: [AcmeTagInput]\n}",
+			"input AcmeProfileInput {\n  address: AcmeAddressInput\n}",
+			"type AcmeProfile {\n  address: AcmeAddress\n}",
+		}},
+	} {
+		out := generate(t, tt.param, file)["users.graphql"]
+		for _, want := range tt.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s: output should contain\n%s\ngot:\n%s", tt.param, want, out)
+			}
+		}
+	}
+}
+
+func TestUntargetedMethods(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+		testMethod("BanUser", ".users.GetUserRequest", ".users.User", &options.MethodOptions{Target: "admin"}),
+	)
+
+	for _, tt := range []struct {
+		param     string
+		want, not []string
+	}{
+		{"target=admin", []string{"getUser(", "banUser("}, nil},
+		{"target=client", []string{"getUser("}, []string{"banUser("}},
+		{"target=admin,exclude_untargeted", []string{"banUser("}, []string{"getUser("}},
+		{"exclude_untargeted", []string{"getUser("}, []string{"banUser("}},
+		{"target=admin,default_target=admin", []string{"getUser(", "banUser("}, nil},
+		{"target=client,default_target=admin", nil, []string{"getUser(", "banUser("}},
+		{"target=client,default_target=client,exclude_untargeted", []string{"getUser("}, []string{"banUser("}},
+	} {
+		out := generate(t, tt.param, file)["users.graphql"]
+		for _, want := range tt.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s: output should contain %q, got:\n%s", tt.param, want, out)
+			}
+		}
+		for _, not := range tt.not {
+			if strings.Contains(out, not) {
+				t.Errorf("%s: output should not contain %q, got:\n%s", tt.param, not, out)
+			}
+		}
+	}
+}
+
+func TestAllNullable(t *testing.T) {
+	required := func(field *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		field.Options = &descriptorpb.FieldOptions{}
+		proto.SetExtension(field.Options, options.E_Required, true)
+		return field
+	}
+	roles := required(scalarField("roles", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING))
+	roles.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", required(scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING))),
+			testMessage("User",
+				required(scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+				roles,
+				required(messageField("team", 3, ".users.Team")),
+			),
+			testMessage("Team", required(scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING))),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+		testMethod("SaveUser", ".users.GetUserRequest", ".users.User", &options.MethodOptions{Kind: "mutation"}),
+	)
+
+	out := generate(t, "", file)["users.graphql"]
+	if !strings.Contains(out, "type User {\n  id: String!\n  roles: [String!]\n  team: Team!\n}") {
+		t.Errorf("required output fields should be non-null by default, got:\n%s", out)
+	}
+
+	out = generate(t, "all_nullable,relay_node", file)["users.graphql"]
+	for _, want := range []string{
+		"interface Node {\n  id: ID\n}",
+		"type User implements Node {\n  id: ID\n  roles: [String]\n  team: Team\n}",
+		"type Team {\n  name: String\n}",
+		"  getUser(input: IGetUserRequest!): User\n",
+		"  saveUser(input: IGetUserRequest!): User\n",
+		// Inputs keep their nullability
+		"input IGetUserRequest {\n  id: String!\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+	// Only arguments are non-null
+	types := out[:strings.Index(out, "input ")] + out[strings.Index(out, "type Query"):]
+	types = strings.NewReplacer("(id: ID!)", "", "(input: IGetUserRequest!)", "").Replace(types)
+	if strings.Contains(types, "!") {
+		t.Errorf("output fields should all be nullable, got:\n%s", out)
+	}
+}
+
+func TestListNullability(t *testing.T) {
+	list := func(name string, number int32, set map[protoreflect.ExtensionType]bool) *descriptorpb.FieldDescriptorProto {
+		field := scalarField(name, number, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+		field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		field.Options = &descriptorpb.FieldOptions{}
+		for extension, value := range set {
+			proto.SetExtension(field.Options, extension, value)
+		}
+		return field
+	}
+	user := testMessage("User",
+		list("tags", 1, nil),
+		list("roles", 2, map[protoreflect.ExtensionType]bool{options.E_Required: true}),
+		list("aliases", 3, map[protoreflect.ExtensionType]bool{options.E_GqlElementNullable: true}),
+		list("emails", 4, map[protoreflect.ExtensionType]bool{options.E_GqlElementNullable: false}),
+	)
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{user},
+		testMethod("SaveUser", ".users.User", ".users.User", &options.MethodOptions{Kind: "mutation"}),
+	)
+
+	out := generate(t, "", file)["users.graphql"]
+	for _, want := range []string{
+		"type User {\n  tags: [String]\n  roles: [String!]\n  aliases: [String]!\n  emails: [String!]!\n}",
+		"input IUser {\n  tags: [String]\n  roles: [String!]\n  aliases: [String]!\n  emails: [String!]!\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+
+	out = generate(t, "flatten_args", file)["users.graphql"]
+	if want := "saveUser(tags: [String], roles: [String!], aliases: [String]!, emails: [String!]!): User!"; !strings.Contains(out, want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+	}
+}
+
+func TestNullableModes(t *testing.T) {
+	optional := func(field *descriptorpb.FieldDescriptorProto, oneof int32) *descriptorpb.FieldDescriptorProto {
+		field.OneofIndex = proto.Int32(oneof)
+		field.Proto3Optional = proto.Bool(true)
+		return field
+	}
+	user := testMessage("User",
+		optional(scalarField("nickname", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING), 0),
+		optional(messageField("billing", 2, ".users.Address"), 1),
+		scalarField("name", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+		messageField("home", 4, ".users.Address"),
+	)
+	user.OneofDecl = []*descriptorpb.OneofDescriptorProto{{Name: proto.String("_nickname")}, {Name: proto.String("_billing")}}
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{user, testMessage("Address", scalarField("city", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING))},
+		testMethod("SaveUser", ".users.User", ".users.User", &options.MethodOptions{Kind: "mutation"}),
+	)
+	file.Syntax = proto.String("proto3")
+
+	const nullableInput = "input IUser {\n  nickname: String\n  billing: IAddress\n  name: String\n  home: IAddress\n}"
+	tests := []struct {
+		parameter string
+		want      []string
+	}{
+		{"", []string{
+			"type User {\n  nickname: String\n  billing: Address\n  name: String\n  home: Address\n}",
+			"saveUser(input: IUser!): User!",
+		}},
+		{"nullable=default", []string{
+			"type User {\n  nickname: String\n  billing: Address\n  name: String\n  home: Address\n}",
+			"saveUser(input: IUser!): User!",
+		}},
+		{"nullable=none", []string{
+			"type User {\n  nickname: String\n  billing: Address\n  name: String!\n  home: Address!\n}",
+			"type Address {\n  city: String!\n}",
+			"saveUser(input: IUser!): User!",
+		}},
+		{"nullable=all", []string{
+			"type User {\n  nickname: String\n  billing: Address\n  name: String\n  home: Address\n}",
+			"saveUser(input: IUser!): User\n",
+		}},
+		// all_nullable takes precedence over nullable=none
+		{"nullable=none,all_nullable", []string{
+			"type User {\n  nickname: String\n  billing: Address\n  name: String\n  home: Address\n}",
+		}},
+	}
+	for _, tt := range tests {
+		out := generate(t, tt.parameter, file)["users.graphql"]
+		for _, want := range append(tt.want, nullableInput) {
+			if !strings.Contains(out, want) {
+				t.Errorf("%q: output should contain\n%s\ngot:\n%s", tt.parameter, want, out)
+			}
+		}
+	}
+
+	// Fields of proto2 files without required are nullable, as they may be unset
+	file.Syntax = proto.String("proto2")
+	out := generate(t, "nullable=none", file)["users.graphql"]
+	if !strings.Contains(out, "type User {\n  nickname: String\n  billing: Address\n  name: String\n  home: Address\n}") {
+		t.Errorf("proto2 fields should stay nullable, got:\n%s", out)
+	}
+}
+
+func TestNonEmpty(t *testing.T) {
+	roles := scalarField("roles", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	roles.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	roles.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(roles.Options, options.E_GqlNonEmpty, true)
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING), roles),
+		},
+		testMethod("SaveUser", ".users.User", ".users.User", &options.MethodOptions{Kind: "mutation"}),
+	)
+
+	out := generate(t, "", file)["users.graphql"]
+	for _, want := range []string{
+		"directive @constraint(minItems: Int) on FIELD_DEFINITION | INPUT_FIELD_DEFINITION | ARGUMENT_DEFINITION\n",
+		"type User {\n  name: String\n  roles: [String] @constraint(minItems: 1)\n}",
+		"input IUser {\n  name: String\n  roles: [String] @constraint(minItems: 1)\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+
+	// The directive is only declared if a field uses it
+	if out := generate(t, "", usersFile("users.proto", "users"))["users.graphql"]; strings.Contains(out, "@constraint") {
+		t.Errorf("@constraint should not be declared, got:\n%s", out)
+	}
+
+	// Flattened arguments keep the directive, and declare it without an input type using it
+	out = generate(t, "flatten_args=true", file)["users.graphql"]
+	for _, want := range []string{
+		"directive @constraint(minItems: Int) on FIELD_DEFINITION | INPUT_FIELD_DEFINITION | ARGUMENT_DEFINITION\n",
+		"saveUser(name: String, roles: [String] @constraint(minItems: 1)): User!",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("flatten_args: output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "input IUser") {
+		t.Errorf("flatten_args: the request should have no input type, got:\n%s", out)
+	}
+
+	roles.Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	stderr := generateError(t, "", file)
+	if !strings.Contains(stderr, "error generating field: gql_non_empty field roles must be repeated") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+}
+
+func TestOutputInputOnly(t *testing.T) {
+	createdAt := scalarField("created_at", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	createdAt.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(createdAt.Options, options.E_GqlOutputOnly, true)
+	password := scalarField("password", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	password.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(password.Options, options.E_GqlInputOnly, true)
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING), createdAt, password),
+		},
+		testMethod("SaveUser", ".users.User", ".users.User", &options.MethodOptions{Kind: "mutation"}),
+	)
+
+	out := generate(t, "", file)["users.graphql"]
+	for _, want := range []string{
+		"type User {\n  name: String\n  createdAt: String\n}",
+		"input IUser {\n  name: String\n  password: String\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+
+	// Flattened arguments are input fields too
+	out = generate(t, "flatten_args", file)["users.graphql"]
+	if want := "saveUser(name: String, password: String): User!"; !strings.Contains(out, want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+	}
+
+	// A gql_input_only field of a message only used as output is generated nowhere
+	file.Service[0].Method[0].InputType = proto.String(".users.GetUserRequest")
+	file.MessageType = append(file.MessageType, testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)))
+	_, stderr := captureOutput(t, func() { out = generate(t, "", file)["users.graphql"] })
+	if want := "field password of User is gql_input_only, but User is only used as output, the field is not generated"; !strings.Contains(stderr, want) {
+		t.Errorf("stderr should contain %q, got: %s", want, stderr)
+	}
+	if !strings.Contains(out, "type User {\n  name: String\n  createdAt: String\n}") {
+		t.Errorf("password should not be generated, got:\n%s", out)
+	}
+
+	// And a gql_output_only field of a message only used as input
+	file.Service[0].Method[0].InputType = proto.String(".users.User")
+	file.Service[0].Method[0].OutputType = proto.String(".users.GetUserRequest")
+	_, stderr = captureOutput(t, func() { generate(t, "", file) })
+	if want := "field created_at of User is gql_output_only, but User is only used as input, the field is not generated"; !strings.Contains(stderr, want) {
+		t.Errorf("stderr should contain %q, got: %s", want, stderr)
+	}
+
+	proto.SetExtension(password.Options, options.E_GqlOutputOnly, true)
+	stderr = generateError(t, "", file)
+	if !strings.Contains(stderr, "error generating field: field password can't be both gql_output_only and gql_input_only") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+}
+
+func TestScalarByName(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("user_uuid", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User",
+				scalarField("user_uuid", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				scalarField("contactEmail", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				scalarField("uuid_count", 3, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+				scalarField("email_verified", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+
+	out := generate(t, "scalar_by_name=UUID:UUID,scalar_by_name=email:Email", file)["users.graphql"]
+	for _, want := range []string{
+		"scalar UUID\nscalar Email\n",
+		"type User {\n  userUuid: UUID\n  contactEmail: Email\n  uuidCount: Int\n  emailVerified: String\n}",
+		"input IGetUserRequest {\n  userUuid: UUID\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "scalar UUID\n") != 1 {
+		t.Errorf("UUID should be declared once, got:\n%s", out)
+	}
+
+	// Substring matching maps names containing a token, but only string fields
+	out = generate(t, "scalar_by_name=uuid:UUID,scalar_by_name=email:Email,scalar_name_match=substring", file)["users.graphql"]
+	if want := "  uuidCount: Int\n  emailVerified: Email\n"; !strings.Contains(out, want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+	}
+
+	// Scalars of flattened arguments are declared too
+	out = generate(t, "scalar_by_name=uuid:UUID,flatten_args", file)["users.graphql"]
+	for _, want := range []string{"scalar UUID\n", "getUser(userUuid: UUID): User!"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+}
+
+func TestArgsType(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("ListUsersRequest",
+				scalarField("page_token", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				scalarField("trace_id", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			),
+			testMessage("ListUsersArgs", scalarField("limit", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32)),
+			testMessage("Users", scalarField("total", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32)),
+		},
+		testMethod("ListUsers", ".users.ListUsersRequest", ".users.Users", &options.MethodOptions{GqlArgsType: ".users.ListUsersArgs"}),
+	)
+
+	out := generate(t, "", file)["users.graphql"]
+	for _, want := range []string{
+		"input IListUsersArgs {\n  limit: Int\n}",
+		"listUsers(input: IListUsersArgs!): Users!",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "ListUsersRequest") {
+		t.Errorf("the RPC input type should not be generated, got:\n%s", out)
+	}
+
+	out = generate(t, "flatten_args", file)["users.graphql"]
+	if want := "listUsers(limit: Int): Users!"; !strings.Contains(out, want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+	}
+
+	file.Service[0].Method[0] = testMethod("ListUsers", ".users.ListUsersRequest", ".users.Users",
+		&options.MethodOptions{GqlArgsType: "users.Missing"})
+	stderr := generateError(t, "", file)
+	if !strings.Contains(stderr, "error generating method ListUsers: gql_args_type users.Missing is not a message") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+}
+
+func TestReservedFieldNames(t *testing.T) {
+	typename := scalarField("type_name", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	typename.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(typename.Options, options.E_GqlName, "__typename")
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			// Names starting with an underscore are not camel cased, so __internal_id stays reserved
+			testMessage("User", scalarField("__internal_id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING), typename),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+		testMethod("SaveUser", ".users.User", ".users.User", &options.MethodOptions{Kind: "mutation"}),
+	)
+
+	var out map[string]string
+	_, stderr := captureOutput(t, func() { out = generate(t, "", file) })
+	if strings.Count(stderr, "field type_name generates") != 1 {
+		t.Errorf("the type and the input of User should warn once, got: %s", stderr)
+	}
+	if want := "type User {\n  _internal_id: String\n  _typename: String\n}"; !strings.Contains(out["users.graphql"], want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out["users.graphql"])
+	}
+	for _, want := range []string{
+		`field __internal_id generates the reserved name __internal_id, names starting with "__" are reserved for introspection, renamed to _internal_id`,
+		"field type_name generates the reserved name __typename, it collides with the introspection field __typename, renamed to _typename",
+	} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("stderr should contain %q, got: %s", want, stderr)
+		}
+	}
+
+	stderr = generateError(t, "reserved_names=error", file)
+	if !strings.Contains(stderr, "error generating field: field __internal_id generates the reserved name __internal_id") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+}
+
+func TestEditions(t *testing.T) {
+	presence := func(name string, number int32, fieldPresence descriptorpb.FeatureSet_FieldPresence) *descriptorpb.FieldDescriptorProto {
+		field := scalarField(name, number, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+		field.Options = &descriptorpb.FieldOptions{Features: &descriptorpb.FeatureSet{FieldPresence: fieldPresence.Enum()}}
+		return field
+	}
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("User",
+				presence("id", 1, descriptorpb.FeatureSet_LEGACY_REQUIRED),
+				scalarField("name", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				presence("email", 3, descriptorpb.FeatureSet_IMPLICIT),
+				presence("phone", 4, descriptorpb.FeatureSet_EXPLICIT),
+			),
+		},
+		testMethod("SaveUser", ".users.User", ".users.User", &options.MethodOptions{Kind: "mutation"}),
+	)
+	file.Syntax = proto.String("editions")
+	file.Edition = descriptorpb.Edition_EDITION_2023.Enum()
+	file.Options = &descriptorpb.FileOptions{Features: &descriptorpb.FeatureSet{FieldPresence: descriptorpb.FeatureSet_IMPLICIT.Enum()}}
+
+	out := generate(t, "", file)["users.graphql"]
+	for _, want := range []string{
+		"type User {\n  id: String!\n  name: String\n  email: String\n  phone: String\n}",
+		"input IUser {\n  id: String!\n  name: String\n  email: String\n  phone: String\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+
+	// With nullable=none, fields with implicit presence are non-null like proto3 fields, whether the presence is set
+	// on the field or inherited from the file. Explicit presence, set on the field or by the edition, keeps them nullable
+	out = generate(t, "nullable=none", file)["users.graphql"]
+	if want := "type User {\n  id: String!\n  name: String!\n  email: String!\n  phone: String\n}"; !strings.Contains(out, want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+	}
+	file.Options = nil
+	out = generate(t, "nullable=none", file)["users.graphql"]
+	if want := "type User {\n  id: String!\n  name: String\n  email: String!\n  phone: String\n}"; !strings.Contains(out, want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+	}
+
+	// Message fields with the DELIMITED encoding reference their message, protoc may describe them as groups
+	address := messageField("address", 5, ".users.Address")
+	address.Type = descriptorpb.FieldDescriptorProto_TYPE_GROUP.Enum()
+	file.MessageType[0].Field = append(file.MessageType[0].Field, address)
+	file.MessageType = append(file.MessageType, testMessage("Address", scalarField("city", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)))
+	out = generate(t, "", file)["users.graphql"]
+	for _, want := range []string{"  address: Address\n}", "type Address {\n  city: String\n}", "  address: IAddress\n}", "input IAddress {\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+
+	plugin := New(&pluginpb.CodeGeneratorRequest{})
+	plugin.SetSupportedFeatures()
+	if features := plugin.Response.GetSupportedFeatures(); features&uint64(pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS) == 0 ||
+		features&uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL) == 0 {
+		t.Errorf("proto3 optional and editions should be supported, got features %d", features)
+	}
+	if plugin.Response.GetMaximumEdition() != int32(descriptorpb.Edition_EDITION_2023) {
+		t.Errorf("editions up to 2023 should be supported, got %d", plugin.Response.GetMaximumEdition())
+	}
+}
+
+func TestExcludePackage(t *testing.T) {
+	// A file of an excluded package, generated because it is passed to protoc
+	internal := testFile("acme/internal.proto", "acme.internal",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("Audit", messageField("actor", 1, ".acme.internal.Actor")),
+			testMessage("Actor", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("GetAudit", ".acme.internal.Audit", ".acme.internal.Audit", nil),
+	)
+	users := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("GetUserRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", messageField("audit", 1, ".acme.internal.Audit")),
+		},
+		testMethod("GetUser", ".users.GetUserRequest", ".users.User", nil),
+	)
+
+	out := generate(t, "exclude_package=acme,scalar=acme.internal.Audit:JSON", internal, users)
+	if strings.Contains(out["acme/internal.graphql"], "type Audit") || strings.Contains(out["acme/internal.graphql"], "type Actor") {
+		t.Errorf("types of excluded packages should not be generated, got:\n%s", out["acme/internal.graphql"])
+	}
+	if want := "type User {\n  audit: JSON\n}"; !strings.Contains(out["users.graphql"], want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out["users.graphql"])
+	}
+
+	// Without the option, the types are generated
+	out = generate(t, "", internal, users)
+	if !strings.Contains(out["acme/internal.graphql"], "type Actor {") {
+		t.Errorf("Actor should be generated by default, got:\n%s", out["acme/internal.graphql"])
+	}
+
+	// Unmapped references fail generation
+	stderr := generateError(t, "exclude_package=acme", internal, users)
+	if !strings.Contains(stderr, "error generating method: method GetAudit references acme.internal.Audit of an excluded package") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+	audits := testFile("audits.proto", "audits",
+		[]*descriptorpb.DescriptorProto{testMessage("GetAuditRequest", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING))},
+		testMethod("GetLastAudit", ".audits.GetAuditRequest", ".acme.internal.Audit", nil),
+	)
+	stderr = generateError(t, "exclude_package=acme", audits)
+	if !strings.Contains(stderr, "error generating method: method GetLastAudit references acme.internal.Audit of an excluded package") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+	out = generate(t, "exclude_package=acme,scalar=acme.internal.Audit:JSON", internal, audits)
+	if want := "scalar JSON\n\ntype Query {\n  getAudit(input: JSON!): JSON!\n}"; !strings.Contains(out["acme/internal.graphql"], want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out["acme/internal.graphql"])
+	}
+	if want := "getLastAudit(input: IGetAuditRequest!): JSON!"; !strings.Contains(out["audits.graphql"], want) {
+		t.Errorf("output should contain\n%s\ngot:\n%s", want, out["audits.graphql"])
+	}
+	stderr = generateError(t, "exclude_package=acme.internal", users)
+	if !strings.Contains(stderr, "error generating field: field audit references acme.internal.Audit of an excluded package") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+	duration := testFile("google/protobuf/duration.proto", "google.protobuf",
+		[]*descriptorpb.DescriptorProto{testMessage("Duration", scalarField("seconds", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64))})
+	users.MessageType[1].Field[0] = messageField("timeout", 1, ".google.protobuf.Duration")
+	stderr = generateError(t, "exclude_package=google.protobuf", duration, users)
+	if !strings.Contains(stderr, "field timeout references google.protobuf.Duration of an excluded package") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+}
+
+func TestEmptyInputMutation(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("Empty"),
+			testMessage("Users", scalarField("count", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32)),
+		},
+		testMethod("ResetUsers", ".users.Empty", ".users.Users", &options.MethodOptions{Kind: "mutation"}),
+		testMethod("CountUsers", ".users.Empty", ".users.Users", nil),
+	)
+
+	// Mutations without input follow the payload options like queries
+	for _, tt := range []struct {
+		parameter, want string
+	}{
+		{"", "  resetUsers: Users!\n"},
+		{"all_nullable", "  resetUsers: Users\n"},
+		{"unwrap_single_field", "  resetUsers: Int\n"},
+		{"mutation_payloads", "  resetUsers: ResetUsersPayload!\n"},
+	} {
+		out := generate(t, tt.parameter, file)["users.graphql"]
+		if !strings.Contains(out, "type Mutation {\n"+tt.want) {
+			t.Errorf("%s: output should contain\n%s\ngot:\n%s", tt.parameter, tt.want, out)
+		}
+	}
+}
+
+func TestMutationPayloads(t *testing.T) {
+	file := testFile("users.proto", "users",
+		[]*descriptorpb.DescriptorProto{
+			testMessage("CreateUserRequest", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+			testMessage("User", scalarField("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		},
+		testMethod("CreateUser", ".users.CreateUserRequest", ".users.User", &options.MethodOptions{Kind: "mutation"}),
+		testMethod("ListUsers", ".users.CreateUserRequest", ".users.User",
+			&options.MethodOptions{Kind: "mutation", GqlOutput: "[User]"}),
+		testMethod("GetUser", ".users.CreateUserRequest", ".users.User", nil),
+	)
+
+	out := generate(t, "mutation_payloads", file)["users.graphql"]
+	for _, want := range []string{
+		"type CreateUserPayload {\n  user: User!\n}",
+		"type ListUsersPayload {\n  user: [User]!\n}",
+		"createUser(input: ICreateUserRequest!): CreateUserPayload!",
+		"listUsers(input: ICreateUserRequest!): ListUsersPayload!",
+		"getUser(input: ICreateUserRequest!): User!",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+
+	out = generate(t, "mutation_payloads,payload_suffix=Result,client_mutation_id", file)["users.graphql"]
+	for _, want := range []string{
+		"type CreateUserResult {\n  user: User!\n  clientMutationId: String\n}",
+		"createUser(input: ICreateUserRequest!): CreateUserResult!",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain\n%s\ngot:\n%s", want, out)
+		}
+	}
+
+	file.MessageType = append(file.MessageType, testMessage("CreateUserPayload", scalarField("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING)))
+	file.Service[0].Method = append(file.Service[0].Method, testMethod("GetPayload", ".users.CreateUserRequest", ".users.CreateUserPayload", nil))
+	stderr := generateError(t, "mutation_payloads", file)
+	if !strings.Contains(stderr, "error generating method CreateUser: users.proto:CreateUserPayload and the payload of mutation CreateUser both generate CreateUserPayload") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+}
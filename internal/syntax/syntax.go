@@ -39,6 +39,8 @@ const (
 	// GraphQL
 	Input      Keyword = "input"
 	ObjectType Keyword = "type"
+	Implements Keyword = "implements"
+	Extend     Keyword = "extend"
 	Mutation   Keyword = "Mutation"
 	Queries    Keyword = "Queries"
 )
@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fverse/protoc-graphql/internal/analyzer"
+)
+
+// Reasons a top-level message or enum is not generated, reported with emit_unused_warnings
+const (
+	// No RPC references the type
+	reasonUnreachable = "unreachable"
+	// Only RPCs with the skip option reference the type
+	reasonSkipped = "skipped"
+	// Only RPCs of other targets reference the type
+	reasonWrongTarget = "wrong target"
+)
+
+// An RPC excluded from the schema, with the types it references
+type excludedMethod struct {
+	name     string
+	target   string
+	skipped  bool
+	analyzer *analyzer.TypeAnalyzer
+}
+
+// Warns about the top-level messages and enums of the proto file that are not generated, if emit_unused_warnings is set.
+// Types left out on purpose, such as custom scalars, empty types or flattened requests, are not reported
+func (schema *Schema) warnUnusedTypes() {
+	if !schema.args.EmitUnusedWarnings {
+		return
+	}
+	excluded := schema.excludedMethods()
+
+	for _, message := range schema.protoFile.MessageType {
+		fullName := schema.messageFullName(message, "")
+		if schema.typeAnalyzer.IsOutputReachable(fullName) || schema.isInputType(fullName) || schema.isScalar(fullName) {
+			continue
+		}
+		schema.warnUnusedType(fullName, excluded, func(ta *analyzer.TypeAnalyzer) bool {
+			return ta.IsOutputReachable(fullName) || ta.IsInputReachable(fullName)
+		})
+	}
+
+	for _, enumType := range schema.protoFile.EnumType {
+		fullName := "." + enumType.GetName()
+		if schema.packageName != nil && *schema.packageName != "" {
+			fullName = "." + *schema.packageName + fullName
+		}
+		if schema.typeAnalyzer.IsEnumReachable(fullName) || schema.isScalarEnum(fullName) {
+			continue
+		}
+		schema.warnUnusedType(fullName, excluded, func(ta *analyzer.TypeAnalyzer) bool {
+			return ta.IsEnumReachable(fullName)
+		})
+	}
+}
+
+// Warns that a type is not generated, with the reason found from the excluded RPCs referencing it
+func (schema *Schema) warnUnusedType(fullName string, excluded []*excludedMethod, references func(*analyzer.TypeAnalyzer) bool) {
+	var skipped, otherTargets []string
+	for _, method := range excluded {
+		if !references(method.analyzer) {
+			continue
+		}
+		if method.skipped {
+			skipped = append(skipped, method.name)
+		} else {
+			otherTargets = append(otherTargets, fmt.Sprintf("%s (target %q)", method.name, method.target))
+		}
+	}
+
+	// Types of RPCs of other targets are generated with another target, so this reason comes first
+	reason, detail := reasonUnreachable, "not referenced by any RPC"
+	switch {
+	case len(otherTargets) > 0:
+		reason, detail = reasonWrongTarget, "referenced by "+strings.Join(otherTargets, ", ")
+	case len(skipped) > 0:
+		reason, detail = reasonSkipped, "referenced by skipped "+strings.Join(skipped, ", ")
+	}
+	schema.Logger.Warn("%s is not generated (%s): %s", schema.source(fullName), reason, detail)
+}
+
+// Returns the RPCs of the proto file excluded by the skip option or the target
+func (schema *Schema) excludedMethods() []*excludedMethod {
+	var excluded []*excludedMethod
+	for _, service := range schema.services() {
+		for _, method := range service.Method {
+			methodOptions := getMethodOptions(method)
+			if !skipMethod(schema.target(), methodOptions) {
+				continue
+			}
+			ta := analyzer.NewTypeAnalyzer(schema.plugin.Request.ProtoFile)
+			ta.ExcludePackages(schema.args.ExcludePackages)
+			ta.JSONInputMaps(schema.args.InputMaps == InputMapsJSON)
+			ta.MarkTypeReachableAsInput(analyzer.RequestType(method))
+			ta.MarkTypeReachableAsOutput(method.GetOutputType())
+			excluded = append(excluded, &excludedMethod{
+				name:     service.GetName() + "." + method.GetName(),
+				target:   methodOptions.Target,
+				skipped:  methodOptions.Skip,
+				analyzer: ta,
+			})
+		}
+	}
+	return excluded
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fverse/protoc-graphql/internal/descriptor"
+)
+
+// Declaration of the directive listing the allowed values of enum fields generated as String
+const valuesDirectiveDeclaration = "directive @values(values: [String!]!) on FIELD_DEFINITION | INPUT_FIELD_DEFINITION | ARGUMENT_DEFINITION"
+
+// Returns the names of the values of an enum, as the GraphQL enum would list them
+func (schema *Schema) enumValueNames(fullName string) []string {
+	enum := schema.typeAnalyzer.Enum(fullName)
+	if enum == nil {
+		return nil
+	}
+	values := make([]*descriptor.EnumValue, 0, len(enum.Value))
+	for _, value := range enum.Value {
+		values = append(values, enumValues(value))
+	}
+	schema.sortEnumValues(values)
+
+	names := make([]string, len(values))
+	for i, value := range values {
+		names[i] = *value.Name
+	}
+	return names
+}
+
+// Returns the @values directive listing the allowed values, e.g. @values(values: ["ACTIVE", "BANNED"])
+func valuesDirective(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
+	}
+	return "@values(values: [" + strings.Join(quoted, ", ") + "])"
+}
+
+// Writes the @values directive of a field, preceded by a space, if it has allowed values
+func (schema *Schema) writeValuesDirective(field *descriptor.Field) {
+	if len(field.AllowedValues) == 0 {
+		return
+	}
+	schema.Space()
+	schema.Write(valuesDirective(field.AllowedValues))
+}
+
+// Checks if a field or argument of the schema uses the @values directive
+func (schema *Schema) collectValuesDirective() {
+	add := func(fields []*descriptor.Field) {
+		for _, field := range fields {
+			schema.declareValues = schema.declareValues || len(field.AllowedValues) > 0
+		}
+	}
+	for _, objectType := range schema.objectTypes {
+		add(objectType.Fields)
+	}
+	for _, inputType := range schema.inputTypes {
+		add(inputType.Fields)
+	}
+	for _, query := range schema.queries {
+		add(query.Arguments)
+	}
+	for _, mutation := range schema.mutations {
+		add(mutation.Arguments)
+	}
+}
+
+// Declares the @values directive, if the schema uses it
+func (schema *Schema) generateValuesDirective() {
+	if !schema.declareValues {
+		return
+	}
+	schema.Write(valuesDirectiveDeclaration)
+	schema.NewLine(2)
+}
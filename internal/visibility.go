@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fverse/protoc-graphql/options"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Returns the message with only the fields generated in its object type, if output is set, or else in its input
+// and flattened arguments: fields with gql_input_only are left out of types, fields with gql_output_only out of inputs.
+// The fields keep their descriptors, the message is only copied if a field is left out
+func (schema *Schema) visibleFields(message *descriptorpb.DescriptorProto, output bool) *descriptorpb.DescriptorProto {
+	hidden := func(field *descriptorpb.FieldDescriptorProto) bool {
+		outputOnly := boolFieldOption(field.GetOptions(), options.E_GqlOutputOnly)
+		inputOnly := boolFieldOption(field.GetOptions(), options.E_GqlInputOnly)
+		if outputOnly && inputOnly {
+			schema.Error(fmt.Errorf("field %s can't be both gql_output_only and gql_input_only", field.GetName()),
+				"error generating field")
+		}
+		return output && inputOnly || !output && outputOnly
+	}
+	if !slices.ContainsFunc(message.Field, hidden) {
+		return message
+	}
+	visible := proto.Clone(message).(*descriptorpb.DescriptorProto)
+	visible.Field = slices.DeleteFunc(slices.Clone(message.Field), hidden)
+	return visible
+}
+
+// Warns about the fields of a message that no type or input generates: the gql_input_only fields of a message
+// only used as output, if output is set, or else the gql_output_only fields of a message only used as input
+func (schema *Schema) checkFieldVisibility(message *descriptorpb.DescriptorProto, fullName string, output bool) {
+	extension, option, usage := options.E_GqlInputOnly, "gql_input_only", "output"
+	if !output {
+		extension, option, usage = options.E_GqlOutputOnly, "gql_output_only", "input"
+	}
+	name := strings.TrimPrefix(schema.source(fullName), schema.protoFile.GetName()+":")
+	for _, field := range message.Field {
+		if boolFieldOption(field.GetOptions(), extension) {
+			schema.Logger.Warn("field %s of %s is %s, but %s is only used as %s, the field is not generated",
+				field.GetName(), name, option, name, usage)
+		}
+	}
+}
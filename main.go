@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"io"
+	"log"
 	"os"
 
 	"github.com/fverse/protoc-graphql/internal"
@@ -14,8 +15,8 @@ func main() {
 	// Handle CLI commands
 	if len(os.Args) > 1 {
 		switch os.Args[1] {
-		case "--version", "-v":
-			fmt.Printf("protoc-gen-graphql %s\n", internal.Version)
+		case "version", "--version", "-v":
+			runVersion(os.Args[2:])
 			os.Exit(0)
 		case "generate", "gen":
 			runGenerate()
@@ -23,6 +24,15 @@ func main() {
 		case "init":
 			runInit()
 			return
+		case "options":
+			runOptions()
+			return
+		case "from-descriptor-set":
+			runFromDescriptorSet()
+			return
+		case "doctor":
+			runDoctor()
+			return
 		case "help", "--help", "-h":
 			printHelp()
 			os.Exit(0)
@@ -34,6 +44,9 @@ func main() {
 }
 
 func runAsPlugin() {
+	// Stdout carries the CodeGeneratorResponse, keep every log on stderr
+	log.SetOutput(os.Stderr)
+
 	data, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading proto: %v\n", err)
@@ -48,7 +61,7 @@ func runAsPlugin() {
 
 	plugin := internal.New(&request)
 	plugin.Execute()
-	plugin.SetSupportOptionalField()
+	plugin.SetSupportedFeatures()
 
 	defer plugin.Info("Codegen completed")
 
@@ -72,6 +85,11 @@ Usage:
 Commands:
   generate, gen    Generate GraphQL schema from proto files (recommended)
   init             Initialize options.proto in your proto directory
+  options          Print the embedded options.proto or its version
+  from-descriptor-set
+                   Generate GraphQL schema from a FileDescriptorSet file
+  version          Print the version, as JSON with --json
+  doctor           Check that protoc is installed and compiles options.proto
   help             Show this help message
 
 Generate Command:
@@ -80,13 +98,84 @@ Generate Command:
   Options:
     -o, --out <dir>          Output directory (default: current directory)
     -I, --proto_path <path>  Additional proto import path (can be repeated)
+    --descriptor_set <path>  Generate from a FileDescriptorSet instead of proto files, "-" for stdin
+    --files <a.proto,...>    Files of the descriptor set to generate (default: not imported ones)
     --target <value>         Set the target (e.g., "admin", "client", "3")
+    --split_by_target        Write the combined schema of each target of --target=a,b to {target}.graphql
+    --default_target <value> Target of the RPCs without a target option
+    --exclude_untargeted     Exclude RPCs without a target option from named targets
+    --service <a,b,...>      Generate only the RPCs of the named services
+    --exclude_package <a,b>  Never generate the types of the proto packages, e.g. google.protobuf
     --keep_case              Keep original field casing
-    --keep_prefix            Keep prefix in type names
+    --field_case <value>     Field casing: "camel", "snake", "pascal" or "original"
+    --type_case <value>      Type and enum casing: "camel", "snake", "pascal" or "original"
+    --acronyms <a,b>         Words kept as written by camel and pascal casing, e.g. ID,URL
+    --keep_prefix            Prefix type and enum names with their package
+    --strip_suffix <a,b>     Strip the suffixes from message names, e.g. Request,Response
+    --type_prefix <prefix>   Prefix the names of generated types, inputs and enums
+    --max_name_length <n>    Truncate longer type, input and enum names, ending them with a hash
     --combine_output         Combine all schemas into one file
+    --no_dedup               Keep duplicate definitions in combined output, for debugging
     --output_filename <name> Custom output filename (use with --combine_output)
+    --operations_file <name> Write Query and Mutation to their own file (use with --combine_output)
+    --enums_file <name>      Write all enums to their own file (without --combine_output)
+    --manifest <name>        Write a JSON manifest of the generated files and their SHA-256 hashes
+    --extend_roots           Extend the Query and Mutation of the first file in the next files
+    --section_order <a,b,..> Order of the sections (default: types,inputs,enums,operations)
     --input_naming <value>   Input naming style: "suffix" or "prefix"
     --affix <value>          Custom affix for input types
+    --input_param_name <p>   Name of the input parameter of operations (default: "input")
+    --annotate_source        Comment types and fields with their proto source
+    --annotate_file          Comment types, inputs and enums with their proto file, e.g. "# source: users.proto"
+    --annotate_package       Comment types, inputs and enums with their proto package, e.g. "# package: acme.users"
+    --annotate_operations    Describe operations with their gRPC method
+    --annotate_proto_type    Describe fields with their proto type, e.g. "(proto: int64)"
+    --scalar <type:scalar>   Map a proto message to a custom scalar (can be repeated)
+    --scalar_by_name <t:s>   Map string fields named like a token to a scalar, e.g. uuid:UUID
+    --scalar_name_match <v>  Name matching of --scalar_by_name: "suffix" (default) or "substring"
+    --scalar_spec <s=url>    Declare a custom scalar with @specifiedBy(url:) (can be repeated)
+    --enum_as_scalar <enum>  Generate a proto enum as String (can be repeated)
+    --enum_as_string_with_values
+                             Generate every enum as String, listing its values with @values
+    --expose_option <o=@d>   Write a custom option as a directive or "description" (can be repeated)
+    --expose_http            Describe operations with their google.api.http REST mappings
+    --double_scalar <scalar> Scalar of double fields, e.g. "Float64" (default: Float)
+    --prepend <file>         Prepend a handwritten GraphQL file to the output
+    --docs <file>            Read descriptions from a Markdown file, by headings like User or User.name
+    --error_on_empty_type    Fail when a referenced message has no fields
+    --keep_empty_messages    Generate messages without fields with a "_: Boolean" field
+    --enum_aliases <value>   Aliased enum values: "keep" or "deprecate"
+    --enum_value_order <v>   Enum value order: "proto" (default), "number" or "name"
+    --indent <value>         Indentation: number of spaces or "tab" (default: 2)
+    --arg_wrap <n>           Write operations with more than n arguments one per line
+    --strip_path_prefix <p>  Strip a path prefix from output file names
+    --flatten_names          Name output files after the proto base name
+    --file_ext <ext>         Extension of the output files, e.g. graphqls (default: graphql)
+    --use_json_name          Name fields after their json_name
+    --strict                 Warn about unknown method kinds
+    --fail_on_warning        Fail generation if any warning is emitted
+    --all_inputs             Generate an input type for every output type
+    --flatten_args           Use request fields as query and mutation arguments
+    --unwrap_single_field    Return the field of single-field response messages
+    --relay_node             Generate the Relay Node interface and node query
+    --resource_queries       Add a get<Type>(id: ID!) query for every type with an id
+    --mutation_payloads      Wrap the result of each mutation in a generated XPayload type
+    --payload_suffix <s>     Suffix of the payload types of --mutation_payloads (default: Payload)
+    --client_mutation_id     Add a clientMutationId field to the payload types
+    --all_nullable           Make every output field and payload nullable
+    --nullable <value>       Output field nullability: "default", "none" (non-null proto3 fields) or "all"
+    --emit_unused_warnings   Warn about top-level messages and enums that are not generated
+    --recursive_inputs <v>   Non-null input cycles: "nullable" or "error"
+    --reserved_names <v>     Fields starting with "__": "sanitize" (default) or "error"
+    --federation_version <v> Federation spec version of the @link import (default: 2.3)
+    --oneof <value>          Oneof fields: "describe" to document their oneof
+    --input_maps <value>     Map fields of inputs: "entries" (default) or "json"
+    --dump_request <file>    Write the CodeGeneratorRequest to a file, as text if it ends with .txtpb
+    --verbose                Print debug messages to stderr
+    --quiet                  Print only errors to stderr
+
+  Imports are also searched in the directories of PROTO_PATH and in the
+  include directory installed with protoc.
 
 Init Command:
   protoc-gen-graphql init [proto_directory]
@@ -97,6 +186,21 @@ Init Command:
   Options:
     --force                  Overwrite existing options.proto
 
+Options Command:
+  protoc-gen-graphql options --print | --version
+
+  Options:
+    --print                  Print the embedded options.proto
+    --version                Print the version of the embedded options.proto
+
+From Descriptor Set Command:
+  protoc-gen-graphql from-descriptor-set [options] <descriptor_set>
+
+  Generates schemas from a FileDescriptorSet built with
+  "protoc --descriptor_set_out" or "buf build", without running protoc.
+  Accepts the output and plugin options of the generate command.
+  Files imported by other files of the set are not generated.
+
 Examples:
   # Generate schema from proto files (auto-includes options.proto)
   protoc-gen-graphql generate -o ./graphql ./protos/*.proto
@@ -104,6 +208,12 @@ Examples:
   # Generate with options
   protoc-gen-graphql generate --target=3 --combine_output -o ./schema ./api.proto
 
+  # Generate schema from a descriptor set built by buf
+  buf build -o api.pb && protoc-gen-graphql from-descriptor-set -o ./schema api.pb
+
+  # Generate schema from a descriptor set piped from buf
+  buf build -o - | protoc-gen-graphql generate --descriptor_set - -o ./schema
+
   # Initialize options.proto in default location (./protobuf/options/)
   protoc-gen-graphql init
 
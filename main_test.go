@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fverse/protoc-graphql/internal"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// testRequest builds a request for a proto file with a single query
+func testRequest(parameter string) *pluginpb.CodeGeneratorRequest {
+	stringType := descriptorpb.FieldDescriptorProto_TYPE_STRING
+	return &pluginpb.CodeGeneratorRequest{
+		Parameter:      proto.String(parameter),
+		FileToGenerate: []string{"users.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:    proto.String("users.proto"),
+				Package: proto.String("users"),
+				MessageType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("GetUserRequest"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{Name: proto.String("id"), Number: proto.Int32(1), Type: &stringType},
+						},
+					},
+					{
+						Name: proto.String("User"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{Name: proto.String("name"), Number: proto.Int32(1), Type: &stringType},
+						},
+					},
+				},
+				Service: []*descriptorpb.ServiceDescriptorProto{
+					{
+						Name: proto.String("UserService"),
+						Method: []*descriptorpb.MethodDescriptorProto{
+							{
+								Name:       proto.String("GetUser"),
+								InputType:  proto.String(".users.GetUserRequest"),
+								OutputType: proto.String(".users.User"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// runPlugin feeds the request to runAsPlugin and returns what it wrote to stdout and stderr
+func runPlugin(t *testing.T, request *pluginpb.CodeGeneratorRequest) ([]byte, string) {
+	t.Helper()
+
+	data, err := proto.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdinR, stdinW, _ := os.Pipe()
+	stdoutR, stdoutW, _ := os.Pipe()
+	stderrR, stderrW, _ := os.Pipe()
+
+	stdin, stdout, stderr := os.Stdin, os.Stdout, os.Stderr
+	os.Stdin, os.Stdout, os.Stderr = stdinR, stdoutW, stderrW
+	defer func() {
+		os.Stdin, os.Stdout, os.Stderr = stdin, stdout, stderr
+	}()
+
+	go func() {
+		stdinW.Write(data)
+		stdinW.Close()
+	}()
+
+	var errOut bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&errOut, stderrR)
+		close(done)
+	}()
+
+	var out []byte
+	outDone := make(chan struct{})
+	go func() {
+		out, _ = io.ReadAll(stdoutR)
+		close(outDone)
+	}()
+
+	runAsPlugin()
+
+	stdoutW.Close()
+	stderrW.Close()
+	<-outDone
+	<-done
+	return out, errOut.String()
+}
+
+func TestPluginStdoutContainsOnlyResponse(t *testing.T) {
+	out, errOut := runPlugin(t, testRequest("verbose"))
+
+	if bytes.Contains(out, []byte(internal.NAME+": ")) {
+		t.Fatalf("stdout contains log output: %q", out)
+	}
+
+	var response pluginpb.CodeGeneratorResponse
+	if err := proto.Unmarshal(out, &response); err != nil {
+		t.Fatalf("stdout is not a valid CodeGeneratorResponse: %v", err)
+	}
+	if len(response.File) != 1 || !strings.Contains(response.File[0].GetContent(), "getUser(") {
+		t.Errorf("unexpected response: %v", &response)
+	}
+
+	// Verbose logs must still be printed, but on stderr
+	if !strings.Contains(errOut, internal.NAME+": debug: ") {
+		t.Errorf("stderr should contain debug logs, got %q", errOut)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fverse/protoc-graphql/internal/embedded"
+)
+
+func runOptions() {
+	if err := printOptions(os.Stdout, os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Usage: protoc-gen-graphql options --print | --version")
+		os.Exit(1)
+	}
+}
+
+// printOptions writes the embedded options.proto with --print, or its version with --version
+func printOptions(w io.Writer, args []string) error {
+	for _, arg := range args {
+		switch arg {
+		case "--print":
+			_, err := io.WriteString(w, embedded.OptionsProto)
+			return err
+		case "--version":
+			_, err := fmt.Fprintln(w, embedded.OptionsVersion)
+			return err
+		}
+	}
+	return errors.New("expected --print or --version")
+}
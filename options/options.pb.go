@@ -113,6 +113,7 @@ type MethodOptions struct {
 	GqlInput      *GqlInput              `protobuf:"bytes,50003,opt,name=gql_input,json=gqlInput,proto3" json:"gql_input,omitempty"`
 	GqlOutput     string                 `protobuf:"bytes,50004,opt,name=gql_output,json=gqlOutput,proto3" json:"gql_output,omitempty"`
 	Skip          bool                   `protobuf:"varint,50005,opt,name=skip,proto3" json:"skip,omitempty"`
+	GqlArgsType   string                 `protobuf:"bytes,50006,opt,name=gql_args_type,json=gqlArgsType,proto3" json:"gql_args_type,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -182,6 +183,13 @@ func (x *MethodOptions) GetSkip() bool {
 	return false
 }
 
+func (x *MethodOptions) GetGqlArgsType() string {
+	if x != nil {
+		return x.GqlArgsType
+	}
+	return ""
+}
+
 var file_options_options_proto_extTypes = []protoimpl.ExtensionInfo{
 	{
 		ExtendedType:  (*descriptor.MethodOptions)(nil),
@@ -199,6 +207,38 @@ var file_options_options_proto_extTypes = []protoimpl.ExtensionInfo{
 		Tag:           "varint,50011,opt,name=skip",
 		Filename:      "options/options.proto",
 	},
+	{
+		ExtendedType:  (*descriptor.MessageOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         50012,
+		Name:          "gql_type_name",
+		Tag:           "bytes,50012,opt,name=gql_type_name",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptor.MessageOptions)(nil),
+		ExtensionType: ([]string)(nil),
+		Field:         50013,
+		Name:          "gql_type_tag",
+		Tag:           "bytes,50013,rep,name=gql_type_tag",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptor.MessageOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50014,
+		Name:          "gql_type_inaccessible",
+		Tag:           "varint,50014,opt,name=gql_type_inaccessible",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptor.MessageOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50015,
+		Name:          "gql_resource_query",
+		Tag:           "varint,50015,opt,name=gql_resource_query",
+		Filename:      "options/options.proto",
+	},
 	{
 		ExtendedType:  (*descriptor.FieldOptions)(nil),
 		ExtensionType: (*bool)(nil),
@@ -215,6 +255,110 @@ var file_options_options_proto_extTypes = []protoimpl.ExtensionInfo{
 		Tag:           "varint,50022,opt,name=keep_case",
 		Filename:      "options/options.proto",
 	},
+	{
+		ExtendedType:  (*descriptor.FieldOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         50023,
+		Name:          "gql_name",
+		Tag:           "bytes,50023,opt,name=gql_name",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptor.FieldOptions)(nil),
+		ExtensionType: ([]string)(nil),
+		Field:         50024,
+		Name:          "gql_tag",
+		Tag:           "bytes,50024,rep,name=gql_tag",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptor.FieldOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50025,
+		Name:          "gql_inaccessible",
+		Tag:           "varint,50025,opt,name=gql_inaccessible",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptor.FieldOptions)(nil),
+		ExtensionType: ([]string)(nil),
+		Field:         50026,
+		Name:          "gql_example",
+		Tag:           "bytes,50026,rep,name=gql_example",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptor.FieldOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50027,
+		Name:          "gql_input_optional",
+		Tag:           "varint,50027,opt,name=gql_input_optional",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptor.FieldOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50028,
+		Name:          "gql_input_required",
+		Tag:           "varint,50028,opt,name=gql_input_required",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptor.FieldOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50029,
+		Name:          "gql_id",
+		Tag:           "varint,50029,opt,name=gql_id",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptor.FieldOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50030,
+		Name:          "gql_element_nullable",
+		Tag:           "varint,50030,opt,name=gql_element_nullable",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptor.FieldOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50031,
+		Name:          "gql_non_empty",
+		Tag:           "varint,50031,opt,name=gql_non_empty",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptor.FieldOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50032,
+		Name:          "gql_output_only",
+		Tag:           "varint,50032,opt,name=gql_output_only",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptor.FieldOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50033,
+		Name:          "gql_input_only",
+		Tag:           "varint,50033,opt,name=gql_input_only",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptor.EnumOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50041,
+		Name:          "gql_as_scalar",
+		Tag:           "varint,50041,opt,name=gql_as_scalar",
+		Filename:      "options/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptor.EnumValueOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         50051,
+		Name:          "gql_value_name",
+		Tag:           "bytes,50051,opt,name=gql_value_name",
+		Filename:      "options/options.proto",
+	},
 }
 
 // Extension fields to descriptor.MethodOptions.
@@ -227,14 +371,56 @@ var (
 var (
 	// optional bool skip = 50011;
 	E_Skip = &file_options_options_proto_extTypes[1]
+	// optional string gql_type_name = 50012;
+	E_GqlTypeName = &file_options_options_proto_extTypes[2]
+	// repeated string gql_type_tag = 50013;
+	E_GqlTypeTag = &file_options_options_proto_extTypes[3]
+	// optional bool gql_type_inaccessible = 50014;
+	E_GqlTypeInaccessible = &file_options_options_proto_extTypes[4]
+	// optional bool gql_resource_query = 50015;
+	E_GqlResourceQuery = &file_options_options_proto_extTypes[5]
 )
 
 // Extension fields to descriptor.FieldOptions.
 var (
 	// optional bool required = 50021;
-	E_Required = &file_options_options_proto_extTypes[2]
+	E_Required = &file_options_options_proto_extTypes[6]
 	// optional bool keep_case = 50022;
-	E_KeepCase = &file_options_options_proto_extTypes[3]
+	E_KeepCase = &file_options_options_proto_extTypes[7]
+	// optional string gql_name = 50023;
+	E_GqlName = &file_options_options_proto_extTypes[8]
+	// repeated string gql_tag = 50024;
+	E_GqlTag = &file_options_options_proto_extTypes[9]
+	// optional bool gql_inaccessible = 50025;
+	E_GqlInaccessible = &file_options_options_proto_extTypes[10]
+	// repeated string gql_example = 50026;
+	E_GqlExample = &file_options_options_proto_extTypes[11]
+	// optional bool gql_input_optional = 50027;
+	E_GqlInputOptional = &file_options_options_proto_extTypes[12]
+	// optional bool gql_input_required = 50028;
+	E_GqlInputRequired = &file_options_options_proto_extTypes[13]
+	// optional bool gql_id = 50029;
+	E_GqlId = &file_options_options_proto_extTypes[14]
+	// optional bool gql_element_nullable = 50030;
+	E_GqlElementNullable = &file_options_options_proto_extTypes[15]
+	// optional bool gql_non_empty = 50031;
+	E_GqlNonEmpty = &file_options_options_proto_extTypes[16]
+	// optional bool gql_output_only = 50032;
+	E_GqlOutputOnly = &file_options_options_proto_extTypes[17]
+	// optional bool gql_input_only = 50033;
+	E_GqlInputOnly = &file_options_options_proto_extTypes[18]
+)
+
+// Extension fields to descriptor.EnumOptions.
+var (
+	// optional bool gql_as_scalar = 50041;
+	E_GqlAsScalar = &file_options_options_proto_extTypes[19]
+)
+
+// Extension fields to descriptor.EnumValueOptions.
+var (
+	// optional string gql_value_name = 50051;
+	E_GqlValueName = &file_options_options_proto_extTypes[20]
 )
 
 var File_options_options_proto protoreflect.FileDescriptor
@@ -248,18 +434,38 @@ const file_options_options_proto_rawDesc = "" +
 	"\boptional\x18\xf1\x86\x03 \x01(\bR\boptional\x12\x1e\n" +
 	"\tprimitive\x18\xf2\x86\x03 \x01(\bR\tprimitive\x12\x16\n" +
 	"\x05array\x18\xf3\x86\x03 \x01(\bR\x05array\x12\x16\n" +
-	"\x05empty\x18\xf4\x86\x03 \x01(\bR\x05empty\"\xa0\x01\n" +
+	"\x05empty\x18\xf4\x86\x03 \x01(\bR\x05empty\"\xc6\x01\n" +
 	"\rMethodOptions\x12\x14\n" +
 	"\x04kind\x18ц\x03 \x01(\tR\x04kind\x12\x18\n" +
 	"\x06target\x18҆\x03 \x01(\tR\x06target\x12(\n" +
 	"\tgql_input\x18ӆ\x03 \x01(\v2\t.GqlInputR\bgqlInput\x12\x1f\n" +
 	"\n" +
 	"gql_output\x18Ԇ\x03 \x01(\tR\tgqlOutput\x12\x14\n" +
-	"\x04skip\x18Ն\x03 \x01(\bR\x04skip:H\n" +
+	"\x04skip\x18Ն\x03 \x01(\bR\x04skip\x12$\n" +
+	"\rgql_args_type\x18ֆ\x03 \x01(\tR\vgqlArgsType:H\n" +
 	"\x06method\x12\x1e.google.protobuf.MethodOptions\x18І\x03 \x01(\v2\x0e.MethodOptionsR\x06method:5\n" +
-	"\x04skip\x12\x1f.google.protobuf.MessageOptions\x18ۆ\x03 \x01(\bR\x04skip:>\n" +
+	"\x04skip\x12\x1f.google.protobuf.MessageOptions\x18ۆ\x03 \x01(\bR\x04skip:H\n" +
+	"\rgql_type_name\x12\x1f.google.protobuf.MessageOptions\x18܆\x03 \x01(\tR\vgqlTypeName\x88\x01\x01:C\n" +
+	"\fgql_type_tag\x12\x1f.google.protobuf.MessageOptions\x18݆\x03 \x03(\tR\n" +
+	"gqlTypeTag:X\n" +
+	"\x15gql_type_inaccessible\x12\x1f.google.protobuf.MessageOptions\x18ކ\x03 \x01(\bR\x13gqlTypeInaccessible\x88\x01\x01:R\n" +
+	"\x12gql_resource_query\x12\x1f.google.protobuf.MessageOptions\x18߆\x03 \x01(\bR\x10gqlResourceQuery\x88\x01\x01:>\n" +
 	"\brequired\x12\x1d.google.protobuf.FieldOptions\x18\xe5\x86\x03 \x01(\bR\brequired\x88\x01\x01:?\n" +
-	"\tkeep_case\x12\x1d.google.protobuf.FieldOptions\x18\xe6\x86\x03 \x01(\bR\bkeepCase\x88\x01\x01B\n" +
+	"\tkeep_case\x12\x1d.google.protobuf.FieldOptions\x18\xe6\x86\x03 \x01(\bR\bkeepCase\x88\x01\x01:=\n" +
+	"\bgql_name\x12\x1d.google.protobuf.FieldOptions\x18\xe7\x86\x03 \x01(\tR\agqlName\x88\x01\x01:8\n" +
+	"\agql_tag\x12\x1d.google.protobuf.FieldOptions\x18\xe8\x86\x03 \x03(\tR\x06gqlTag:M\n" +
+	"\x10gql_inaccessible\x12\x1d.google.protobuf.FieldOptions\x18\xe9\x86\x03 \x01(\bR\x0fgqlInaccessible\x88\x01\x01:@\n" +
+	"\vgql_example\x12\x1d.google.protobuf.FieldOptions\x18\xea\x86\x03 \x03(\tR\n" +
+	"gqlExample:P\n" +
+	"\x12gql_input_optional\x12\x1d.google.protobuf.FieldOptions\x18\xeb\x86\x03 \x01(\bR\x10gqlInputOptional\x88\x01\x01:P\n" +
+	"\x12gql_input_required\x12\x1d.google.protobuf.FieldOptions\x18\xec\x86\x03 \x01(\bR\x10gqlInputRequired\x88\x01\x01:9\n" +
+	"\x06gql_id\x12\x1d.google.protobuf.FieldOptions\x18\xed\x86\x03 \x01(\bR\x05gqlId\x88\x01\x01:T\n" +
+	"\x14gql_element_nullable\x12\x1d.google.protobuf.FieldOptions\x18\xee\x86\x03 \x01(\bR\x12gqlElementNullable\x88\x01\x01:F\n" +
+	"\rgql_non_empty\x12\x1d.google.protobuf.FieldOptions\x18\xef\x86\x03 \x01(\bR\vgqlNonEmpty\x88\x01\x01:J\n" +
+	"\x0fgql_output_only\x12\x1d.google.protobuf.FieldOptions\x18\xf0\x86\x03 \x01(\bR\rgqlOutputOnly\x88\x01\x01:H\n" +
+	"\x0egql_input_only\x12\x1d.google.protobuf.FieldOptions\x18\xf1\x86\x03 \x01(\bR\fgqlInputOnly\x88\x01\x01:E\n" +
+	"\rgql_as_scalar\x12\x1c.google.protobuf.EnumOptions\x18\xf9\x86\x03 \x01(\bR\vgqlAsScalar\x88\x01\x01:L\n" +
+	"\x0egql_value_name\x12!.google.protobuf.EnumValueOptions\x18\x83\x87\x03 \x01(\tR\fgqlValueName\x88\x01\x01B\n" +
 	"Z\b/optionsb\x06proto3"
 
 var (
@@ -276,24 +482,43 @@ func file_options_options_proto_rawDescGZIP() []byte {
 
 var file_options_options_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
 var file_options_options_proto_goTypes = []any{
-	(*GqlInput)(nil),                  // 0: GqlInput
-	(*MethodOptions)(nil),             // 1: MethodOptions
-	(*descriptor.MethodOptions)(nil),  // 2: google.protobuf.MethodOptions
-	(*descriptor.MessageOptions)(nil), // 3: google.protobuf.MessageOptions
-	(*descriptor.FieldOptions)(nil),   // 4: google.protobuf.FieldOptions
+	(*GqlInput)(nil),                    // 0: GqlInput
+	(*MethodOptions)(nil),               // 1: MethodOptions
+	(*descriptor.MethodOptions)(nil),    // 2: google.protobuf.MethodOptions
+	(*descriptor.MessageOptions)(nil),   // 3: google.protobuf.MessageOptions
+	(*descriptor.FieldOptions)(nil),     // 4: google.protobuf.FieldOptions
+	(*descriptor.EnumOptions)(nil),      // 5: google.protobuf.EnumOptions
+	(*descriptor.EnumValueOptions)(nil), // 6: google.protobuf.EnumValueOptions
 }
 var file_options_options_proto_depIdxs = []int32{
-	0, // 0: MethodOptions.gql_input:type_name -> GqlInput
-	2, // 1: method:extendee -> google.protobuf.MethodOptions
-	3, // 2: skip:extendee -> google.protobuf.MessageOptions
-	4, // 3: required:extendee -> google.protobuf.FieldOptions
-	4, // 4: keep_case:extendee -> google.protobuf.FieldOptions
-	1, // 5: method:type_name -> MethodOptions
-	6, // [6:6] is the sub-list for method output_type
-	6, // [6:6] is the sub-list for method input_type
-	5, // [5:6] is the sub-list for extension type_name
-	1, // [1:5] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	0,  // 0: MethodOptions.gql_input:type_name -> GqlInput
+	2,  // 1: method:extendee -> google.protobuf.MethodOptions
+	3,  // 2: skip:extendee -> google.protobuf.MessageOptions
+	3,  // 3: gql_type_name:extendee -> google.protobuf.MessageOptions
+	3,  // 4: gql_type_tag:extendee -> google.protobuf.MessageOptions
+	3,  // 5: gql_type_inaccessible:extendee -> google.protobuf.MessageOptions
+	3,  // 6: gql_resource_query:extendee -> google.protobuf.MessageOptions
+	4,  // 7: required:extendee -> google.protobuf.FieldOptions
+	4,  // 8: keep_case:extendee -> google.protobuf.FieldOptions
+	4,  // 9: gql_name:extendee -> google.protobuf.FieldOptions
+	4,  // 10: gql_tag:extendee -> google.protobuf.FieldOptions
+	4,  // 11: gql_inaccessible:extendee -> google.protobuf.FieldOptions
+	4,  // 12: gql_example:extendee -> google.protobuf.FieldOptions
+	4,  // 13: gql_input_optional:extendee -> google.protobuf.FieldOptions
+	4,  // 14: gql_input_required:extendee -> google.protobuf.FieldOptions
+	4,  // 15: gql_id:extendee -> google.protobuf.FieldOptions
+	4,  // 16: gql_element_nullable:extendee -> google.protobuf.FieldOptions
+	4,  // 17: gql_non_empty:extendee -> google.protobuf.FieldOptions
+	4,  // 18: gql_output_only:extendee -> google.protobuf.FieldOptions
+	4,  // 19: gql_input_only:extendee -> google.protobuf.FieldOptions
+	5,  // 20: gql_as_scalar:extendee -> google.protobuf.EnumOptions
+	6,  // 21: gql_value_name:extendee -> google.protobuf.EnumValueOptions
+	1,  // 22: method:type_name -> MethodOptions
+	23, // [23:23] is the sub-list for method output_type
+	23, // [23:23] is the sub-list for method input_type
+	22, // [22:23] is the sub-list for extension type_name
+	1,  // [1:22] is the sub-list for extension extendee
+	0,  // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_options_options_proto_init() }
@@ -308,7 +533,7 @@ func file_options_options_proto_init() {
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_options_options_proto_rawDesc), len(file_options_options_proto_rawDesc)),
 			NumEnums:      0,
 			NumMessages:   2,
-			NumExtensions: 4,
+			NumExtensions: 21,
 			NumServices:   0,
 		},
 		GoTypes:           file_options_options_proto_goTypes,
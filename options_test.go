@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fverse/protoc-graphql/internal/embedded"
+)
+
+func TestPrintOptions(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--print"}, embedded.OptionsProto},
+		{[]string{"--version"}, embedded.OptionsVersion + "\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := printOptions(&buf, tt.args); err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.args, buf.String(), tt.want)
+		}
+	}
+
+	if err := printOptions(new(bytes.Buffer), nil); err == nil {
+		t.Error("expected an error without --print or --version")
+	}
+}
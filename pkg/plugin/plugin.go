@@ -0,0 +1,38 @@
+// Package plugin runs protoc-gen-graphql from Go programs embedding it, e.g. to register their own type mappers
+package plugin
+
+import (
+	"github.com/fverse/protoc-graphql/internal"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// TypeMapper maps proto fields to GraphQL types, for programs embedding the plugin with their own scalars.
+// MapField returns false to leave a field to the built-in mappings
+type TypeMapper interface {
+	MapField(field *descriptorpb.FieldDescriptorProto) (gqlType string, ok bool)
+}
+
+// Plugin generates the GraphQL schemas of a protoc code generator request
+type Plugin struct {
+	plugin *internal.Plugin
+}
+
+// New creates a Plugin from a request, parsing its parameter as the plugin options
+func New(request *pluginpb.CodeGeneratorRequest) *Plugin {
+	return &Plugin{plugin: internal.New(request)}
+}
+
+// AddTypeMapper registers a type mapper, before Execute. The first mapper mapping a field takes precedence
+// over the built-in mappings. The type is a scalar, declared unless built in, and keeps the list and nullability of the field
+func (p *Plugin) AddTypeMapper(mapper TypeMapper) {
+	p.plugin.AddTypeMapper(mapper)
+}
+
+// Execute generates the schemas and returns the response to write to protoc.
+// Like the plugin binary, it exits the program if generation fails
+func (p *Plugin) Execute() *pluginpb.CodeGeneratorResponse {
+	p.plugin.Execute()
+	p.plugin.SetSupportedFeatures()
+	return p.plugin.Response
+}
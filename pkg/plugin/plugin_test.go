@@ -0,0 +1,85 @@
+package plugin_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fverse/protoc-graphql/pkg/plugin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Maps the fields named email to the Email scalar
+type emailMapper struct{}
+
+func (emailMapper) MapField(field *descriptorpb.FieldDescriptorProto) (string, bool) {
+	return "Email", field.GetName() == "email"
+}
+
+func field(name string, number int32, fieldType descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(name),
+		Number:   proto.Int32(number),
+		Type:     fieldType.Enum(),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+	if typeName != "" {
+		field.TypeName = proto.String(typeName)
+	}
+	return field
+}
+
+func TestTypeMapper(t *testing.T) {
+	email := field("email", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".users.Address")
+	email.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("users.proto"),
+		Package: proto.String("users"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("GetUserRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+			}},
+			{Name: proto.String("User"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""), email,
+			}},
+			{Name: proto.String("Address"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("value", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+			}},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("UserService"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("GetUser"),
+				InputType:  proto.String(".users.GetUserRequest"),
+				OutputType: proto.String(".users.User"),
+			}},
+		}},
+	}
+
+	p := plugin.New(&pluginpb.CodeGeneratorRequest{
+		Parameter:      proto.String(""),
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{file},
+		FileToGenerate: []string{file.GetName()},
+	})
+	p.AddTypeMapper(emailMapper{})
+	response := p.Execute()
+
+	if len(response.File) != 1 {
+		t.Fatalf("expected one file, got %d", len(response.File))
+	}
+	out := response.File[0].GetContent()
+	for _, want := range []string{
+		"scalar Email\n",
+		"type User {\n  name: String\n  email: [Email]\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+	if response.GetSupportedFeatures() == 0 {
+		t.Error("the supported features should be set")
+	}
+}
@@ -27,23 +27,53 @@ func String(v string) *string {
 	return &v
 }
 
-// CamelCase converts string to camel case.
+// CamelCase converts string to camel case. Words matching one of the acronyms, ignoring case,
+// are written as the acronym unless they come first, e.g. httpURL for http_url with the acronym URL.
 // Credits: This function is a slightly modified version of CamelCase function of the "github.com/samber/lo" package
-func CamelCase(str string) string {
+func CamelCase(str string, acronyms ...string) string {
 	if len(str) == 0 || str[0] == '_' {
 		return str
 	}
 	items := Words(str)
 	for i, item := range items {
-		item = strings.ToLower(item)
-		if i > 0 {
-			item = UppercaseFirst(item)
+		if i == 0 {
+			items[i] = strings.ToLower(item)
+		} else {
+			items[i] = capitalize(item, acronyms)
 		}
-		items[i] = item
 	}
 	return strings.Join(items, "")
 }
 
+// PascalCase converts string to pascal case. Words matching one of the acronyms, ignoring case,
+// are written as the acronym, e.g. UserID for user_id with the acronym ID.
+func PascalCase(str string, acronyms ...string) string {
+	items := Words(str)
+	for i, item := range items {
+		items[i] = capitalize(item, acronyms)
+	}
+	return strings.Join(items, "")
+}
+
+// Capitalizes a word, or returns the acronym it matches
+func capitalize(word string, acronyms []string) string {
+	for _, acronym := range acronyms {
+		if strings.EqualFold(word, acronym) {
+			return acronym
+		}
+	}
+	return UppercaseFirst(strings.ToLower(word))
+}
+
+// SnakeCase converts string to snake case.
+func SnakeCase(str string) string {
+	items := Words(str)
+	for i, item := range items {
+		items[i] = strings.ToLower(item)
+	}
+	return strings.Join(items, "_")
+}
+
 var (
 	splitWordReg         = regexp.MustCompile(`([a-z])([A-Z0-9])|([a-zA-Z])([0-9])|([0-9])([a-zA-Z])|([A-Z])([A-Z])([a-z])`)
 	splitNumberLetterReg = regexp.MustCompile(`([0-9])([a-zA-Z])`)
@@ -0,0 +1,14 @@
+
+=
+common.protocommon"
+Address
+city (	Rcitybproto3
+
+users.protouserscommon.proto" 
+GetUserRequest
+id (	Rid"E
+User
+name (	Rname)
+address (2.common.AddressRaddress2P
+UserServiceA
+GetUser.users.GetUserRequest.users.User"query*bproto3
\ No newline at end of file
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/fverse/protoc-graphql/internal"
+)
+
+// versionInfo is the machine-readable output of `version --json`
+type versionInfo struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	GoVersion string `json:"goVersion"`
+	// VCS revision and modification state, when the binary was built from a checkout
+	Revision string `json:"revision,omitempty"`
+	Modified bool   `json:"modified,omitempty"`
+}
+
+func runVersion(args []string) {
+	asJSON := false
+	for _, arg := range args {
+		if arg == "--json" {
+			asJSON = true
+		}
+	}
+
+	if err := printVersion(os.Stdout, asJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// printVersion writes the version to w, as JSON if asJSON is set
+func printVersion(w io.Writer, asJSON bool) error {
+	info := getVersionInfo()
+	if !asJSON {
+		_, err := fmt.Fprintf(w, "%s %s\n", info.Name, info.Version)
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	return encoder.Encode(info)
+}
+
+// getVersionInfo collects the version and the build info embedded in the binary
+func getVersionInfo() versionInfo {
+	info := versionInfo{
+		Name:      internal.NAME,
+		Version:   internal.Version,
+		GoVersion: runtime.Version(),
+	}
+
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	// Installed with `go install`, the module version is known even without ldflags
+	if info.Version == "dev" && buildInfo.Main.Version != "" && buildInfo.Main.Version != "(devel)" {
+		info.Version = buildInfo.Main.Version
+	}
+	for _, setting := range buildInfo.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Revision = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"testing"
+
+	"github.com/fverse/protoc-graphql/internal"
+)
+
+func TestPrintVersionJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printVersion(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+
+	var info map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &info); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if info["name"] != internal.NAME {
+		t.Errorf("name = %v, want %s", info["name"], internal.NAME)
+	}
+	if version, _ := info["version"].(string); version == "" {
+		t.Errorf("version should be set, got %v", info["version"])
+	}
+	if info["goVersion"] != runtime.Version() {
+		t.Errorf("goVersion = %v, want %s", info["goVersion"], runtime.Version())
+	}
+}
+
+func TestPrintVersion(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printVersion(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	if want := internal.NAME + " " + getVersionInfo().Version + "\n"; buf.String() != want {
+		t.Errorf("printVersion() = %q, want %q", buf.String(), want)
+	}
+}